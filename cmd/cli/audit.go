@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/audit"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+)
+
+// auditCmd lists the preprocessing runs recorded in the audit database, or
+// exports their statistics as CSV.
+func auditCmd(ctx context.Context, cfg config.Configuration, args []string) error {
+	p := pflag.NewFlagSet("audit", pflag.ContinueOnError)
+	p.String("outcome", "", "Only the runs with this outcome")
+	p.String("producer", "", "Only the runs of the SIPs of this producer")
+	p.String("profile", "", "Only the runs with this processing profile")
+	p.String("since", "", "Only the runs started on or after this date (YYYY-MM-DD)")
+	p.String("until", "", "Only the runs started before this date (YYYY-MM-DD)")
+	p.Int("limit", 50, "Maximum number of runs listed")
+	p.String("by", "day", "Group the statistics by "+strings.Join(audit.Groupings, ", "))
+	p.Usage = func() {
+		fmt.Fprintf(
+			os.Stderr,
+			"Usage: %s audit [FLAGS] (runs | stats)\n\n"+
+				"  runs   List the runs, most recent first\n"+
+				"  stats  Export the statistics of the runs as CSV\n\nFlags:\n",
+			appName,
+		)
+		p.PrintDefaults()
+	}
+	if err := p.Parse(args); err != nil {
+		return err
+	}
+	if p.NArg() != 1 || p.Arg(0) != "runs" && p.Arg(0) != "stats" {
+		p.Usage()
+		return pflag.ErrHelp
+	}
+	if cfg.Audit.DatabasePath == "" {
+		return errors.New("audit: Audit.DatabasePath is not configured")
+	}
+
+	var f audit.Filter
+	f.Outcome, _ = p.GetString("outcome")
+	f.Producer, _ = p.GetString("producer")
+	f.Profile, _ = p.GetString("profile")
+	f.Limit, _ = p.GetInt("limit")
+	for _, d := range []struct {
+		flag string
+		dst  *time.Time
+	}{
+		{"since", &f.Since},
+		{"until", &f.Until},
+	} {
+		v, _ := p.GetString(d.flag)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return fmt.Errorf("audit: invalid --%s date: %q", d.flag, v)
+		}
+		*d.dst = t
+	}
+
+	db, err := audit.Open(cfg.Audit.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if p.Arg(0) == "stats" {
+		by, _ := p.GetString("by")
+		stats, err := db.Stats(ctx, by, f)
+		if err != nil {
+			return err
+		}
+		return audit.WriteCSV(os.Stdout, by, stats)
+	}
+
+	runs, err := db.Runs(ctx, f)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tSIP\tOUTCOME\tFILES\tFAILURES\tWARNINGS\tDURATION\tWORKFLOW")
+	for _, r := range runs {
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+			r.StartedAt.Local().Format(time.DateTime),
+			r.RelativePath,
+			r.Outcome,
+			r.Files,
+			r.Failures,
+			r.Warnings,
+			r.CompletedAt.Sub(r.StartedAt).Round(time.Second),
+			r.WorkflowID,
+		)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+	temporalsdk_client "go.temporal.io/sdk/client"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/workflow"
+)
+
+// batch starts a batch preprocessing workflow for the SIPs at the given paths,
+// or in a directory of the shared path, and optionally waits for its summary.
+func batch(ctx context.Context, cfg config.Configuration, args []string) error {
+	p := pflag.NewFlagSet("batch", pflag.ContinueOnError)
+	p.String("dir", "", "Preprocess the SIPs in this directory of the shared path")
+	p.Int("concurrency", 0, "Maximum number of SIPs preprocessed at the same time (default: configured value)")
+	p.String("producer", "", "Name of the producer of the SIPs")
+	p.String("profile", "", "Processing profile of the SIPs (default: configured default profile)")
+	p.String("language", "", "Language of the event names and messages (default: configured language)")
+	p.Bool("dry-run", false, "Only validate the SIPs")
+	p.Bool("wait", false, "Wait for the batch to complete and show its summary")
+	p.Usage = func() {
+		fmt.Fprintf(
+			os.Stderr,
+			"Usage: %s batch [FLAGS] (--dir DIR | PATH...)\n\nFlags:\n",
+			appName,
+		)
+		p.PrintDefaults()
+	}
+	if err := p.Parse(args); err != nil {
+		return err
+	}
+	dir, _ := p.GetString("dir")
+	if (dir == "") == (p.NArg() == 0) {
+		p.Usage()
+		return pflag.ErrHelp
+	}
+	concurrency, _ := p.GetInt("concurrency")
+	producer, _ := p.GetString("producer")
+	profile, _ := p.GetString("profile")
+	language, _ := p.GetString("language")
+	dryRun, _ := p.GetBool("dry-run")
+
+	c, err := temporalsdk_client.Dial(temporalsdk_client.Options{
+		HostPort:  cfg.Temporal.Address,
+		Namespace: cfg.Temporal.Namespace,
+	})
+	if err != nil {
+		return fmt.Errorf("batch: connect to Temporal: %v", err)
+	}
+	defer c.Close()
+
+	run, err := c.ExecuteWorkflow(
+		ctx,
+		temporalsdk_client.StartWorkflowOptions{TaskQueue: cfg.Temporal.TaskQueue},
+		cfg.Temporal.BatchWorkflowName,
+		&workflow.BatchPreprocessingWorkflowParams{
+			RelativePaths:  p.Args(),
+			Directory:      dir,
+			MaxConcurrency: concurrency,
+			Producer:       producer,
+			Profile:        profile,
+			Language:       language,
+			DryRun:         dryRun,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("batch: start workflow: %v", err)
+	}
+	fmt.Printf("Started batch preprocessing workflow %q (run ID: %q).\n", run.GetID(), run.GetRunID())
+
+	if wait, _ := p.GetBool("wait"); !wait {
+		return nil
+	}
+
+	var res workflow.BatchPreprocessingWorkflowResult
+	if err := run.Get(ctx, &res); err != nil {
+		return fmt.Errorf("batch: %v", err)
+	}
+	fmt.Printf(
+		"\nPreprocessed %d SIPs in %s.\n",
+		len(res.Report.SIPs),
+		res.Report.CompletedAt.Sub(res.Report.StartedAt),
+	)
+	if res.ReportPath != "" {
+		fmt.Printf("Report: %s\n", res.ReportPath)
+	}
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SIP\tOUTCOME\tFAILURES\tWARNINGS\tWORKFLOW")
+	for _, s := range res.Report.SIPs {
+		wid := s.WorkflowID
+		if wid == "" {
+			wid = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", s.RelativePath, s.Outcome, s.Failures, s.Warnings, wid)
+	}
+
+	return tw.Flush()
+}
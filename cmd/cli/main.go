@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/pflag"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/version"
+)
+
+const appName = "preprocessing-cli"
+
+const usage = `Usage: %s [--config FILE] COMMAND [ARGS]
+
+Commands:
+  audit              List the recorded preprocessing runs or export their statistics
+  batch              Preprocess a batch of SIPs
+  progress           Show the progress of a preprocessing workflow
+  restore            Restore a quarantined SIP to the shared path
+  review             Show or decide the review of a SIP with warnings
+  search-attributes  Register the workflow search attributes in Temporal
+  verify             Verify the signature and the checksums of a signed SIP
+
+Flags:
+`
+
+// command runs a subcommand with its arguments.
+type command func(ctx context.Context, cfg config.Configuration, args []string) error
+
+var commands = map[string]command{
+	"audit":             auditCmd,
+	"batch":             batch,
+	"progress":          progress,
+	"restore":           restore,
+	"review":            review,
+	"search-attributes": searchAttributes,
+	"verify":            verify,
+}
+
+// standalone lists the commands that run without the configuration.
+var standalone = map[string]bool{
+	"verify": true,
+}
+
+func main() {
+	p := pflag.NewFlagSet(appName, pflag.ContinueOnError)
+	p.String("config", "", "Configuration file")
+	p.Bool("version", false, "Show version information")
+	p.SetInterspersed(false)
+	p.Usage = func() {
+		fmt.Fprintf(os.Stderr, usage, appName)
+		p.PrintDefaults()
+	}
+	if err := p.Parse(os.Args[1:]); errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		os.Exit(1)
+	}
+
+	if v, _ := p.GetBool("version"); v {
+		fmt.Println(version.Info(appName))
+		os.Exit(0)
+	}
+
+	if p.NArg() == 0 {
+		p.Usage()
+		os.Exit(1)
+	}
+	cmd, ok := commands[p.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", p.Arg(0))
+		p.Usage()
+		os.Exit(1)
+	}
+
+	var cfg config.Configuration
+	if !standalone[p.Arg(0)] {
+		configFile, _ := p.GetString("config")
+		if _, _, err := config.Read(&cfg, configFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read configuration: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cmd(ctx, cfg, p.Args()[1:]); err != nil {
+		if !errors.Is(err, pflag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		stop()
+		os.Exit(1)
+	}
+}
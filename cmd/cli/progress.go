@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+	temporalsdk_client "go.temporal.io/sdk/client"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/workflow"
+)
+
+// progress shows the progress of a preprocessing workflow.
+func progress(ctx context.Context, cfg config.Configuration, args []string) error {
+	p := pflag.NewFlagSet("progress", pflag.ContinueOnError)
+	p.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s progress ID\n", appName)
+	}
+	if err := p.Parse(args); err != nil {
+		return err
+	}
+	if p.NArg() != 1 {
+		p.Usage()
+		return pflag.ErrHelp
+	}
+
+	c, err := temporalsdk_client.Dial(temporalsdk_client.Options{
+		HostPort:  cfg.Temporal.Address,
+		Namespace: cfg.Temporal.Namespace,
+	})
+	if err != nil {
+		return fmt.Errorf("progress: connect to Temporal: %v", err)
+	}
+	defer c.Close()
+
+	res, err := c.QueryWorkflow(ctx, p.Arg(0), "", workflow.ProgressQuery)
+	if err != nil {
+		return fmt.Errorf("progress: query workflow: %v", err)
+	}
+	var pr workflow.Progress
+	if err := res.Get(&pr); err != nil {
+		return fmt.Errorf("progress: %v", err)
+	}
+
+	current := pr.CurrentStep
+	if current == "" {
+		current = "none"
+	}
+	fmt.Printf("Current step: %s\n", current)
+	fmt.Printf("Steps: %d completed, about %d remaining\n", pr.CompletedSteps, pr.RemainingSteps)
+	fmt.Printf("Files processed: %d\n\n", pr.FilesProcessed)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STEP\tOUTCOME\tSTARTED\tCOMPLETED")
+	printEvents(tw, pr.PreservationTasks, "")
+
+	return tw.Flush()
+}
+
+// printEvents prints a row for each event, followed by its child events
+// indented under it.
+func printEvents(tw *tabwriter.Writer, events []*eventlog.Event, indent string) {
+	for _, ev := range events {
+		outcome, completed := ev.Outcome.String(), ev.CompletedAt.Format("15:04:05")
+		if ev.CompletedAt.IsZero() {
+			outcome, completed = "running", "-"
+		}
+		fmt.Fprintf(tw, "%s%s\t%s\t%s\t%s\n", indent, ev.Name, outcome, ev.StartedAt.Format("15:04:05"), completed)
+		printEvents(tw, ev.Children, indent+"  ")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+	temporalsdk_client "go.temporal.io/sdk/client"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/quarantine"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/workflow"
+)
+
+// restore moves a quarantined SIP back to its original location in the shared
+// path and, optionally, starts a new preprocessing workflow for it.
+func restore(ctx context.Context, cfg config.Configuration, args []string) error {
+	p := pflag.NewFlagSet("restore", pflag.ContinueOnError)
+	p.Bool("start", false, "Start a preprocessing workflow for the restored SIP")
+	p.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s restore [--start] ID\n\nFlags:\n", appName)
+		p.PrintDefaults()
+	}
+	if err := p.Parse(args); err != nil {
+		return err
+	}
+	if p.NArg() != 1 {
+		p.Usage()
+		return pflag.ErrHelp
+	}
+	if cfg.QuarantinePath == "" {
+		return errors.New("restore: QuarantinePath is not configured")
+	}
+
+	r, err := quarantine.Restore(cfg.QuarantinePath, p.Arg(0), cfg.SharedPath)
+	if err != nil {
+		return fmt.Errorf("restore: %v", err)
+	}
+	fmt.Printf("Restored SIP %q to %q.\n", r.ID, r.RelativePath)
+
+	if start, _ := p.GetBool("start"); !start {
+		return nil
+	}
+
+	c, err := temporalsdk_client.Dial(temporalsdk_client.Options{
+		HostPort:  cfg.Temporal.Address,
+		Namespace: cfg.Temporal.Namespace,
+	})
+	if err != nil {
+		return fmt.Errorf("restore: connect to Temporal: %v", err)
+	}
+	defer c.Close()
+
+	// Start the workflow with the params of the rejected run, if recorded.
+	params := &workflow.PreprocessingWorkflowParams{}
+	if len(r.Params) > 0 {
+		if err := json.Unmarshal(r.Params, params); err != nil {
+			return fmt.Errorf("restore: invalid workflow params: %v", err)
+		}
+	}
+	params.RelativePath = r.RelativePath
+
+	run, err := c.ExecuteWorkflow(
+		ctx,
+		temporalsdk_client.StartWorkflowOptions{TaskQueue: cfg.Temporal.TaskQueue},
+		cfg.Temporal.WorkflowName,
+		params,
+	)
+	if err != nil {
+		return fmt.Errorf("restore: start workflow: %v", err)
+	}
+	fmt.Printf("Started preprocessing workflow %q (run ID: %q).\n", run.GetID(), run.GetRunID())
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+	temporalsdk_client "go.temporal.io/sdk/client"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/workflow"
+)
+
+// review shows the findings of a SIP waiting for review or, with --approve or
+// --reject, sends the review decision to the workflow.
+func review(ctx context.Context, cfg config.Configuration, args []string) error {
+	p := pflag.NewFlagSet("review", pflag.ContinueOnError)
+	p.Bool("approve", false, "Approve the SIP")
+	p.Bool("reject", false, "Reject the SIP")
+	p.String("reviewer", "", "Name of the reviewer (required to approve or reject)")
+	p.String("comment", "", "Comment explaining the decision")
+	p.Usage = func() {
+		fmt.Fprintf(
+			os.Stderr,
+			"Usage: %s review [--approve|--reject --reviewer NAME [--comment TEXT]] ID\n\nFlags:\n",
+			appName,
+		)
+		p.PrintDefaults()
+	}
+	if err := p.Parse(args); err != nil {
+		return err
+	}
+	approve, _ := p.GetBool("approve")
+	reject, _ := p.GetBool("reject")
+	if p.NArg() != 1 || (approve && reject) {
+		p.Usage()
+		return pflag.ErrHelp
+	}
+	id := p.Arg(0)
+
+	c, err := temporalsdk_client.Dial(temporalsdk_client.Options{
+		HostPort:  cfg.Temporal.Address,
+		Namespace: cfg.Temporal.Namespace,
+	})
+	if err != nil {
+		return fmt.Errorf("review: connect to Temporal: %v", err)
+	}
+	defer c.Close()
+
+	if !approve && !reject {
+		return showReview(ctx, c, id)
+	}
+
+	reviewer, _ := p.GetString("reviewer")
+	if reviewer == "" {
+		return errors.New("review: --reviewer is required")
+	}
+	comment, _ := p.GetString("comment")
+
+	h, err := c.UpdateWorkflow(ctx, id, "", workflow.ReviewUpdate, workflow.ReviewDecision{
+		Approved: approve,
+		Reviewer: reviewer,
+		Comment:  comment,
+	})
+	if err != nil {
+		return fmt.Errorf("review: %v", err)
+	}
+	if err := h.Get(ctx, nil); err != nil {
+		return fmt.Errorf("review: %v", err)
+	}
+
+	if approve {
+		fmt.Printf("Approved SIP of workflow %q.\n", id)
+	} else {
+		fmt.Printf("Rejected SIP of workflow %q.\n", id)
+	}
+
+	return nil
+}
+
+func showReview(ctx context.Context, c temporalsdk_client.Client, id string) error {
+	res, err := c.QueryWorkflow(ctx, id, "", workflow.ReviewQuery)
+	if err != nil {
+		return fmt.Errorf("review: query workflow: %v", err)
+	}
+	var r workflow.Review
+	if err := res.Get(&r); err != nil {
+		return fmt.Errorf("review: %v", err)
+	}
+
+	switch {
+	case r.Pending && r.Deadline.IsZero():
+		fmt.Println("Waiting for review.")
+	case r.Pending:
+		fmt.Printf("Waiting for review until %s.\n", r.Deadline.Format("2006-01-02 15:04:05 MST"))
+	case r.Decision.Approved:
+		fmt.Println("Approved.")
+	default:
+		fmt.Println("Rejected.")
+	}
+
+	fmt.Printf("\nFindings (%d):\n", len(r.Findings))
+	for _, f := range r.Findings {
+		fmt.Printf("  - %s\n", f.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+	temporalapi_enums "go.temporal.io/api/enums/v1"
+	temporalapi_operatorservice "go.temporal.io/api/operatorservice/v1"
+	temporalsdk_client "go.temporal.io/sdk/client"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/workflow"
+)
+
+// searchAttributes registers the custom search attributes of the
+// preprocessing workflow in the configured Temporal namespace, skipping the
+// attributes already registered.
+func searchAttributes(ctx context.Context, cfg config.Configuration, args []string) error {
+	p := pflag.NewFlagSet("search-attributes", pflag.ContinueOnError)
+	p.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s search-attributes\n", appName)
+	}
+	if err := p.Parse(args); err != nil {
+		return err
+	}
+	if p.NArg() != 0 {
+		p.Usage()
+		return pflag.ErrHelp
+	}
+
+	c, err := temporalsdk_client.Dial(temporalsdk_client.Options{
+		HostPort:  cfg.Temporal.Address,
+		Namespace: cfg.Temporal.Namespace,
+	})
+	if err != nil {
+		return fmt.Errorf("search-attributes: connect to Temporal: %v", err)
+	}
+	defer c.Close()
+
+	existing, err := c.OperatorService().ListSearchAttributes(
+		ctx,
+		&temporalapi_operatorservice.ListSearchAttributesRequest{Namespace: cfg.Temporal.Namespace},
+	)
+	if err != nil {
+		return fmt.Errorf("search-attributes: list search attributes: %v", err)
+	}
+
+	add := map[string]temporalapi_enums.IndexedValueType{}
+	for _, k := range workflow.SearchAttributes {
+		if t, ok := existing.GetCustomAttributes()[k.GetName()]; ok {
+			if t != k.GetValueType() {
+				return fmt.Errorf("search-attributes: %s is already registered with type %s", k.GetName(), t)
+			}
+			fmt.Printf("%s (%s) is already registered.\n", k.GetName(), t)
+			continue
+		}
+		add[k.GetName()] = k.GetValueType()
+	}
+	if len(add) == 0 {
+		return nil
+	}
+
+	_, err = c.OperatorService().AddSearchAttributes(ctx, &temporalapi_operatorservice.AddSearchAttributesRequest{
+		Namespace:        cfg.Temporal.Namespace,
+		SearchAttributes: add,
+	})
+	if err != nil {
+		return fmt.Errorf("search-attributes: register search attributes: %v", err)
+	}
+	for _, k := range workflow.SearchAttributes {
+		if t, ok := add[k.GetName()]; ok {
+			fmt.Printf("Registered %s (%s).\n", k.GetName(), t)
+		}
+	}
+
+	return nil
+}
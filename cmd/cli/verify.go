@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/signature"
+)
+
+// verify checks the signature and the checksums of a signed preprocessed SIP.
+// It runs without the configuration, cfg is empty.
+func verify(ctx context.Context, cfg config.Configuration, args []string) error {
+	p := pflag.NewFlagSet("verify", pflag.ContinueOnError)
+	p.String("public-key", "", "Public key file, or the signing private key file")
+	p.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s verify --public-key FILE PATH\n\nFlags:\n", appName)
+		p.PrintDefaults()
+	}
+	if err := p.Parse(args); err != nil {
+		return err
+	}
+	if p.NArg() != 1 {
+		p.Usage()
+		return pflag.ErrHelp
+	}
+	key, _ := p.GetString("public-key")
+	if key == "" {
+		return errors.New("verify: missing --public-key")
+	}
+
+	pub, err := signature.ReadPublicKey(key)
+	if err != nil {
+		return fmt.Errorf("verify: %v", err)
+	}
+	sig, err := signature.Verify(p.Arg(0), pub)
+	if err != nil {
+		return fmt.Errorf("verify: %s: %v", p.Arg(0), err)
+	}
+	fmt.Printf("Valid signature of %q (key %s), signed files:\n", p.Arg(0), sig.KeyID)
+	for _, f := range sig.Files {
+		fmt.Printf("  %s\n", f.Path)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package workercmd
 import (
 	"context"
 	"crypto/rand"
+	"net/http"
 
 	"github.com/artefactual-sdps/temporal-activities/bagcreate"
 	"github.com/artefactual-sdps/temporal-activities/ffvalidate"
@@ -15,7 +16,12 @@ import (
 	temporalsdk_workflow "go.temporal.io/sdk/workflow"
 
 	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/audit"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/clamd"
 	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/signature"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/version"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/webhook"
 	"github.com/artefactual-sdps/preprocessing-demo/internal/workflow"
 )
 
@@ -26,6 +32,7 @@ type Main struct {
 	cfg            config.Configuration
 	temporalWorker temporalsdk_worker.Worker
 	temporalClient temporalsdk_client.Client
+	auditDB        *audit.DB
 }
 
 func NewMain(logger logr.Logger, cfg config.Configuration) *Main {
@@ -57,18 +64,124 @@ func (m *Main) Run(ctx context.Context) error {
 	m.temporalWorker = w
 
 	w.RegisterWorkflowWithOptions(
-		workflow.NewPreprocessingWorkflow(m.cfg.SharedPath).Execute,
+		workflow.NewPreprocessingWorkflow(m.cfg).Execute,
 		temporalsdk_workflow.RegisterOptions{Name: m.cfg.Temporal.WorkflowName},
 	)
+	w.RegisterWorkflowWithOptions(
+		workflow.NewBatchPreprocessingWorkflow(m.cfg).Execute,
+		temporalsdk_workflow.RegisterOptions{Name: m.cfg.Temporal.BatchWorkflowName},
+	)
 
 	w.RegisterActivityWithOptions(
-		ffvalidate.New(m.cfg.FileFormat).Execute,
+		activities.Heartbeat(ffvalidate.New(m.cfg.FileFormat).Execute),
 		temporalsdk_activity.RegisterOptions{Name: ffvalidate.Name},
 	)
 	w.RegisterActivityWithOptions(
-		bagcreate.New(m.cfg.Bagit).Execute,
+		activities.Heartbeat(bagcreate.New(m.cfg.Bagit).Execute),
 		temporalsdk_activity.RegisterOptions{Name: bagcreate.Name},
 	)
+	for name := range m.cfg.Profiles {
+		cfg, err := m.cfg.WithProfile(name)
+		if err != nil {
+			return err
+		}
+		w.RegisterActivityWithOptions(
+			activities.Heartbeat(ffvalidate.New(cfg.FileFormat).Execute),
+			temporalsdk_activity.RegisterOptions{Name: workflow.ProfileActivityName(ffvalidate.Name, name)},
+		)
+		w.RegisterActivityWithOptions(
+			activities.Heartbeat(bagcreate.New(cfg.Bagit).Execute),
+			temporalsdk_activity.RegisterOptions{Name: workflow.ProfileActivityName(bagcreate.Name, name)},
+		)
+	}
+	w.RegisterActivityWithOptions(
+		activities.Heartbeat(activities.NewValidateStructure().Execute),
+		temporalsdk_activity.RegisterOptions{Name: activities.ValidateStructureName},
+	)
+	w.RegisterActivityWithOptions(
+		activities.NewVerifyChecksums(m.cfg.Fixity.ManifestNames).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.VerifyChecksumsName},
+	)
+	if m.cfg.ClamAV.Address != "" {
+		clamdClient, err := clamd.New(m.cfg.ClamAV)
+		if err != nil {
+			m.logger.Error(err, "Unable to create clamd client.")
+			return err
+		}
+		w.RegisterActivityWithOptions(
+			activities.NewScanViruses(clamdClient).Execute,
+			temporalsdk_activity.RegisterOptions{Name: activities.ScanVirusesName},
+		)
+	}
+	// The file formats validation identifies the formats too, only identify
+	// them in the check-files step when they're needed.
+	var identifier ffvalidate.FormatIdentifier
+	if m.cfg.Validation.FormatStatistics || len(m.cfg.Validation.DeprecatedFormats) > 0 {
+		identifier = ffvalidate.NewSiegfriedEmbed()
+	}
+	w.RegisterActivityWithOptions(
+		activities.NewCheckFiles(identifier, m.cfg.Validation.DeprecatedFormats).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.CheckFilesName},
+	)
+	w.RegisterActivityWithOptions(
+		activities.NewQuarantineSIP(m.cfg.QuarantinePath).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.QuarantineSIPName},
+	)
+	w.RegisterActivityWithOptions(
+		activities.NewUnbagSIP().Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.UnbagSIPName},
+	)
+	w.RegisterActivityWithOptions(
+		activities.NewMeasureSIP().Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.MeasureSIPName},
+	)
+	w.RegisterActivityWithOptions(
+		activities.NewWritePreprocessingLog(version.Long).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.WritePreprocessingLogName},
+	)
+	w.RegisterActivityWithOptions(
+		activities.NewWriteValidationReport().Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.WriteValidationReportName},
+	)
+	w.RegisterActivityWithOptions(
+		activities.NewNotifyWebhook(webhook.New(&http.Client{}, m.cfg.Webhooks)).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.NotifyWebhookName},
+	)
+	if m.cfg.Audit.DatabasePath != "" {
+		db, err := audit.Open(m.cfg.Audit.DatabasePath)
+		if err != nil {
+			m.logger.Error(err, "Unable to open audit database.")
+			return err
+		}
+		m.auditDB = db
+		w.RegisterActivityWithOptions(
+			activities.NewRecordRun(db).Execute,
+			temporalsdk_activity.RegisterOptions{Name: activities.RecordRunName},
+		)
+	}
+	if m.cfg.Signing.KeyPath != "" {
+		key, err := signature.ReadPrivateKey(m.cfg.Signing.KeyPath)
+		if err != nil {
+			m.logger.Error(err, "Unable to read signing key.")
+			return err
+		}
+		w.RegisterActivityWithOptions(
+			activities.NewSignSIP(key).Execute,
+			temporalsdk_activity.RegisterOptions{Name: activities.SignSIPName},
+		)
+	}
+	w.RegisterActivityWithOptions(
+		activities.NewAddBagInfo().Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.AddBagInfoName},
+	)
+	w.RegisterActivityWithOptions(
+		activities.NewListSIPs(m.cfg.SharedPath).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.ListSIPsName},
+	)
+	w.RegisterActivityWithOptions(
+		activities.NewWriteBatchReport(m.cfg.Batch.ReportPath).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.WriteBatchReportName},
+	)
 	w.RegisterActivityWithOptions(
 		activities.NewAddPREMISAgent().Execute,
 		temporalsdk_activity.RegisterOptions{Name: activities.AddPREMISAgentName},
@@ -99,5 +212,11 @@ func (m *Main) Close() error {
 		m.temporalClient.Close()
 	}
 
+	if m.auditDB != nil {
+		if err := m.auditDB.Close(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
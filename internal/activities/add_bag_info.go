@@ -0,0 +1,122 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const AddBagInfoName = "add-bag-info"
+
+const bagInfoName = "bag-info.txt"
+
+type (
+	// BagInfoTag is a metadata element of the bag-info.txt file.
+	BagInfoTag struct {
+		Label string
+		Value string
+	}
+
+	AddBagInfoParams struct {
+		BagPath string
+
+		// Tags are added to bag-info.txt, replacing the existing tags with the
+		// same labels.
+		Tags []BagInfoTag
+	}
+
+	AddBagInfoResult struct{}
+
+	AddBagInfoActivity struct{}
+)
+
+func NewAddBagInfo() *AddBagInfoActivity {
+	return &AddBagInfoActivity{}
+}
+
+// Execute adds metadata tags to the bag-info.txt file of the bag at BagPath
+// and updates its checksums in the tag manifests.
+func (a *AddBagInfoActivity) Execute(ctx context.Context, params *AddBagInfoParams) (*AddBagInfoResult, error) {
+	if err := addBagInfo(params.BagPath, params.Tags); err != nil {
+		return nil, fmt.Errorf("%s: %v", AddBagInfoName, err)
+	}
+
+	return &AddBagInfoResult{}, nil
+}
+
+func addBagInfo(bagPath string, tags []BagInfoTag) error {
+	labels := map[string]bool{}
+	for _, t := range tags {
+		if t.Label == "" || strings.ContainsAny(t.Label, ":\r\n") || strings.ContainsAny(t.Value, "\r\n") {
+			return fmt.Errorf("invalid tag: %q: %q", t.Label, t.Value)
+		}
+		labels[strings.ToLower(t.Label)] = true
+	}
+
+	p := filepath.Join(bagPath, bagInfoName)
+	b, err := os.ReadFile(p) // #nosec G304 -- path is relative to the bag.
+	if err != nil {
+		return err
+	}
+
+	// Keep the existing tags, and their continuation lines, not replaced.
+	var lines []string
+	replaced := false
+	for _, line := range strings.Split(strings.TrimRight(string(b), "\r\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			label, _, _ := strings.Cut(line, ":")
+			replaced = labels[strings.ToLower(strings.TrimSpace(label))]
+		}
+		if !replaced {
+			lines = append(lines, line)
+		}
+	}
+	for _, t := range tags {
+		lines = append(lines, t.Label+": "+t.Value)
+	}
+
+	if err := os.WriteFile(p, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		return err
+	}
+
+	return updateTagManifests(bagPath, bagInfoName)
+}
+
+// updateTagManifests updates the checksums of the name tag file in the tag
+// manifests of the bag at bagPath.
+func updateTagManifests(bagPath, name string) error {
+	manifests, err := filepath.Glob(filepath.Join(bagPath, "tagmanifest-*.txt"))
+	if err != nil {
+		return err
+	}
+
+	for _, m := range manifests {
+		alg := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), "tagmanifest-"), ".txt")
+		sum, err := fileChecksum(filepath.Join(bagPath, name), alg)
+		if err != nil {
+			return err
+		}
+
+		b, err := os.ReadFile(m) // #nosec G304 -- path is relative to the bag.
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+		for i, line := range lines {
+			if _, path, ok := strings.Cut(line, " "); ok && strings.TrimSpace(path) == name {
+				lines[i] = sum + "  " + name
+			}
+		}
+		if err := os.WriteFile(m, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package activities_test
+
+import (
+	"crypto/md5" // #nosec G501 -- used to compute a tag manifest checksum.
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+)
+
+const bagitTxt = "BagIt-Version: 0.97\nTag-File-Character-Encoding: UTF-8\n"
+
+const bagInfo = `Bag-Software-Agent: bagit.go
+Bagging-Date: 2024-05-01
+Source-Organization: Old organization
+  with a long name
+Payload-Oxum: 9.1
+`
+
+func TestAddBagInfo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		params      activities.AddBagInfoParams
+		wantBagInfo string
+		wantErr     string
+	}{
+		{
+			name: "Adds tags to bag-info.txt",
+			params: activities.AddBagInfoParams{
+				Tags: []activities.BagInfoTag{
+					{Label: "External-Identifier", Value: "c1f5b2a0-7c6a-4c8a-9a49-8f2a5a0e5b1a"},
+					{Label: "SIP-Name", Value: "sip"},
+				},
+			},
+			wantBagInfo: bagInfo + `External-Identifier: c1f5b2a0-7c6a-4c8a-9a49-8f2a5a0e5b1a
+SIP-Name: sip
+`,
+		},
+		{
+			name: "Replaces the tags with the same labels",
+			params: activities.AddBagInfoParams{
+				Tags: []activities.BagInfoTag{
+					{Label: "source-organization", Value: "Artefactual"},
+				},
+			},
+			wantBagInfo: `Bag-Software-Agent: bagit.go
+Bagging-Date: 2024-05-01
+Payload-Oxum: 9.1
+source-organization: Artefactual
+`,
+		},
+		{
+			name: "Errors when a tag value is not valid",
+			params: activities.AddBagInfoParams{
+				Tags: []activities.BagInfoTag{
+					{Label: "SIP-Name", Value: "line\nbreak"},
+				},
+			},
+			wantErr: `add-bag-info: invalid tag: "SIP-Name": "line\nbreak"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			bag := fs.NewDir(t, "",
+				fs.WithFile("bag-info.txt", bagInfo),
+				fs.WithFile("bagit.txt", bagitTxt),
+				fs.WithFile("tagmanifest-md5.txt", "0  bagit.txt\n0  bag-info.txt\n"),
+				fs.WithFile("tagmanifest-sha256.txt", "0  bagit.txt\n0  bag-info.txt\n"),
+				fs.WithDir("data", fs.WithFile("small.txt", "I am small")),
+			)
+
+			ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+			env := ts.NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(
+				activities.NewAddBagInfo().Execute,
+				temporalsdk_activity.RegisterOptions{Name: activities.AddBagInfoName},
+			)
+
+			params := tt.params
+			params.BagPath = bag.Path()
+			_, err := env.ExecuteActivity(activities.AddBagInfoName, &params)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+
+			md5Sum := md5.Sum([]byte(tt.wantBagInfo)) // #nosec G401 -- not used for security.
+			sha256Sum := sha256.Sum256([]byte(tt.wantBagInfo))
+			assert.Assert(t, fs.Equal(bag.Path(), fs.Expected(t, fs.MatchAnyFileMode,
+				fs.WithFile("bag-info.txt", tt.wantBagInfo, fs.MatchAnyFileMode),
+				fs.WithFile("bagit.txt", bagitTxt, fs.MatchAnyFileMode),
+				fs.WithFile(
+					"tagmanifest-md5.txt",
+					"0  bagit.txt\n"+hex.EncodeToString(md5Sum[:])+"  bag-info.txt\n",
+					fs.MatchAnyFileMode,
+				),
+				fs.WithFile(
+					"tagmanifest-sha256.txt",
+					"0  bagit.txt\n"+hex.EncodeToString(sha256Sum[:])+"  bag-info.txt\n",
+					fs.MatchAnyFileMode,
+				),
+				fs.WithDir("data", fs.MatchAnyFileMode, fs.WithFile("small.txt", "I am small", fs.MatchAnyFileMode)),
+			)))
+		})
+	}
+}
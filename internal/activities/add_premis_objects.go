@@ -14,10 +14,18 @@ import (
 
 const AddPREMISObjectsName = "add-premis-objects"
 
+// premisObjectsCheckpoint is the number of objects added between two writes of
+// the PREMIS file, a retry resumes from the objects of the last write.
+const premisObjectsCheckpoint = 1000
+
 type (
 	AddPREMISObjectsParams struct {
 		SIPPath        string
 		PREMISFilePath string
+
+		// IntellectualEntity is the SIP object, added after its files if not
+		// nil.
+		IntellectualEntity *premis.IntellectualEntity
 	}
 
 	AddPREMISObjectsResult struct{}
@@ -52,7 +60,31 @@ func (a *AddPREMISObjectsActivity) Execute(
 		return nil, err
 	}
 
+	// Objects of the files added by a previous attempt are already in the
+	// PREMIS file, which wasn't part of the transfer files then. They are
+	// taken from the file rather than from the heartbeat progress, which may
+	// not have been recorded before the worker died.
+	added, err := premis.FileOriginalNames(doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(added) > 0 {
+		rel, err := filepath.Rel(params.SIPPath, params.PREMISFilePath)
+		if err != nil {
+			return nil, err
+		}
+		added[rel] = true
+	}
+
+	h := startHeartbeat(ctx)
+	defer h.stop()
+
+	n := 0
 	for _, subpath := range subpaths {
+		if added[subpath] {
+			continue
+		}
+
 		id, err := uuid.NewRandomFromReader(a.rng)
 		if err != nil {
 			return nil, fmt.Errorf("generate UUID: %v", err)
@@ -68,6 +100,21 @@ func (a *AddPREMISObjectsActivity) Execute(
 		if err != nil {
 			return nil, err
 		}
+
+		n++
+		if n%premisObjectsCheckpoint == 0 {
+			if err := premis.WriteIndentedToFile(doc, params.PREMISFilePath); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Files are matched by name when added, so the SIP is added last to not be
+	// mistaken for a file with the same name.
+	if params.IntellectualEntity != nil {
+		if err := premis.AppendIntellectualEntityXML(doc, *params.IntellectualEntity); err != nil {
+			return nil, err
+		}
 	}
 
 	err = premis.WriteIndentedToFile(doc, params.PREMISFilePath)
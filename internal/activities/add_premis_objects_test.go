@@ -11,6 +11,7 @@ import (
 	"gotest.tools/v3/fs"
 
 	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/premis"
 )
 
 const expectedPREMISWithFile = `<?xml version="1.0" encoding="UTF-8"?>
@@ -32,10 +33,88 @@ const expectedPREMISWithFile = `<?xml version="1.0" encoding="UTF-8"?>
 </premis:premis>
 `
 
+const expectedPREMISWithIntellectualEntity = `<?xml version="1.0" encoding="UTF-8"?>
+<premis:premis xmlns:premis="http://www.loc.gov/premis/v3" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.loc.gov/premis/v3 https://www.loc.gov/standards/premis/premis.xsd" version="3.0">
+  <premis:object xsi:type="premis:file">
+    <premis:objectIdentifier>
+      <premis:objectIdentifierType>UUID</premis:objectIdentifierType>
+      <premis:objectIdentifierValue>52fdfc07-2182-454f-963f-5f0f9a621d72</premis:objectIdentifierValue>
+    </premis:objectIdentifier>
+    <premis:objectCharacteristics>
+      <premis:format>
+        <premis:formatDesignation>
+          <premis:formatName></premis:formatName>
+        </premis:formatDesignation>
+      </premis:format>
+    </premis:objectCharacteristics>
+    <premis:originalName>sip</premis:originalName>
+  </premis:object>
+  <premis:object xsi:type="premis:intellectualEntity">
+    <premis:objectIdentifier>
+      <premis:objectIdentifierType>UUID</premis:objectIdentifierType>
+      <premis:objectIdentifierValue>6f3bb3e0-3a4e-4c8f-9d3e-5e7b1c1f7e6a</premis:objectIdentifierValue>
+    </premis:objectIdentifier>
+    <premis:originalName>sip</premis:originalName>
+  </premis:object>
+</premis:premis>
+`
+
 const expectedPREMISNoFiles = `<?xml version="1.0" encoding="UTF-8"?>
 <premis:premis xmlns:premis="http://www.loc.gov/premis/v3" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.loc.gov/premis/v3 https://www.loc.gov/standards/premis/premis.xsd" version="3.0"></premis:premis>
 `
 
+const checkpointPREMIS = `<?xml version="1.0" encoding="UTF-8"?>
+<premis:premis xmlns:premis="http://www.loc.gov/premis/v3" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.loc.gov/premis/v3 https://www.loc.gov/standards/premis/premis.xsd" version="3.0">
+  <premis:object xsi:type="premis:file">
+    <premis:objectIdentifier>
+      <premis:objectIdentifierType>UUID</premis:objectIdentifierType>
+      <premis:objectIdentifierValue>9566c74d-1003-4c4d-bbbb-0407d1e2c649</premis:objectIdentifierValue>
+    </premis:objectIdentifier>
+    <premis:objectCharacteristics>
+      <premis:format>
+        <premis:formatDesignation>
+          <premis:formatName></premis:formatName>
+        </premis:formatDesignation>
+      </premis:format>
+    </premis:objectCharacteristics>
+    <premis:originalName>a.txt</premis:originalName>
+  </premis:object>
+</premis:premis>
+`
+
+const expectedPREMISResumed = `<?xml version="1.0" encoding="UTF-8"?>
+<premis:premis xmlns:premis="http://www.loc.gov/premis/v3" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.loc.gov/premis/v3 https://www.loc.gov/standards/premis/premis.xsd" version="3.0">
+  <premis:object xsi:type="premis:file">
+    <premis:objectIdentifier>
+      <premis:objectIdentifierType>UUID</premis:objectIdentifierType>
+      <premis:objectIdentifierValue>9566c74d-1003-4c4d-bbbb-0407d1e2c649</premis:objectIdentifierValue>
+    </premis:objectIdentifier>
+    <premis:objectCharacteristics>
+      <premis:format>
+        <premis:formatDesignation>
+          <premis:formatName></premis:formatName>
+        </premis:formatDesignation>
+      </premis:format>
+    </premis:objectCharacteristics>
+    <premis:originalName>a.txt</premis:originalName>
+  </premis:object>
+  <premis:object xsi:type="premis:file">
+    <premis:objectIdentifier>
+      <premis:objectIdentifierType>UUID</premis:objectIdentifierType>
+      <premis:objectIdentifierValue>52fdfc07-2182-454f-963f-5f0f9a621d72</premis:objectIdentifierValue>
+    </premis:objectIdentifier>
+    <premis:objectCharacteristics>
+      <premis:format>
+        <premis:formatDesignation>
+          <premis:formatName></premis:formatName>
+        </premis:formatDesignation>
+      </premis:format>
+    </premis:objectCharacteristics>
+    <premis:originalName>b.txt</premis:originalName>
+  </premis:object>
+</premis:premis>
+`
+
 func TestAddPREMISObjects(t *testing.T) {
 	t.Parallel()
 
@@ -44,9 +123,24 @@ func TestAddPREMISObjects(t *testing.T) {
 		fs.WithFile("somefile.txt", "somestuff"),
 	)
 
+	// Test transfer with a file named like the SIP.
+	transferSIPFile := fs.NewDir(t, "",
+		fs.WithFile("sip", "somestuff"),
+	)
+
 	// Test transfer with no files.
 	transferNoFiles := fs.NewDir(t, "")
 
+	// Test transfer with the PREMIS file written by a previous attempt whose
+	// worker died before recording a heartbeat.
+	transferCheckpoint := fs.NewDir(t, "",
+		fs.WithFile("a.txt", "somestuff"),
+		fs.WithFile("b.txt", "otherstuff"),
+		fs.WithDir("metadata",
+			fs.WithFile("premis.xml", checkpointPREMIS),
+		),
+	)
+
 	tests := []struct {
 		name       string
 		params     activities.AddPREMISObjectsParams
@@ -63,6 +157,21 @@ func TestAddPREMISObjects(t *testing.T) {
 			result:     activities.AddPREMISObjectsResult{},
 			wantPREMIS: expectedPREMISWithFile,
 		},
+		{
+			name: "Add PREMIS objects for transfer with an intellectual entity",
+			params: activities.AddPREMISObjectsParams{
+				SIPPath:        transferSIPFile.Path(),
+				PREMISFilePath: transferSIPFile.Join("metadata", "premis.xml"),
+				IntellectualEntity: &premis.IntellectualEntity{
+					Identifiers: []premis.ObjectIdentifier{
+						{IdType: "UUID", IdValue: "6f3bb3e0-3a4e-4c8f-9d3e-5e7b1c1f7e6a"},
+					},
+					OriginalName: "sip",
+				},
+			},
+			result:     activities.AddPREMISObjectsResult{},
+			wantPREMIS: expectedPREMISWithIntellectualEntity,
+		},
 		{
 			name: "Add PREMIS objects for empty transfer",
 			params: activities.AddPREMISObjectsParams{
@@ -72,6 +181,15 @@ func TestAddPREMISObjects(t *testing.T) {
 			result:     activities.AddPREMISObjectsResult{},
 			wantPREMIS: expectedPREMISNoFiles,
 		},
+		{
+			name: "Resumes from a PREMIS file written without a matching heartbeat",
+			params: activities.AddPREMISObjectsParams{
+				SIPPath:        transferCheckpoint.Path(),
+				PREMISFilePath: transferCheckpoint.Join("metadata", "premis.xml"),
+			},
+			result:     activities.AddPREMISObjectsResult{},
+			wantPREMIS: expectedPREMISResumed,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,176 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/artefactual-sdps/temporal-activities/ffvalidate"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/sipstats"
+)
+
+const CheckFilesName = "check-files"
+
+type (
+	CheckFilesParams struct {
+		SIPPath string
+	}
+
+	CheckFilesResult struct {
+		// Checked is the number of files checked.
+		Checked int
+
+		// Size is the total size of the files checked, in bytes.
+		Size int64
+
+		// Stats are the statistics of the files checked, broken down by
+		// format if the formats were identified.
+		Stats sipstats.Stats
+
+		// Failures lists the empty files, unusual file names and deprecated
+		// file formats found.
+		Failures []eventlog.Failure
+	}
+
+	// CheckFilesProgress is the heartbeat details of the check-files activity,
+	// a retry resumes after the Walked files and directories.
+	CheckFilesProgress struct {
+		Result CheckFilesResult
+		Walked int
+	}
+
+	CheckFilesActivity struct {
+		identifier        ffvalidate.FormatIdentifier
+		deprecatedFormats []string
+	}
+)
+
+// NewCheckFiles returns an activity that checks the SIP for empty files,
+// unusual file and directory names and files in one of the deprecatedFormats
+// (PRONOM PUIDs), identified with identifier, and computes the statistics of
+// the SIP files. Formats aren't identified if identifier is nil.
+func NewCheckFiles(identifier ffvalidate.FormatIdentifier, deprecatedFormats []string) *CheckFilesActivity {
+	return &CheckFilesActivity{
+		identifier:        identifier,
+		deprecatedFormats: deprecatedFormats,
+	}
+}
+
+func (a *CheckFilesActivity) Execute(ctx context.Context, params *CheckFilesParams) (*CheckFilesResult, error) {
+	progress, _ := resumeProgress[CheckFilesProgress](ctx)
+	res := &progress.Result
+	skip := progress.Walked
+
+	h := startHeartbeat(ctx)
+	defer h.stop()
+
+	err := filepath.WalkDir(params.SIPPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == params.SIPPath {
+			return nil
+		}
+		if skip > 0 {
+			skip--
+			return nil
+		}
+
+		if err := a.check(res, params.SIPPath, p, d); err != nil {
+			return err
+		}
+		progress.Walked++
+
+		// The walk updates the formats in place, heartbeat a copy.
+		snapshot := *res
+		snapshot.Stats.Formats = slices.Clone(res.Stats.Formats)
+		h.update(CheckFilesProgress{Result: snapshot, Walked: progress.Walked})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", CheckFilesName, err)
+	}
+
+	return res, nil
+}
+
+// check adds the failures of the file or directory at p to res.
+func (a *CheckFilesActivity) check(res *CheckFilesResult, sipPath, p string, d fs.DirEntry) error {
+	rel, err := filepath.Rel(sipPath, p)
+	if err != nil {
+		return err
+	}
+
+	if unusualName(d.Name()) {
+		res.Failures = append(res.Failures, eventlog.Failure{
+			Path:   rel,
+			Check:  "file name",
+			Code:   "unusual-file-name",
+			Params: messages.Params{"path": rel},
+		}.Localize(messages.DefaultLanguage))
+	}
+	if d.IsDir() {
+		res.Stats.AddDir(rel)
+		return nil
+	}
+
+	res.Checked++
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+	res.Size += info.Size()
+	if info.Size() == 0 {
+		res.Failures = append(res.Failures, eventlog.Failure{
+			Path:   rel,
+			Check:  "empty file",
+			Code:   "empty-file",
+			Params: messages.Params{"path": rel},
+		}.Localize(messages.DefaultLanguage))
+	}
+
+	// Empty files have no format, siegfried fails to identify them.
+	var puid string
+	if a.identifier != nil && info.Size() > 0 {
+		ff, err := a.identifier.Identify(p)
+		if err != nil {
+			return fmt.Errorf("identify format of %q: %v", rel, err)
+		}
+		puid = ff.ID
+	}
+	res.Stats.AddFile(rel, info.Size(), puid)
+
+	if puid != "" && slices.Contains(a.deprecatedFormats, puid) {
+		res.Failures = append(res.Failures, eventlog.Failure{
+			Path:   rel,
+			Check:  "deprecated format",
+			Code:   "deprecated-format",
+			Params: messages.Params{"puid": puid, "path": rel},
+			PUID:   puid,
+		}.Localize(messages.DefaultLanguage))
+	}
+
+	return nil
+}
+
+// unusualName reports whether name is likely to cause problems in other
+// systems: invalid UTF-8, control characters, characters reserved on Windows,
+// or leading or trailing spaces or a trailing period.
+func unusualName(name string) bool {
+	if !utf8.ValidString(name) || strings.TrimSpace(name) != name || strings.HasSuffix(name, ".") {
+		return true
+	}
+
+	return strings.ContainsFunc(name, func(r rune) bool {
+		return unicode.IsControl(r) || strings.ContainsRune(`<>:"\|?*`, r)
+	})
+}
@@ -0,0 +1,289 @@
+package activities_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artefactual-sdps/temporal-activities/ffvalidate"
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_converter "go.temporal.io/sdk/converter"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/sipstats"
+)
+
+// extIdentifier identifies file formats by file extension. Like siegfried, it
+// fails to identify empty files.
+type extIdentifier map[string]string
+
+func (i extIdentifier) Identify(path string) (*ffvalidate.FileFormat, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return nil, errors.New("empty source")
+	}
+
+	return &ffvalidate.FileFormat{Namespace: "PRONOM", ID: i[filepath.Ext(path)]}, nil
+}
+
+func (i extIdentifier) Version() string {
+	return "1.0.0"
+}
+
+// slowIdentifier identifies the format of every file as fmt/1, after a delay.
+type slowIdentifier struct{}
+
+func (i slowIdentifier) Identify(path string) (*ffvalidate.FileFormat, error) {
+	time.Sleep(time.Millisecond)
+	return &ffvalidate.FileFormat{Namespace: "PRONOM", ID: "fmt/1"}, nil
+}
+
+func (i slowIdentifier) Version() string {
+	return "1.0.0"
+}
+
+func TestCheckFiles(t *testing.T) {
+	t.Parallel()
+
+	identifier := extIdentifier{".txt": "x-fmt/111", ".doc": "fmt/40"}
+
+	tests := []struct {
+		name              string
+		identifier        ffvalidate.FormatIdentifier
+		deprecatedFormats []string
+		sipPath           string
+		progress          *activities.CheckFilesProgress
+		want              activities.CheckFilesResult
+		wantErr           string
+	}{
+		{
+			name: "Checks a SIP without problems",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("small.txt", smallContent),
+				fs.WithDir("content",
+					fs.WithFile("file-1_v2.doc", "Word document"),
+				),
+			).Path(),
+			identifier:        identifier,
+			deprecatedFormats: []string{"fmt/39"},
+			want: activities.CheckFilesResult{
+				Checked: 2,
+				Size:    int64(len(smallContent) + len("Word document")),
+				Stats: sipstats.Stats{
+					Files: 2,
+					Size:  int64(len(smallContent) + len("Word document")),
+					Formats: []sipstats.Format{
+						{PUID: "fmt/40", Files: 1, Size: int64(len("Word document"))},
+						{PUID: "x-fmt/111", Files: 1, Size: int64(len(smallContent))},
+					},
+					LargestFile: sipstats.File{Path: "small.txt", Size: int64(len(smallContent))},
+					DeepestPath: "content/file-1_v2.doc",
+					Depth:       2,
+				},
+			},
+		},
+		{
+			name: "Reports empty files, unusual names and deprecated formats",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("empty.txt", ""),
+				fs.WithFile("what?.txt", smallContent),
+				fs.WithDir("content. ",
+					fs.WithFile("report.doc", "Word document"),
+				),
+			).Path(),
+			identifier:        identifier,
+			deprecatedFormats: []string{"fmt/40"},
+			want: activities.CheckFilesResult{
+				Checked: 3,
+				Size:    int64(len(smallContent) + len("Word document")),
+				Stats: sipstats.Stats{
+					Files: 3,
+					Size:  int64(len(smallContent) + len("Word document")),
+					Formats: []sipstats.Format{
+						{PUID: "fmt/40", Files: 1, Size: int64(len("Word document"))},
+						{PUID: "x-fmt/111", Files: 1, Size: int64(len(smallContent))},
+					},
+					LargestFile: sipstats.File{Path: "what?.txt", Size: int64(len(smallContent))},
+					DeepestPath: "content. /report.doc",
+					Depth:       2,
+				},
+				Failures: []eventlog.Failure{
+					{
+						Path:    "content. ",
+						Check:   "file name",
+						Code:    "unusual-file-name",
+						Params:  messages.Params{"path": "content. "},
+						Message: `unusual file name: "content. "`,
+					},
+					{
+						Path:    "content. /report.doc",
+						Check:   "deprecated format",
+						Code:    "deprecated-format",
+						Params:  messages.Params{"path": "content. /report.doc", "puid": "fmt/40"},
+						Message: `file format "fmt/40" is deprecated: "content. /report.doc"`,
+						PUID:    "fmt/40",
+					},
+					{
+						Path:    "empty.txt",
+						Check:   "empty file",
+						Code:    "empty-file",
+						Params:  messages.Params{"path": "empty.txt"},
+						Message: `empty file: "empty.txt"`,
+					},
+					{
+						Path:    "what?.txt",
+						Check:   "file name",
+						Code:    "unusual-file-name",
+						Params:  messages.Params{"path": "what?.txt"},
+						Message: `unusual file name: "what?.txt"`,
+					},
+				},
+			},
+		},
+		{
+			name: "Doesn't identify formats without an identifier",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("report.doc", "Word document"),
+			).Path(),
+			deprecatedFormats: []string{"fmt/40"},
+			want: activities.CheckFilesResult{
+				Checked: 1,
+				Size:    int64(len("Word document")),
+				Stats: sipstats.Stats{
+					Files:       1,
+					Size:        int64(len("Word document")),
+					LargestFile: sipstats.File{Path: "report.doc", Size: int64(len("Word document"))},
+					DeepestPath: "report.doc",
+					Depth:       1,
+				},
+			},
+		},
+		{
+			name: "Resumes after the files checked by a previous attempt",
+			sipPath: fs.NewDir(t, "",
+				fs.WithDir("a",
+					fs.WithFile("empty.txt", ""),
+				),
+				fs.WithFile("b.txt", smallContent),
+			).Path(),
+			identifier: identifier,
+			progress: &activities.CheckFilesProgress{
+				Result: activities.CheckFilesResult{
+					Checked: 1,
+					Stats: sipstats.Stats{
+						Files:       1,
+						LargestFile: sipstats.File{Path: "a/empty.txt"},
+						DeepestPath: "a/empty.txt",
+						Depth:       2,
+					},
+				},
+				Walked: 2,
+			},
+			want: activities.CheckFilesResult{
+				Checked: 2,
+				Size:    int64(len(smallContent)),
+				Stats: sipstats.Stats{
+					Files:       2,
+					Size:        int64(len(smallContent)),
+					Formats:     []sipstats.Format{{PUID: "x-fmt/111", Files: 1, Size: int64(len(smallContent))}},
+					LargestFile: sipstats.File{Path: "b.txt", Size: int64(len(smallContent))},
+					DeepestPath: "a/empty.txt",
+					Depth:       2,
+				},
+			},
+		},
+		{
+			name:    "Errors when the SIP path doesn't exist",
+			sipPath: filepath.Join(t.TempDir(), "missing"),
+			wantErr: "no such file or directory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+			env := ts.NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(
+				activities.NewCheckFiles(tt.identifier, tt.deprecatedFormats).Execute,
+				temporalsdk_activity.RegisterOptions{Name: activities.CheckFilesName},
+			)
+			if tt.progress != nil {
+				env.SetHeartbeatDetails(tt.progress)
+			}
+
+			future, err := env.ExecuteActivity(
+				activities.CheckFilesName,
+				&activities.CheckFilesParams{SIPPath: tt.sipPath},
+			)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+
+			var res activities.CheckFilesResult
+			future.Get(&res)
+			assert.DeepEqual(t, res, tt.want)
+		})
+	}
+}
+
+// TestCheckFilesHeartbeat checks that the heartbeats recorded during the walk
+// don't race with the walk updating the statistics, run it with -race.
+func TestCheckFilesHeartbeat(t *testing.T) {
+	activities.SetHeartbeatInterval(t, 5*time.Millisecond)
+
+	const files = 50
+	ops := make([]fs.PathOp, files)
+	for i := range files {
+		ops[i] = fs.WithFile(fmt.Sprintf("file-%02d.txt", i), "a")
+	}
+	sip := fs.NewDir(t, "", ops...)
+
+	ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+	env := ts.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(
+		activities.NewCheckFiles(slowIdentifier{}, nil).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.CheckFilesName},
+	)
+	var heartbeats []activities.CheckFilesProgress
+	env.SetOnActivityHeartbeatListener(func(_ *temporalsdk_activity.Info, details temporalsdk_converter.EncodedValues) {
+		var progress activities.CheckFilesProgress
+		if details.HasValues() && details.Get(&progress) == nil {
+			heartbeats = append(heartbeats, progress)
+		}
+	})
+
+	future, err := env.ExecuteActivity(
+		activities.CheckFilesName,
+		&activities.CheckFilesParams{SIPPath: sip.Path()},
+	)
+	assert.NilError(t, err)
+
+	var res activities.CheckFilesResult
+	future.Get(&res)
+	assert.DeepEqual(t, res.Stats.Formats, []sipstats.Format{{PUID: "fmt/1", Files: files, Size: files}})
+
+	// The heartbeats have the progress of the walk when they were recorded.
+	assert.Assert(t, len(heartbeats) > 0)
+	for _, p := range heartbeats {
+		assert.Assert(t, p.Walked < files)
+		assert.DeepEqual(
+			t,
+			p.Result.Stats.Formats,
+			[]sipstats.Format{{PUID: "fmt/1", Files: p.Walked, Size: int64(p.Walked)}},
+		)
+	}
+}
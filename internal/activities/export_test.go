@@ -0,0 +1,14 @@
+package activities
+
+import (
+	"testing"
+	"time"
+)
+
+// SetHeartbeatInterval sets the interval of the activity heartbeats for the
+// duration of the test t, which must not run in parallel.
+func SetHeartbeatInterval(t *testing.T, d time.Duration) {
+	prev := heartbeatInterval
+	heartbeatInterval = d
+	t.Cleanup(func() { heartbeatInterval = prev })
+}
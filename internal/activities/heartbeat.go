@@ -0,0 +1,105 @@
+package activities
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+)
+
+// heartbeatInterval is the maximum time between the heartbeats of a
+// long-running activity.
+var heartbeatInterval = 10 * time.Second
+
+// heartbeater records the heartbeats of a long-running activity in the
+// background with its latest progress details, so a dead worker is detected
+// before the activity times out and a retry can resume from the progress.
+type heartbeater struct {
+	mu      sync.Mutex
+	details any
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// startHeartbeat starts recording heartbeats for the activity running with
+// ctx, every heartbeatInterval or half the activity heartbeat timeout if it's
+// shorter. It does nothing outside an activity, e.g. in unit tests. The
+// heartbeater must be stopped before the activity returns.
+func startHeartbeat(ctx context.Context) *heartbeater {
+	h := &heartbeater{cancel: func() {}, done: make(chan struct{})}
+	if !temporalsdk_activity.IsActivity(ctx) {
+		close(h.done)
+		return h
+	}
+
+	interval := heartbeatInterval
+	if t := temporalsdk_activity.GetInfo(ctx).HeartbeatTimeout; t > 0 {
+		interval = min(interval, t/2)
+	}
+
+	ctx, h.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(h.done)
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				h.mu.Lock()
+				details := h.details
+				h.mu.Unlock()
+				temporalsdk_activity.RecordHeartbeat(ctx, details)
+			}
+		}
+	}()
+
+	return h
+}
+
+// update sets the progress details sent with the next heartbeats. details must
+// not be modified afterwards, pass a copy of the activity progress.
+func (h *heartbeater) update(details any) {
+	h.mu.Lock()
+	h.details = details
+	h.mu.Unlock()
+}
+
+// stop stops recording heartbeats.
+func (h *heartbeater) stop() {
+	h.cancel()
+	<-h.done
+}
+
+// resumeProgress returns the progress details of the last heartbeat recorded
+// by a previous attempt of the activity running with ctx, or false if there is
+// no progress to resume from.
+func resumeProgress[T any](ctx context.Context) (T, bool) {
+	var progress T
+	if !temporalsdk_activity.IsActivity(ctx) || !temporalsdk_activity.HasHeartbeatDetails(ctx) {
+		return progress, false
+	}
+	if err := temporalsdk_activity.GetHeartbeatDetails(ctx, &progress); err != nil {
+		return *new(T), false
+	}
+
+	return progress, true
+}
+
+// Heartbeat wraps a long-running activity that doesn't record heartbeats
+// itself, e.g. from the temporal-activities module, recording heartbeats
+// without progress details while it runs.
+func Heartbeat[P, R any](
+	execute func(context.Context, P) (R, error),
+) func(context.Context, P) (R, error) {
+	return func(ctx context.Context, params P) (R, error) {
+		h := startHeartbeat(ctx)
+		defer h.stop()
+
+		return execute(ctx, params)
+	}
+}
@@ -0,0 +1,55 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const ListSIPsName = "list-sips"
+
+type (
+	ListSIPsParams struct {
+		// Path is the directory to list, relative to the shared path.
+		Path string
+	}
+
+	ListSIPsResult struct {
+		// RelativePaths are the paths of the SIPs found, relative to the
+		// shared path, in lexical order.
+		RelativePaths []string
+	}
+
+	ListSIPsActivity struct {
+		sharedPath string
+	}
+)
+
+func NewListSIPs(sharedPath string) *ListSIPsActivity {
+	return &ListSIPsActivity{sharedPath: sharedPath}
+}
+
+// Execute lists the SIPs in a directory of the shared path, i.e. its entries
+// that aren't hidden.
+func (a *ListSIPsActivity) Execute(ctx context.Context, params *ListSIPsParams) (*ListSIPsResult, error) {
+	if !filepath.IsLocal(params.Path) {
+		return nil, fmt.Errorf("%s: invalid path: %q", ListSIPsName, params.Path)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(a.sharedPath, params.Path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ListSIPsName, err)
+	}
+
+	res := &ListSIPsResult{}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		res.RelativePaths = append(res.RelativePaths, filepath.Join(params.Path, e.Name()))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,78 @@
+package activities_test
+
+import (
+	"testing"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+)
+
+func TestListSIPs(t *testing.T) {
+	t.Parallel()
+
+	shared := fs.NewDir(t, "",
+		fs.WithDir("batch",
+			fs.WithDir("sip-b", fs.WithFile("file.txt", "somestuff")),
+			fs.WithDir("sip-a", fs.WithFile("file.txt", "somestuff")),
+			fs.WithFile("sip-c.zip", "not a zip"),
+			fs.WithFile(".DS_Store", ""),
+		),
+	)
+
+	tests := []struct {
+		name    string
+		path    string
+		want    activities.ListSIPsResult
+		wantErr string
+	}{
+		{
+			name: "Lists the SIPs in a directory",
+			path: "batch",
+			want: activities.ListSIPsResult{
+				RelativePaths: []string{"batch/sip-a", "batch/sip-b", "batch/sip-c.zip"},
+			},
+		},
+		{
+			name: "Lists the SIPs in the shared path",
+			path: ".",
+			want: activities.ListSIPsResult{RelativePaths: []string{"batch"}},
+		},
+		{
+			name:    "Errors when the directory is outside the shared path",
+			path:    "../batch",
+			wantErr: `list-sips: invalid path: "../batch"`,
+		},
+		{
+			name:    "Errors when the directory doesn't exist",
+			path:    "missing",
+			wantErr: "list-sips: open " + shared.Join("missing") + ": no such file or directory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+			env := ts.NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(
+				activities.NewListSIPs(shared.Path()).Execute,
+				temporalsdk_activity.RegisterOptions{Name: activities.ListSIPsName},
+			)
+
+			future, err := env.ExecuteActivity(activities.ListSIPsName, &activities.ListSIPsParams{Path: tt.path})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+
+			var res activities.ListSIPsResult
+			future.Get(&res)
+			assert.DeepEqual(t, res, tt.want)
+		})
+	}
+}
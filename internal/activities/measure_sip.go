@@ -0,0 +1,63 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+const MeasureSIPName = "measure-sip"
+
+type (
+	MeasureSIPParams struct {
+		SIPPath string
+	}
+
+	MeasureSIPResult struct {
+		// Files is the number of files in the SIP.
+		Files int
+
+		// Size is the total size of the SIP files, in bytes.
+		Size int64
+	}
+
+	MeasureSIPActivity struct{}
+)
+
+// NewMeasureSIP returns an activity that measures the number and size of the
+// SIP files, only reading the file system metadata. The workflow scales the
+// timeouts of the activities processing every SIP file with the SIP size.
+func NewMeasureSIP() *MeasureSIPActivity {
+	return &MeasureSIPActivity{}
+}
+
+func (a *MeasureSIPActivity) Execute(ctx context.Context, params *MeasureSIPParams) (*MeasureSIPResult, error) {
+	h := startHeartbeat(ctx)
+	defer h.stop()
+
+	res := &MeasureSIPResult{}
+	err := filepath.WalkDir(params.SIPPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		res.Files++
+		res.Size += info.Size()
+		h.update(*res)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", MeasureSIPName, err)
+	}
+
+	return res, nil
+}
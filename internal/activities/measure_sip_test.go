@@ -0,0 +1,73 @@
+package activities_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+)
+
+func TestMeasureSIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		sipPath string
+		want    activities.MeasureSIPResult
+		wantErr string
+	}{
+		{
+			name: "Measures the SIP files",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("small.txt", smallContent),
+				fs.WithDir("content",
+					fs.WithFile("file.txt", "somestuff"),
+					fs.WithFile("empty.txt", ""),
+					fs.WithDir("empty"),
+				),
+			).Path(),
+			want: activities.MeasureSIPResult{Files: 3, Size: int64(len(smallContent) + len("somestuff"))},
+		},
+		{
+			name:    "Measures an empty SIP",
+			sipPath: t.TempDir(),
+			want:    activities.MeasureSIPResult{},
+		},
+		{
+			name:    "Errors when the SIP doesn't exist",
+			sipPath: filepath.Join(t.TempDir(), "missing"),
+			wantErr: "measure-sip: lstat ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+			env := ts.NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(
+				activities.NewMeasureSIP().Execute,
+				temporalsdk_activity.RegisterOptions{Name: activities.MeasureSIPName},
+			)
+
+			future, err := env.ExecuteActivity(
+				activities.MeasureSIPName,
+				&activities.MeasureSIPParams{SIPPath: tt.sipPath},
+			)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+
+			var res activities.MeasureSIPResult
+			future.Get(&res)
+			assert.DeepEqual(t, res, tt.want)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.artefactual.dev/tools/temporal"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/webhook"
+)
+
+const NotifyWebhookName = "notify-webhook"
+
+type (
+	NotifyWebhookParams struct {
+		// URL is the URL of the configured endpoint to notify.
+		URL string
+
+		Payload webhook.Payload
+	}
+
+	NotifyWebhookResult struct{}
+
+	NotifyWebhookActivity struct {
+		client *webhook.Client
+	}
+)
+
+func NewNotifyWebhook(client *webhook.Client) *NotifyWebhookActivity {
+	return &NotifyWebhookActivity{client: client}
+}
+
+// Execute posts the signed payload to the endpoint. A notification rejected
+// with a client error other than a timeout or a rate limit is not retried.
+func (a *NotifyWebhookActivity) Execute(
+	ctx context.Context,
+	params *NotifyWebhookParams,
+) (*NotifyWebhookResult, error) {
+	if err := a.client.Notify(ctx, params.URL, params.Payload); err != nil {
+		var statusErr *webhook.StatusError
+		if errors.As(err, &statusErr) && !statusErr.Temporary() {
+			return nil, temporal.NewNonRetryableError(fmt.Errorf("%s: %v", NotifyWebhookName, err))
+		}
+		return nil, fmt.Errorf("%s: %v", NotifyWebhookName, err)
+	}
+
+	return &NotifyWebhookResult{}, nil
+}
@@ -0,0 +1,85 @@
+package activities_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_temporal "go.temporal.io/sdk/temporal"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/webhook"
+)
+
+func TestNotifyWebhook(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name             string
+		status           int
+		wantErr          string
+		wantNonRetryable bool
+	}{
+		{
+			name:   "Notifies the endpoint",
+			status: http.StatusOK,
+		},
+		{
+			name:    "Retries a server error",
+			status:  http.StatusServiceUnavailable,
+			wantErr: "notify-webhook: unexpected status 503: unavailable",
+		},
+		{
+			name:             "Doesn't retry a client error",
+			status:           http.StatusUnauthorized,
+			wantErr:          "notify-webhook: unexpected status 401: unavailable",
+			wantNonRetryable: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var signed bool
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				signed = webhook.Verify("s3cr3t", body, r.Header.Get(webhook.SignatureHeader))
+				if tt.status != http.StatusOK {
+					http.Error(w, "unavailable", tt.status)
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+			env := ts.NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(
+				activities.NewNotifyWebhook(
+					webhook.New(srv.Client(), []webhook.Endpoint{{URL: srv.URL, Secret: "s3cr3t"}}),
+				).Execute,
+				temporalsdk_activity.RegisterOptions{Name: activities.NotifyWebhookName},
+			)
+
+			_, err := env.ExecuteActivity(
+				activities.NotifyWebhookName,
+				&activities.NotifyWebhookParams{
+					URL:     srv.URL,
+					Payload: webhook.Payload{WorkflowID: "preprocessing-1", Outcome: "success"},
+				},
+			)
+			assert.Assert(t, signed)
+			if tt.wantErr == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+
+			var appErr *temporalsdk_temporal.ApplicationError
+			assert.Assert(t, errors.As(err, &appErr))
+			assert.Equal(t, appErr.NonRetryable(), tt.wantNonRetryable)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/quarantine"
+)
+
+const QuarantineSIPName = "quarantine-sip"
+
+type (
+	QuarantineSIPParams struct {
+		SIPPath string
+		Report  quarantine.Report
+	}
+
+	QuarantineSIPResult struct {
+		// Path is the path of the SIP in quarantine.
+		Path string
+	}
+
+	QuarantineSIPActivity struct {
+		quarantinePath string
+	}
+)
+
+func NewQuarantineSIP(quarantinePath string) *QuarantineSIPActivity {
+	return &QuarantineSIPActivity{quarantinePath: quarantinePath}
+}
+
+func (a *QuarantineSIPActivity) Execute(
+	ctx context.Context,
+	params *QuarantineSIPParams,
+) (*QuarantineSIPResult, error) {
+	// Moving the SIP to another file system copies every file.
+	h := startHeartbeat(ctx)
+	defer h.stop()
+
+	p, err := quarantine.Quarantine(params.SIPPath, a.quarantinePath, params.Report)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", QuarantineSIPName, err)
+	}
+
+	return &QuarantineSIPResult{Path: p}, nil
+}
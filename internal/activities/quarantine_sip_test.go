@@ -0,0 +1,52 @@
+package activities_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/quarantine"
+)
+
+func TestQuarantineSIP(t *testing.T) {
+	t.Parallel()
+
+	sip := fs.NewDir(t, "", fs.WithFile("somefile.txt", "somestuff"))
+	qdir := fs.NewDir(t, "")
+
+	ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+	env := ts.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(
+		activities.NewQuarantineSIP(qdir.Path()).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.QuarantineSIPName},
+	)
+
+	future, err := env.ExecuteActivity(
+		activities.QuarantineSIPName,
+		&activities.QuarantineSIPParams{
+			SIPPath: sip.Path(),
+			Report:  quarantine.Report{ID: "workflow-id", RelativePath: "sip"},
+		},
+	)
+	assert.NilError(t, err)
+
+	var res activities.QuarantineSIPResult
+	future.Get(&res)
+	assert.DeepEqual(t, res, activities.QuarantineSIPResult{
+		Path: qdir.Join("workflow-id", filepath.Base(sip.Path())),
+	})
+
+	_, err = env.ExecuteActivity(
+		activities.QuarantineSIPName,
+		&activities.QuarantineSIPParams{
+			SIPPath: sip.Path(),
+			Report:  quarantine.Report{ID: "../workflow-id", RelativePath: "sip"},
+		},
+	)
+	assert.ErrorContains(t, err, `quarantine-sip: invalid ID: "../workflow-id"`)
+}
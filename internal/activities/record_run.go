@@ -0,0 +1,36 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/audit"
+)
+
+const RecordRunName = "record-run"
+
+type (
+	RecordRunParams struct {
+		Run audit.Run
+	}
+
+	RecordRunResult struct{}
+
+	RecordRunActivity struct {
+		db *audit.DB
+	}
+)
+
+func NewRecordRun(db *audit.DB) *RecordRunActivity {
+	return &RecordRunActivity{db: db}
+}
+
+// Execute records the run in the audit database, replacing an existing record
+// of the same run.
+func (a *RecordRunActivity) Execute(ctx context.Context, params *RecordRunParams) (*RecordRunResult, error) {
+	if err := a.db.Record(ctx, params.Run); err != nil {
+		return nil, fmt.Errorf("%s: %v", RecordRunName, err)
+	}
+
+	return &RecordRunResult{}, nil
+}
@@ -0,0 +1,54 @@
+package activities_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/audit"
+)
+
+func TestRecordRun(t *testing.T) {
+	t.Parallel()
+
+	db, err := audit.Open(filepath.Join(t.TempDir(), "audit.db"))
+	assert.NilError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+	env := ts.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(
+		activities.NewRecordRun(db).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.RecordRunName},
+	)
+
+	startedAt := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	run := audit.Run{
+		WorkflowID:   "preprocessing-1",
+		RunID:        "run-1",
+		RelativePath: "transfer",
+		SIPName:      "transfer",
+		Language:     "en",
+		Outcome:      "success",
+		StartedAt:    startedAt,
+		CompletedAt:  startedAt.Add(time.Minute),
+		Files:        2,
+		Size:         1024,
+	}
+
+	// A retried activity replaces the run.
+	for range 2 {
+		_, err = env.ExecuteActivity(activities.RecordRunName, &activities.RecordRunParams{Run: run})
+		assert.NilError(t, err)
+	}
+
+	runs, err := db.Runs(context.Background(), audit.Filter{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, runs, []audit.Run{run})
+}
@@ -0,0 +1,122 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/clamd"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+)
+
+const ScanVirusesName = "scan-viruses"
+
+type (
+	ScanVirusesParams struct {
+		SIPPath string
+	}
+
+	ScanVirusesResult struct {
+		// Scanned is the number of files scanned.
+		Scanned int
+
+		// Failures lists the infected files and the malware signatures found,
+		// and the files too large to be scanned by clamd.
+		Failures []eventlog.Failure
+	}
+
+	// ScanVirusesProgress is the heartbeat details of the scan-viruses
+	// activity, a retry resumes after the files already scanned.
+	ScanVirusesProgress struct {
+		Result ScanVirusesResult
+	}
+
+	ScanVirusesActivity struct {
+		client *clamd.Client
+	}
+)
+
+func NewScanViruses(client *clamd.Client) *ScanVirusesActivity {
+	return &ScanVirusesActivity{client: client}
+}
+
+func (a *ScanVirusesActivity) Execute(
+	ctx context.Context,
+	params *ScanVirusesParams,
+) (*ScanVirusesResult, error) {
+	res := &ScanVirusesResult{}
+	if progress, ok := resumeProgress[ScanVirusesProgress](ctx); ok {
+		res = &progress.Result
+	}
+	skip := res.Scanned
+
+	h := startHeartbeat(ctx)
+	defer h.stop()
+
+	err := filepath.WalkDir(params.SIPPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if skip > 0 {
+			skip--
+			return nil
+		}
+
+		rel, err := filepath.Rel(params.SIPPath, p)
+		if err != nil {
+			return err
+		}
+
+		r, err := a.scan(ctx, p)
+		if errors.Is(err, clamd.ErrStreamLimit) {
+			// Retrying won't help, report the file instead of failing.
+			res.Scanned++
+			res.Failures = append(res.Failures, eventlog.Failure{
+				Path:   rel,
+				Check:  "virus",
+				Code:   "virus-scan-size-limit",
+				Params: messages.Params{"path": rel},
+			}.Localize(messages.DefaultLanguage))
+			h.update(ScanVirusesProgress{Result: *res})
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("scan %q: %v", rel, err)
+		}
+
+		res.Scanned++
+		if r.Infected {
+			res.Failures = append(res.Failures, eventlog.Failure{
+				Path:   rel,
+				Check:  "virus",
+				Code:   "virus-found",
+				Params: messages.Params{"signature": r.Signature, "path": rel},
+			}.Localize(messages.DefaultLanguage))
+		}
+		h.update(ScanVirusesProgress{Result: *res})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ScanVirusesName, err)
+	}
+
+	return res, nil
+}
+
+func (a *ScanVirusesActivity) scan(ctx context.Context, path string) (*clamd.Result, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is within the SIP.
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return a.client.Scan(ctx, f)
+}
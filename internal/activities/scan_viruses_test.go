@@ -0,0 +1,160 @@
+package activities_test
+
+import (
+	"strings"
+	"testing"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/clamd"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/clamd/clamdtest"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+)
+
+func TestScanViruses(t *testing.T) {
+	t.Parallel()
+
+	eicarFailure := eventlog.Failure{
+		Path:    "a.com",
+		Check:   "virus",
+		Code:    "virus-found",
+		Params:  messages.Params{"path": "a.com", "signature": "Win.Test.EICAR_HDB-1"},
+		Message: `virus "Win.Test.EICAR_HDB-1" found: "a.com"`,
+	}
+
+	tests := []struct {
+		name     string
+		sipPath  string
+		progress *activities.ScanVirusesProgress
+		stopped  bool
+		opts     []clamdtest.Option
+		want     activities.ScanVirusesResult
+		wantErr  string
+	}{
+		{
+			name: "Scans a clean SIP",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("clean.txt", "I am clean.\n"),
+				fs.WithDir("content",
+					fs.WithFile("also-clean.txt", "Me too.\n"),
+				),
+			).Path(),
+			want: activities.ScanVirusesResult{Scanned: 2},
+		},
+		{
+			name: "Reports infected files",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("clean.txt", "I am clean.\n"),
+				fs.WithDir("content",
+					fs.WithFile("eicar.com", clamdtest.EICAR),
+				),
+			).Path(),
+			want: activities.ScanVirusesResult{
+				Scanned: 2,
+				Failures: []eventlog.Failure{
+					{
+						Path:    "content/eicar.com",
+						Check:   "virus",
+						Code:    "virus-found",
+						Params:  messages.Params{"path": "content/eicar.com", "signature": "Win.Test.EICAR_HDB-1"},
+						Message: `virus "Win.Test.EICAR_HDB-1" found: "content/eicar.com"`,
+					},
+				},
+			},
+		},
+		{
+			name: "Resumes after the files scanned by a previous attempt",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("a.com", clamdtest.EICAR),
+				fs.WithFile("b.txt", "I am clean.\n"),
+			).Path(),
+			progress: &activities.ScanVirusesProgress{
+				Result: activities.ScanVirusesResult{Scanned: 1, Failures: []eventlog.Failure{eicarFailure}},
+			},
+			want: activities.ScanVirusesResult{
+				Scanned:  2,
+				Failures: []eventlog.Failure{eicarFailure},
+			},
+		},
+		{
+			name: "Reports files exceeding the clamd stream limit",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("clean.txt", "I am clean.\n"),
+				fs.WithFile("large.bin", strings.Repeat("a", 200_000)),
+			).Path(),
+			opts: []clamdtest.Option{clamdtest.WithStreamMaxLength(100_000)},
+			want: activities.ScanVirusesResult{
+				Scanned: 2,
+				Failures: []eventlog.Failure{
+					{
+						Path:    "large.bin",
+						Check:   "virus",
+						Code:    "virus-scan-size-limit",
+						Params:  messages.Params{"path": "large.bin"},
+						Message: `file exceeds clamd stream limit and was not scanned: "large.bin"`,
+					},
+				},
+			},
+		},
+		{
+			name: "Errors when clamd drops the connection",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("large.bin", strings.Repeat("a", 200_000)),
+			).Path(),
+			opts: []clamdtest.Option{
+				clamdtest.WithConnectionDrop(50_000),
+				clamdtest.WithStreamMaxLength(100_000),
+			},
+			wantErr: `scan-viruses: scan "large.bin": clamd: `,
+		},
+		{
+			name: "Errors when clamd is not available",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("clean.txt", "I am clean.\n"),
+			).Path(),
+			stopped: true,
+			wantErr: `scan-viruses: scan "clean.txt": clamd: connect:`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			address := "unix://" + t.TempDir() + "/missing.sock"
+			if !tt.stopped {
+				address = clamdtest.NewServer(t, "tcp", tt.opts...).Address
+			}
+			client, err := clamd.New(clamd.Config{Address: address})
+			assert.NilError(t, err)
+
+			ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+			env := ts.NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(
+				activities.NewScanViruses(client).Execute,
+				temporalsdk_activity.RegisterOptions{Name: activities.ScanVirusesName},
+			)
+			if tt.progress != nil {
+				env.SetHeartbeatDetails(tt.progress)
+			}
+
+			future, err := env.ExecuteActivity(
+				activities.ScanVirusesName,
+				&activities.ScanVirusesParams{SIPPath: tt.sipPath},
+			)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+
+			var res activities.ScanVirusesResult
+			future.Get(&res)
+			assert.DeepEqual(t, res, tt.want)
+		})
+	}
+}
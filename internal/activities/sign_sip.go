@@ -0,0 +1,54 @@
+package activities
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/signature"
+)
+
+const SignSIPName = "sign-sip"
+
+type (
+	SignSIPParams struct {
+		BagPath string
+	}
+
+	SignSIPResult struct {
+		// Path is the path of the signature file, relative to the bag.
+		Path string
+
+		// KeyID identifies the signing key.
+		KeyID string
+
+		// Files lists the signed files, relative to the bag.
+		Files []string
+	}
+
+	SignSIPActivity struct {
+		key ed25519.PrivateKey
+	}
+)
+
+// NewSignSIP returns an activity that signs the tag manifests and premis.xml
+// of a bagged SIP with key.
+func NewSignSIP(key ed25519.PrivateKey) *SignSIPActivity {
+	return &SignSIPActivity{key: key}
+}
+
+// Execute writes a detached signature of the bag at params.BagPath, replacing
+// the signature of a previous attempt.
+func (a *SignSIPActivity) Execute(ctx context.Context, params *SignSIPParams) (*SignSIPResult, error) {
+	sig, err := signature.Sign(params.BagPath, a.key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", SignSIPName, err)
+	}
+
+	res := &SignSIPResult{Path: signature.Path, KeyID: sig.KeyID}
+	for _, f := range sig.Files {
+		res.Files = append(res.Files, f.Path)
+	}
+
+	return res, nil
+}
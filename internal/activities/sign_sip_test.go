@@ -0,0 +1,80 @@
+package activities_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/signature"
+)
+
+func TestSignSIP(t *testing.T) {
+	t.Parallel()
+
+	key := ed25519.NewKeyFromSeed([]byte("0123456789abcdef0123456789abcdef"))
+	pub := key.Public().(ed25519.PublicKey)
+
+	for _, tt := range []struct {
+		name    string
+		bagPath string
+		want    activities.SignSIPResult
+		wantErr string
+	}{
+		{
+			name: "Signs the tag manifests and premis.xml",
+			bagPath: fs.NewDir(t, "",
+				fs.WithFile("bagit.txt", "BagIt-Version: 0.97\n"),
+				fs.WithFile("manifest-sha512.txt", ""),
+				fs.WithFile("tagmanifest-sha512.txt", ""),
+				fs.WithFile("tagmanifest-md5.txt", ""),
+				fs.WithDir("data"),
+				fs.WithDir("metadata", fs.WithFile("premis.xml", "<premis/>")),
+			).Path(),
+			want: activities.SignSIPResult{
+				Path:  "metadata/preprocessing-signature.json",
+				KeyID: signature.KeyID(pub),
+				Files: []string{"metadata/premis.xml", "tagmanifest-md5.txt", "tagmanifest-sha512.txt"},
+			},
+		},
+		{
+			name: "Errors when the SIP isn't bagged",
+			bagPath: fs.NewDir(t, "",
+				fs.WithDir("metadata", fs.WithFile("premis.xml", "<premis/>")),
+			).Path(),
+			wantErr: "sign-sip: no tag manifest found",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+			env := ts.NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(
+				activities.NewSignSIP(key).Execute,
+				temporalsdk_activity.RegisterOptions{Name: activities.SignSIPName},
+			)
+
+			future, err := env.ExecuteActivity(
+				activities.SignSIPName,
+				&activities.SignSIPParams{BagPath: tt.bagPath},
+			)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+
+			var res activities.SignSIPResult
+			future.Get(&res)
+			assert.DeepEqual(t, res, tt.want)
+
+			_, err = signature.Verify(tt.bagPath, pub)
+			assert.NilError(t, err)
+		})
+	}
+}
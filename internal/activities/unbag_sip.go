@@ -0,0 +1,165 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const UnbagSIPName = "unbag-sip"
+
+// IncompleteMarkerName is the name of the file written to a SIP that couldn't
+// be restored to its original layout.
+const IncompleteMarkerName = "PREPROCESSING_INCOMPLETE.txt"
+
+// maxClockSkew is the tolerated difference between the workflow and the worker
+// file system clocks when comparing the bagging start time to file times.
+const maxClockSkew = time.Minute
+
+type (
+	UnbagSIPParams struct {
+		SIPPath string
+
+		// BagStartedAt is the time the SIP bagging started.
+		BagStartedAt time.Time
+
+		// LogRunID is the run ID of the workflow that wrote the preprocessing
+		// log to the SIP before bagging, if any. The log is removed once the
+		// SIP layout is restored, unless it was written by another run.
+		LogRunID string
+
+		// RemoveMetadataDir is true if the SIP metadata directory was created
+		// to write the preprocessing log, it's removed with the log.
+		RemoveMetadataDir bool
+	}
+
+	UnbagSIPResult struct {
+		// Restored is true if the SIP layout was changed by bagging and has
+		// been restored, false if bagging hadn't changed it.
+		Restored bool
+	}
+
+	UnbagSIPActivity struct{}
+)
+
+func NewUnbagSIP() *UnbagSIPActivity {
+	return &UnbagSIPActivity{}
+}
+
+// Execute restores the original layout of a SIP whose bagging was interrupted
+// or completed, moving the payload files out of the bag "data" directory and
+// removing the bag tag files. A "data" directory not modified since bagging
+// started belongs to the original SIP, it's left untouched.
+//
+// The preprocessing log written by the workflow run LogRunID is then removed
+// from the SIP.
+//
+// If the SIP can't be restored, an IncompleteMarkerName file explaining the
+// error is written to the SIP.
+func (a *UnbagSIPActivity) Execute(ctx context.Context, params *UnbagSIPParams) (*UnbagSIPResult, error) {
+	h := startHeartbeat(ctx)
+	defer h.stop()
+
+	restored, err := unbag(params.SIPPath, params.BagStartedAt.Add(-maxClockSkew))
+	if err == nil && params.LogRunID != "" {
+		err = removePreprocessingLog(params.SIPPath, params.LogRunID, params.RemoveMetadataDir)
+	}
+	if err != nil {
+		msg := fmt.Sprintf(
+			"Preprocessing was cancelled and the SIP couldn't be restored to its original layout: %v\n",
+			err,
+		)
+		if werr := os.WriteFile(filepath.Join(params.SIPPath, IncompleteMarkerName), []byte(msg), 0o600); werr != nil {
+			err = errors.Join(err, werr)
+		}
+		return nil, fmt.Errorf("%s: %v", UnbagSIPName, err)
+	}
+
+	return &UnbagSIPResult{Restored: restored}, nil
+}
+
+// unbag restores the layout of the SIP at sipPath if it has a "data" directory
+// modified after since.
+func unbag(sipPath string, since time.Time) (bool, error) {
+	dataPath := filepath.Join(sipPath, "data")
+	info, err := os.Stat(dataPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !info.IsDir() || info.ModTime().Before(since) {
+		return false, nil
+	}
+
+	// The manifests are written once all the payload files have been moved to
+	// the data directory, every other entry of the SIP is then a bag tag file
+	// or directory. Before that, the entries are original files not moved yet.
+	manifests, err := filepath.Glob(filepath.Join(sipPath, "manifest-*.txt"))
+	if err != nil {
+		return false, err
+	}
+	if len(manifests) > 0 {
+		entries, err := os.ReadDir(sipPath)
+		if err != nil {
+			return false, err
+		}
+		for _, e := range entries {
+			if e.Name() == "data" {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(sipPath, e.Name())); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	payload, err := os.ReadDir(dataPath)
+	if err != nil {
+		return false, err
+	}
+	for _, e := range payload {
+		dest := filepath.Join(sipPath, e.Name())
+		if _, err := os.Lstat(dest); err == nil {
+			return false, fmt.Errorf("move %q out of the bag: file exists", e.Name())
+		}
+		if err := os.Rename(filepath.Join(dataPath, e.Name()), dest); err != nil {
+			return false, err
+		}
+	}
+	if err := os.Remove(dataPath); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// removePreprocessingLog removes the preprocessing log written by the workflow
+// run runID from the SIP at sipPath and, if removeDir is true, the metadata
+// directory containing it.
+func removePreprocessingLog(sipPath, runID string, removeDir bool) error {
+	p := filepath.Join(sipPath, PreprocessingLogPath)
+	id, err := logRunID(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil || id != runID {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil {
+		return err
+	}
+	if removeDir {
+		if err := os.Remove(filepath.Dir(p)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
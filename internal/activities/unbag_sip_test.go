@@ -0,0 +1,192 @@
+package activities_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/artefactual-sdps/temporal-activities/bagcreate"
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+)
+
+func TestUnbagSIP(t *testing.T) {
+	t.Parallel()
+
+	bagged := fs.NewDir(t, "",
+		fs.WithFile("small.txt", smallContent),
+		fs.WithDir("content",
+			fs.WithFile("file.txt", "somestuff"),
+		),
+	)
+	bagStartedAt := time.Now()
+	_, err := bagcreate.New(bagcreate.Config{}).Execute(
+		context.Background(),
+		&bagcreate.Params{SourcePath: bagged.Path()},
+	)
+	assert.NilError(t, err)
+	fs.Apply(t, bagged, fs.WithDir("metadata", fs.WithFile("premis.xml", "<premis/>")))
+
+	tests := []struct {
+		name         string
+		sipPath      string
+		bagStartedAt time.Time
+		logRunID     string
+		removeDir    bool
+		want         activities.UnbagSIPResult
+		wantSIP      fs.Manifest
+		wantErr      string
+	}{
+		{
+			name:         "Restores a bagged SIP",
+			sipPath:      bagged.Path(),
+			bagStartedAt: bagStartedAt,
+			want:         activities.UnbagSIPResult{Restored: true},
+			wantSIP: fs.Expected(t, fs.MatchAnyFileMode,
+				fs.WithFile("small.txt", smallContent, fs.MatchAnyFileMode),
+				fs.WithDir("content", fs.MatchAnyFileMode,
+					fs.WithFile("file.txt", "somestuff", fs.MatchAnyFileMode),
+				),
+			),
+		},
+		{
+			name: "Restores a SIP with files not moved to the bag yet",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("b.txt", "not moved"),
+				fs.WithDir("data",
+					fs.WithFile("a.txt", "moved"),
+				),
+			).Path(),
+			bagStartedAt: time.Now(),
+			want:         activities.UnbagSIPResult{Restored: true},
+			wantSIP: fs.Expected(t, fs.MatchAnyFileMode,
+				fs.WithFile("a.txt", "moved", fs.MatchAnyFileMode),
+				fs.WithFile("b.txt", "not moved", fs.MatchAnyFileMode),
+			),
+		},
+		{
+			name: "Leaves a data directory of the original SIP untouched",
+			sipPath: fs.NewDir(t, "",
+				fs.WithDir("data",
+					fs.WithFile("a.txt", "original"),
+				),
+			).Path(),
+			bagStartedAt: time.Now().Add(time.Hour),
+			wantSIP: fs.Expected(t, fs.MatchAnyFileMode,
+				fs.WithDir("data", fs.MatchAnyFileMode,
+					fs.WithFile("a.txt", "original", fs.MatchAnyFileMode),
+				),
+			),
+		},
+		{
+			name: "Removes the preprocessing log from a restored SIP",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("manifest-sha512.txt", "checksums"),
+				fs.WithDir("data",
+					fs.WithFile("a.txt", "content"),
+					fs.WithDir("metadata",
+						fs.WithFile("preprocessing-log.json", `{"RunID": "run-id"}`),
+						fs.WithFile("mets.xml", "mets"),
+					),
+				),
+			).Path(),
+			bagStartedAt: time.Now(),
+			logRunID:     "run-id",
+			want:         activities.UnbagSIPResult{Restored: true},
+			wantSIP: fs.Expected(t, fs.MatchAnyFileMode,
+				fs.WithFile("a.txt", "content", fs.MatchAnyFileMode),
+				fs.WithDir("metadata", fs.MatchAnyFileMode,
+					fs.WithFile("mets.xml", "mets", fs.MatchAnyFileMode),
+				),
+			),
+		},
+		{
+			name: "Removes the preprocessing log and its metadata directory from a SIP not bagged yet",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("a.txt", "content"),
+				fs.WithDir("metadata",
+					fs.WithFile("preprocessing-log.json", `{"RunID": "run-id"}`),
+				),
+			).Path(),
+			bagStartedAt: time.Now(),
+			logRunID:     "run-id",
+			removeDir:    true,
+			wantSIP: fs.Expected(t, fs.MatchAnyFileMode,
+				fs.WithFile("a.txt", "content", fs.MatchAnyFileMode),
+			),
+		},
+		{
+			name: "Keeps a preprocessing log not written by the workflow run",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("a.txt", "content"),
+				fs.WithDir("metadata",
+					fs.WithFile("preprocessing-log.json", "producer notes"),
+				),
+			).Path(),
+			bagStartedAt: time.Now(),
+			logRunID:     "run-id",
+			removeDir:    true,
+			wantSIP: fs.Expected(t, fs.MatchAnyFileMode,
+				fs.WithFile("a.txt", "content", fs.MatchAnyFileMode),
+				fs.WithDir("metadata", fs.MatchAnyFileMode,
+					fs.WithFile("preprocessing-log.json", "producer notes", fs.MatchAnyFileMode),
+				),
+			),
+		},
+		{
+			name: "Marks the SIP as incomplete when it can't be restored",
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("a.txt", "not moved"),
+				fs.WithDir("data",
+					fs.WithFile("a.txt", "moved"),
+				),
+			).Path(),
+			bagStartedAt: time.Now(),
+			wantErr:      `unbag-sip: move "a.txt" out of the bag: file exists`,
+			wantSIP: fs.Expected(t, fs.MatchAnyFileMode,
+				fs.WithFile("a.txt", "not moved", fs.MatchAnyFileMode),
+				fs.WithFile(activities.IncompleteMarkerName, "", fs.MatchAnyFileMode, fs.MatchAnyFileContent),
+				fs.WithDir("data", fs.MatchAnyFileMode,
+					fs.WithFile("a.txt", "moved", fs.MatchAnyFileMode),
+				),
+			),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+			env := ts.NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(
+				activities.NewUnbagSIP().Execute,
+				temporalsdk_activity.RegisterOptions{Name: activities.UnbagSIPName},
+			)
+
+			future, err := env.ExecuteActivity(
+				activities.UnbagSIPName,
+				&activities.UnbagSIPParams{
+					SIPPath:           tt.sipPath,
+					BagStartedAt:      tt.bagStartedAt,
+					LogRunID:          tt.logRunID,
+					RemoveMetadataDir: tt.removeDir,
+				},
+			)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NilError(t, err)
+
+				var res activities.UnbagSIPResult
+				future.Get(&res)
+				assert.DeepEqual(t, res, tt.want)
+			}
+
+			assert.Assert(t, fs.Equal(tt.sipPath, tt.wantSIP))
+		})
+	}
+}
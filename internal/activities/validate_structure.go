@@ -0,0 +1,110 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+)
+
+const ValidateStructureName = "validate-structure"
+
+type (
+	ValidateStructureParams struct {
+		SIPPath string
+
+		// RequiredPaths and ForbiddenPaths are the structure rules, see
+		// config.StructureConfig.
+		RequiredPaths  []string
+		ForbiddenPaths []string
+	}
+
+	ValidateStructureResult struct {
+		// Failures lists the required paths missing and the forbidden paths
+		// found.
+		Failures []eventlog.Failure
+	}
+
+	ValidateStructureActivity struct{}
+)
+
+func NewValidateStructure() *ValidateStructureActivity {
+	return &ValidateStructureActivity{}
+}
+
+// Execute checks that the SIP has a path matching each of the RequiredPaths
+// patterns and no path matching one of the ForbiddenPaths patterns.
+func (a *ValidateStructureActivity) Execute(
+	ctx context.Context,
+	params *ValidateStructureParams,
+) (*ValidateStructureResult, error) {
+	for _, pattern := range append(params.RequiredPaths, params.ForbiddenPaths...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern: %q", ValidateStructureName, pattern)
+		}
+	}
+
+	res := &ValidateStructureResult{}
+	found := make([]bool, len(params.RequiredPaths))
+	err := filepath.WalkDir(params.SIPPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == params.SIPPath {
+			return nil
+		}
+
+		rel, err := filepath.Rel(params.SIPPath, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		for i, pattern := range params.RequiredPaths {
+			found[i] = found[i] || matchPath(pattern, rel)
+		}
+		for _, pattern := range params.ForbiddenPaths {
+			if matchPath(pattern, rel) {
+				res.Failures = append(res.Failures, eventlog.Failure{
+					Path:   rel,
+					Check:  "structure",
+					Code:   "forbidden-path",
+					Params: messages.Params{"pattern": pattern, "path": rel},
+				}.Localize(messages.DefaultLanguage))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ValidateStructureName, err)
+	}
+
+	for i, pattern := range params.RequiredPaths {
+		if !found[i] {
+			res.Failures = append(res.Failures, eventlog.Failure{
+				Check:  "structure",
+				Code:   "missing-required-path",
+				Params: messages.Params{"pattern": pattern},
+			}.Localize(messages.DefaultLanguage))
+		}
+	}
+
+	return res, nil
+}
+
+// matchPath reports whether the slash-separated path rel matches pattern, or
+// its base name does if pattern has no slash.
+func matchPath(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		rel = path.Base(rel)
+	}
+	ok, _ := path.Match(pattern, rel)
+
+	return ok
+}
@@ -0,0 +1,109 @@
+package activities_test
+
+import (
+	"testing"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+)
+
+func TestValidateStructure(t *testing.T) {
+	t.Parallel()
+
+	sip := fs.NewDir(t, "",
+		fs.WithDir("metadata",
+			fs.WithFile("mets.xml", "<mets/>"),
+		),
+		fs.WithDir("objects",
+			fs.WithFile("file.txt", "content"),
+			fs.WithFile("Thumbs.db", "thumbnails"),
+			fs.WithDir("images",
+				fs.WithFile("Thumbs.db", "thumbnails"),
+			),
+		),
+	)
+
+	tests := []struct {
+		name    string
+		params  activities.ValidateStructureParams
+		want    activities.ValidateStructureResult
+		wantErr string
+	}{
+		{
+			name: "Validates a SIP matching the rules",
+			params: activities.ValidateStructureParams{
+				RequiredPaths:  []string{"metadata/*.xml", "objects"},
+				ForbiddenPaths: []string{".DS_Store", "objects/*.exe"},
+			},
+		},
+		{
+			name: "Reports missing required paths and forbidden paths",
+			params: activities.ValidateStructureParams{
+				RequiredPaths:  []string{"metadata/*.xml", "metadata/submissionDocumentation"},
+				ForbiddenPaths: []string{"Thumbs.db"},
+			},
+			want: activities.ValidateStructureResult{
+				Failures: []eventlog.Failure{
+					{
+						Path:    "objects/Thumbs.db",
+						Check:   "structure",
+						Code:    "forbidden-path",
+						Params:  messages.Params{"path": "objects/Thumbs.db", "pattern": "Thumbs.db"},
+						Message: `forbidden path "Thumbs.db": "objects/Thumbs.db"`,
+					},
+					{
+						Path:    "objects/images/Thumbs.db",
+						Check:   "structure",
+						Code:    "forbidden-path",
+						Params:  messages.Params{"path": "objects/images/Thumbs.db", "pattern": "Thumbs.db"},
+						Message: `forbidden path "Thumbs.db": "objects/images/Thumbs.db"`,
+					},
+					{
+						Check:   "structure",
+						Code:    "missing-required-path",
+						Params:  messages.Params{"pattern": "metadata/submissionDocumentation"},
+						Message: `missing required path: "metadata/submissionDocumentation"`,
+					},
+				},
+			},
+		},
+		{
+			name: "Errors when a pattern is invalid",
+			params: activities.ValidateStructureParams{
+				RequiredPaths: []string{"metadata/[.xml"},
+			},
+			wantErr: `validate-structure: invalid pattern: "metadata/[.xml"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+			env := ts.NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(
+				activities.NewValidateStructure().Execute,
+				temporalsdk_activity.RegisterOptions{Name: activities.ValidateStructureName},
+			)
+
+			params := tt.params
+			params.SIPPath = sip.Path()
+			future, err := env.ExecuteActivity(activities.ValidateStructureName, &params)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+
+			var res activities.ValidateStructureResult
+			future.Get(&res)
+			assert.DeepEqual(t, res, tt.want)
+		})
+	}
+}
@@ -0,0 +1,418 @@
+package activities
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"  // #nosec G501 -- MD5 is used to verify producer checksums.
+	"crypto/sha1" // #nosec G505 -- SHA-1 is used to verify producer checksums.
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+)
+
+const VerifyChecksumsName = "verify-checksums"
+
+type (
+	VerifyChecksumsParams struct {
+		SIPPath string
+	}
+
+	VerifyChecksumsResult struct {
+		// Manifests lists the checksum manifests found in the SIP, relative to
+		// the SIP path.
+		Manifests []string
+
+		// Verified is the number of files with a matching checksum, files
+		// listed in several manifests are counted once.
+		Verified int
+
+		// Failures lists the mismatched, missing and unlisted files, and any
+		// manifest entries that couldn't be parsed.
+		Failures []eventlog.Failure
+	}
+
+	// VerifyChecksumsProgress is the heartbeat details of the
+	// verify-checksums activity, a retry resumes after the Entry first entries
+	// of the Manifest-th checksum manifest.
+	VerifyChecksumsProgress struct {
+		Result   VerifyChecksumsResult
+		Manifest int
+		Entry    int
+	}
+
+	VerifyChecksumsActivity struct {
+		manifestNames []string
+	}
+)
+
+// checksum is a single manifest entry.
+type checksum struct {
+	manifest string
+	path     string
+	alg      string
+	value    string
+}
+
+// bsdLine matches BSD-style checksum lines, e.g. "MD5 (file.txt) = d41d...".
+var bsdLine = regexp.MustCompile(`^(MD5|SHA1|SHA256|SHA512) \((.+)\) = ([0-9a-fA-F]+)$`)
+
+// NewVerifyChecksums returns an activity that verifies the SIP files against
+// the producer-supplied checksum manifests found with the given file names.
+func NewVerifyChecksums(manifestNames []string) *VerifyChecksumsActivity {
+	return &VerifyChecksumsActivity{manifestNames: manifestNames}
+}
+
+func (a *VerifyChecksumsActivity) Execute(
+	ctx context.Context,
+	params *VerifyChecksumsParams,
+) (*VerifyChecksumsResult, error) {
+	res := &VerifyChecksumsResult{}
+	if len(a.manifestNames) == 0 {
+		return res, nil
+	}
+
+	var files []string
+	err := filepath.WalkDir(params.SIPPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(params.SIPPath, p)
+		if err != nil {
+			return err
+		}
+
+		if a.isManifest(d.Name()) {
+			res.Manifests = append(res.Manifests, rel)
+		} else {
+			files = append(files, rel)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", VerifyChecksumsName, err)
+	}
+
+	if len(res.Manifests) == 0 {
+		return res, nil
+	}
+
+	// Resume the verification, manifests are always found in the same order.
+	progress, resumed := resumeProgress[VerifyChecksumsProgress](ctx)
+	if resumed {
+		progress.Result.Manifests = res.Manifests
+		res = &progress.Result
+	}
+
+	h := startHeartbeat(ctx)
+	defer h.stop()
+
+	listed := make(map[string]struct{})
+	for i, m := range res.Manifests {
+		entries, failures, err := parseManifest(params.SIPPath, m)
+		if err != nil {
+			return nil, fmt.Errorf("%s: parse %q: %v", VerifyChecksumsName, m, err)
+		}
+		if !resumed || i > progress.Manifest {
+			res.Failures = append(res.Failures, failures...)
+		}
+
+		for j, c := range entries {
+			_, seen := listed[c.path]
+			listed[c.path] = struct{}{}
+			if resumed && (i < progress.Manifest || (i == progress.Manifest && j < progress.Entry)) {
+				continue
+			}
+
+			ok, err := verify(filepath.Join(params.SIPPath, c.path), c.alg, c.value)
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				res.Failures = append(res.Failures, checksumFailure(
+					c.path, "checksum-file-missing", "", messages.Params{"manifest": c.manifest, "path": c.path},
+				))
+			case err != nil:
+				return nil, fmt.Errorf("%s: verify %q: %v", VerifyChecksumsName, c.path, err)
+			case ok:
+				if !seen {
+					res.Verified++
+				}
+			default:
+				res.Failures = append(res.Failures, checksumFailure(
+					c.path, "checksum-mismatch", "", messages.Params{"algorithm": c.alg, "path": c.path},
+				))
+			}
+			h.update(VerifyChecksumsProgress{Result: *res, Manifest: i, Entry: j + 1})
+		}
+	}
+
+	for _, f := range files {
+		if _, ok := listed[f]; !ok {
+			res.Failures = append(res.Failures, checksumFailure(
+				f, "checksum-file-unlisted", "", messages.Params{"path": f},
+			))
+		}
+	}
+
+	return res, nil
+}
+
+func (a *VerifyChecksumsActivity) isManifest(name string) bool {
+	return slices.ContainsFunc(a.manifestNames, func(s string) bool {
+		return strings.EqualFold(s, name)
+	})
+}
+
+// parseManifest reads the checksum manifest at rel (relative to sipPath) and
+// returns its entries with paths relative to sipPath. Entries that can't be
+// used are returned as failures.
+func parseManifest(sipPath, rel string) ([]checksum, []eventlog.Failure, error) {
+	f, err := os.Open(filepath.Join(sipPath, rel)) // #nosec G304 -- manifest path is discovered in the SIP.
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var entries []checksum
+	var failures []eventlog.Failure
+	if strings.EqualFold(filepath.Ext(rel), ".csv") {
+		entries, failures = parseCSVManifest(f, rel)
+	} else {
+		entries, failures, err = parseSumsManifest(f, rel)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Make entry paths relative to the SIP and reject those outside of it.
+	valid := entries[:0]
+	for _, c := range entries {
+		p := filepath.Join(filepath.Dir(rel), filepath.FromSlash(c.path))
+		if !filepath.IsLocal(p) {
+			failures = append(failures, checksumFailure(
+				rel, "checksum-path-invalid", "", messages.Params{"manifest": rel, "path": c.path},
+			))
+			continue
+		}
+		c.path = p
+		valid = append(valid, c)
+	}
+
+	return valid, failures, nil
+}
+
+// parseSumsManifest parses the output format of the md5sum, sha1sum, etc.
+// commands, in either GNU or BSD style.
+func parseSumsManifest(r io.Reader, name string) ([]checksum, []eventlog.Failure, error) {
+	var entries []checksum
+	var failures []eventlog.Failure
+
+	s := bufio.NewScanner(r)
+	for i := 1; s.Scan(); i++ {
+		line := strings.TrimRight(s.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var value, path string
+		if m := bsdLine.FindStringSubmatch(line); m != nil {
+			value, path = m[3], m[2]
+		} else if v, p, ok := strings.Cut(line, " "); ok {
+			// GNU style: "<checksum>  <path>" or "<checksum> *<path>".
+			value, path = v, strings.TrimPrefix(strings.TrimPrefix(p, " "), "*")
+		}
+
+		alg := algorithmForChecksum(value)
+		if alg == "" || path == "" {
+			failures = append(failures, checksumFailure(
+				name,
+				"checksum-manifest-invalid",
+				"checksum-manifest-invalid-entry",
+				messages.Params{"manifest": name, "line": strconv.Itoa(i)},
+			))
+			continue
+		}
+
+		entries = append(entries, checksum{manifest: name, path: path, alg: alg, value: strings.ToLower(value)})
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return entries, failures, nil
+}
+
+// parseCSVManifest parses a CSV manifest with a header row that names a path
+// column ("path", "file" or "filename") and one or more checksum columns
+// ("checksum", "md5", "sha1", "sha256" or "sha512"). Invalid CSV is reported
+// as a failure, not an error, as it's a problem with the SIP content.
+func parseCSVManifest(r io.Reader, name string) ([]checksum, []eventlog.Failure) {
+	var entries []checksum
+	var failures []eventlog.Failure
+
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, []eventlog.Failure{checksumFailure(
+			name,
+			"checksum-manifest-invalid",
+			"checksum-manifest-invalid-csv",
+			messages.Params{"manifest": name, "error": err.Error()},
+		)}
+	}
+
+	type sumCol struct {
+		index int
+		alg   string
+	}
+
+	pathIndex := -1
+	var sumCols []sumCol
+	for i, col := range header {
+		switch col = strings.ToLower(strings.TrimSpace(col)); col {
+		case "path", "file", "filename":
+			pathIndex = i
+		case "checksum":
+			sumCols = append(sumCols, sumCol{index: i})
+		case "md5", "sha1", "sha256", "sha512":
+			sumCols = append(sumCols, sumCol{index: i, alg: col})
+		}
+	}
+	if pathIndex == -1 || len(sumCols) == 0 {
+		return nil, []eventlog.Failure{checksumFailure(
+			name, "checksum-manifest-invalid", "checksum-manifest-missing-column", messages.Params{"manifest": name},
+		)}
+	}
+
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			failures = append(failures, checksumFailure(
+				name,
+				"checksum-manifest-invalid",
+				"checksum-manifest-invalid-csv",
+				messages.Params{"manifest": name, "error": err.Error()},
+			))
+			break
+		}
+
+		line, _ := cr.FieldPos(pathIndex)
+		path := strings.TrimSpace(row[pathIndex])
+		for _, col := range sumCols {
+			value := strings.TrimSpace(row[col.index])
+			if value == "" {
+				continue
+			}
+			alg := col.alg
+			if alg == "" {
+				alg = algorithmForChecksum(value)
+			}
+			if alg == "" || path == "" || algorithmForChecksum(value) != alg {
+				failures = append(failures, checksumFailure(
+					name,
+					"checksum-manifest-invalid",
+					"checksum-manifest-invalid-entry",
+					messages.Params{"manifest": name, "line": strconv.Itoa(line)},
+				))
+				continue
+			}
+
+			entries = append(entries, checksum{manifest: name, path: path, alg: alg, value: strings.ToLower(value)})
+		}
+	}
+
+	return entries, failures
+}
+
+// checksumFailure returns a checksum verification failure of the file at path,
+// with the message msgCode if it isn't code.
+func checksumFailure(path, code, msgCode string, params messages.Params) eventlog.Failure {
+	return eventlog.Failure{
+		Path:        path,
+		Check:       "checksum",
+		Code:        code,
+		MessageCode: msgCode,
+		Params:      params,
+	}.Localize(messages.DefaultLanguage)
+}
+
+// algorithmForChecksum returns the name of the hash algorithm that produces
+// hex encoded checksums like s, or an empty string if there is none.
+func algorithmForChecksum(s string) string {
+	if _, err := hex.DecodeString(s); err != nil {
+		return ""
+	}
+
+	switch len(s) {
+	case md5.Size * 2:
+		return "md5"
+	case sha1.Size * 2:
+		return "sha1"
+	case sha256.Size * 2:
+		return "sha256"
+	case sha512.Size * 2:
+		return "sha512"
+	}
+
+	return ""
+}
+
+func verify(path, alg, want string) (bool, error) {
+	got, err := fileChecksum(path, alg)
+	if err != nil {
+		return false, err
+	}
+
+	return got == want, nil
+}
+
+// fileChecksum returns the hex encoded checksum of the file at path with the
+// alg algorithm ("md5", "sha1", "sha256" or "sha512").
+func fileChecksum(path, alg string) (string, error) {
+	var h hash.Hash
+	switch alg {
+	case "md5":
+		h = md5.New() // #nosec G401
+	case "sha1":
+		h = sha1.New() // #nosec G401
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return "", fmt.Errorf("unsupported algorithm: %s", alg)
+	}
+
+	f, err := os.Open(path) // #nosec G304 -- path is relative to the SIP.
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,255 @@
+package activities_test
+
+import (
+	"testing"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+)
+
+const (
+	md5Small     = "fbdea08bab9d1c2f39f486f92f85a673"
+	md5Another   = "6b1f2e3a8f6e0c7b6d9b0a5a2b0f4f0e"
+	sha256Small  = "4450c8a88130a3b397bfc659245c4f0f87a8c79d017a60bdb1bd32f4b51c8133"
+	smallContent = "I am a small file.\n"
+)
+
+func TestVerifyChecksums(t *testing.T) {
+	t.Parallel()
+
+	manifestNames := []string{"checksums.sha256", "manifest.csv"}
+
+	deleted := fs.NewDir(t, "")
+	deleted.Remove()
+
+	tests := []struct {
+		name          string
+		manifestNames []string
+		sipPath       string
+		progress      *activities.VerifyChecksumsProgress
+		want          activities.VerifyChecksumsResult
+		wantErr       string
+	}{
+		{
+			name:          "Skips verification when no manifest names are configured",
+			manifestNames: nil,
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("checksums.sha256", sha256Small+"  small.txt\n"),
+				fs.WithFile("small.txt", smallContent),
+			).Path(),
+			want: activities.VerifyChecksumsResult{},
+		},
+		{
+			name:          "Returns no manifests when none are found",
+			manifestNames: manifestNames,
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("small.txt", smallContent),
+			).Path(),
+			want: activities.VerifyChecksumsResult{},
+		},
+		{
+			name:          "Verifies a GNU style checksum file",
+			manifestNames: manifestNames,
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("CHECKSUMS.sha256", sha256Small+" *small.txt\n"),
+				fs.WithFile("small.txt", smallContent),
+			).Path(),
+			want: activities.VerifyChecksumsResult{
+				Manifests: []string{"CHECKSUMS.sha256"},
+				Verified:  1,
+			},
+		},
+		{
+			name:          "Verifies a BSD style checksum file in a subdirectory",
+			manifestNames: manifestNames,
+			sipPath: fs.NewDir(t, "",
+				fs.WithDir("content",
+					fs.WithFile("checksums.sha256", "SHA256 (small.txt) = "+sha256Small+"\n"),
+					fs.WithFile("small.txt", smallContent),
+				),
+			).Path(),
+			want: activities.VerifyChecksumsResult{
+				Manifests: []string{"content/checksums.sha256"},
+				Verified:  1,
+			},
+		},
+		{
+			name:          "Verifies a CSV manifest",
+			manifestNames: manifestNames,
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("manifest.csv", "Path,SHA256\ndata/small.txt,"+sha256Small+"\n"),
+				fs.WithDir("data",
+					fs.WithFile("small.txt", smallContent),
+				),
+			).Path(),
+			want: activities.VerifyChecksumsResult{
+				Manifests: []string{"manifest.csv"},
+				Verified:  1,
+			},
+		},
+		{
+			name:          "Counts the files listed in several manifests once",
+			manifestNames: manifestNames,
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("checksums.sha256", sha256Small+"  small.txt\n"),
+				fs.WithFile("manifest.csv", "Path,MD5\nsmall.txt,"+md5Small+"\n"),
+				fs.WithFile("small.txt", smallContent),
+			).Path(),
+			want: activities.VerifyChecksumsResult{
+				Manifests: []string{"checksums.sha256", "manifest.csv"},
+				Verified:  1,
+			},
+		},
+		{
+			name:          "Reports mismatched, missing and unlisted files",
+			manifestNames: manifestNames,
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("checksums.sha256",
+					sha256Small+"  small.txt\n"+
+						sha256Small+"  missing.txt\n"+
+						"not-a-checksum  invalid.txt\n"+
+						sha256Small+"  ../outside.txt\n",
+				),
+				fs.WithFile("manifest.csv", "filename,checksum\nanother.txt,"+md5Another+"\n"),
+				fs.WithFile("small.txt", "I have been modified.\n"),
+				fs.WithFile("another.txt", "I am another file.\n"),
+				fs.WithFile("unlisted.txt", "Nobody knows me.\n"),
+			).Path(),
+			want: activities.VerifyChecksumsResult{
+				Manifests: []string{"checksums.sha256", "manifest.csv"},
+				Failures: []eventlog.Failure{
+					{
+						Path:        "checksums.sha256",
+						Check:       "checksum",
+						Code:        "checksum-manifest-invalid",
+						MessageCode: "checksum-manifest-invalid-entry",
+						Params:      messages.Params{"line": "3", "manifest": "checksums.sha256"},
+						Message:     `invalid entry in "checksums.sha256" at line 3`,
+					},
+					{
+						Path:    "checksums.sha256",
+						Check:   "checksum",
+						Code:    "checksum-path-invalid",
+						Params:  messages.Params{"manifest": "checksums.sha256", "path": "../outside.txt"},
+						Message: `path outside of SIP listed in "checksums.sha256": "../outside.txt"`,
+					},
+					{
+						Path:    "small.txt",
+						Check:   "checksum",
+						Code:    "checksum-mismatch",
+						Params:  messages.Params{"algorithm": "sha256", "path": "small.txt"},
+						Message: `sha256 checksum mismatch: "small.txt"`,
+					},
+					{
+						Path:    "missing.txt",
+						Check:   "checksum",
+						Code:    "checksum-file-missing",
+						Params:  messages.Params{"manifest": "checksums.sha256", "path": "missing.txt"},
+						Message: `file listed in "checksums.sha256" not found: "missing.txt"`,
+					},
+					{
+						Path:    "another.txt",
+						Check:   "checksum",
+						Code:    "checksum-mismatch",
+						Params:  messages.Params{"algorithm": "md5", "path": "another.txt"},
+						Message: `md5 checksum mismatch: "another.txt"`,
+					},
+					{
+						Path:    "unlisted.txt",
+						Check:   "checksum",
+						Code:    "checksum-file-unlisted",
+						Params:  messages.Params{"path": "unlisted.txt"},
+						Message: `file not listed in any checksum manifest: "unlisted.txt"`,
+					},
+				},
+			},
+		},
+		{
+			name:          "Reports an invalid CSV manifest",
+			manifestNames: manifestNames,
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("manifest.csv", "name,hash\nsmall.txt,"+md5Small+"\n"),
+				fs.WithFile("small.txt", smallContent),
+			).Path(),
+			want: activities.VerifyChecksumsResult{
+				Manifests: []string{"manifest.csv"},
+				Failures: []eventlog.Failure{
+					{
+						Path:        "manifest.csv",
+						Check:       "checksum",
+						Code:        "checksum-manifest-invalid",
+						MessageCode: "checksum-manifest-missing-column",
+						Params:      messages.Params{"manifest": "manifest.csv"},
+						Message:     `missing path or checksum column in "manifest.csv"`,
+					},
+					{
+						Path:    "small.txt",
+						Check:   "checksum",
+						Code:    "checksum-file-unlisted",
+						Params:  messages.Params{"path": "small.txt"},
+						Message: `file not listed in any checksum manifest: "small.txt"`,
+					},
+				},
+			},
+		},
+		{
+			name:          "Resumes after the entries verified by a previous attempt",
+			manifestNames: manifestNames,
+			sipPath: fs.NewDir(t, "",
+				fs.WithFile("checksums.sha256", sha256Small+"  a.txt\n"+sha256Small+"  small.txt\n"),
+				fs.WithFile("a.txt", "Changed since the previous attempt.\n"),
+				fs.WithFile("small.txt", smallContent),
+			).Path(),
+			progress: &activities.VerifyChecksumsProgress{
+				Result:   activities.VerifyChecksumsResult{Verified: 1},
+				Manifest: 0,
+				Entry:    1,
+			},
+			want: activities.VerifyChecksumsResult{
+				Manifests: []string{"checksums.sha256"},
+				Verified:  2,
+			},
+		},
+		{
+			name:          "Errors when the SIP path doesn't exist",
+			manifestNames: manifestNames,
+			sipPath:       deleted.Path(),
+			wantErr:       "no such file or directory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+			env := ts.NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(
+				activities.NewVerifyChecksums(tt.manifestNames).Execute,
+				temporalsdk_activity.RegisterOptions{Name: activities.VerifyChecksumsName},
+			)
+			if tt.progress != nil {
+				env.SetHeartbeatDetails(tt.progress)
+			}
+
+			future, err := env.ExecuteActivity(
+				activities.VerifyChecksumsName,
+				&activities.VerifyChecksumsParams{SIPPath: tt.sipPath},
+			)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+
+			var res activities.VerifyChecksumsResult
+			future.Get(&res)
+			assert.DeepEqual(t, res, tt.want)
+		})
+	}
+}
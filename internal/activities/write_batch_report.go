@@ -0,0 +1,41 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/batch"
+)
+
+const WriteBatchReportName = "write-batch-report"
+
+type (
+	WriteBatchReportParams struct {
+		Report batch.Report
+	}
+
+	WriteBatchReportResult struct {
+		// Path is the path of the report file.
+		Path string
+	}
+
+	WriteBatchReportActivity struct {
+		reportPath string
+	}
+)
+
+func NewWriteBatchReport(reportPath string) *WriteBatchReportActivity {
+	return &WriteBatchReportActivity{reportPath: reportPath}
+}
+
+func (a *WriteBatchReportActivity) Execute(
+	ctx context.Context,
+	params *WriteBatchReportParams,
+) (*WriteBatchReportResult, error) {
+	p, err := batch.Write(a.reportPath, params.Report)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", WriteBatchReportName, err)
+	}
+
+	return &WriteBatchReportResult{Path: p}, nil
+}
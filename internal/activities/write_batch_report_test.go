@@ -0,0 +1,42 @@
+package activities_test
+
+import (
+	"testing"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/batch"
+)
+
+func TestWriteBatchReport(t *testing.T) {
+	t.Parallel()
+
+	dir := fs.NewDir(t, "")
+
+	ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+	env := ts.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(
+		activities.NewWriteBatchReport(dir.Path()).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.WriteBatchReportName},
+	)
+
+	future, err := env.ExecuteActivity(
+		activities.WriteBatchReportName,
+		&activities.WriteBatchReportParams{Report: batch.Report{ID: "batch-id"}},
+	)
+	assert.NilError(t, err)
+
+	var res activities.WriteBatchReportResult
+	future.Get(&res)
+	assert.DeepEqual(t, res, activities.WriteBatchReportResult{Path: dir.Join("batch-id.json")})
+
+	_, err = env.ExecuteActivity(
+		activities.WriteBatchReportName,
+		&activities.WriteBatchReportParams{Report: batch.Report{ID: "../batch-id"}},
+	)
+	assert.ErrorContains(t, err, `write-batch-report: invalid ID: "../batch-id"`)
+}
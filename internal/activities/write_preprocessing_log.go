@@ -0,0 +1,123 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.artefactual.dev/tools/temporal"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+)
+
+const WritePreprocessingLogName = "write-preprocessing-log"
+
+// PreprocessingLogPath is the path of the preprocessing log in the SIP.
+const PreprocessingLogPath = "metadata/preprocessing-log.json"
+
+type (
+	WritePreprocessingLogParams struct {
+		SIPPath string
+
+		// Log is the preprocessing log to write, its WorkerVersion is set by
+		// the activity.
+		Log eventlog.Log
+	}
+
+	WritePreprocessingLogResult struct {
+		// MetadataCreated is true if the SIP metadata directory was created
+		// to write the log.
+		MetadataCreated bool
+	}
+
+	WritePreprocessingLogActivity struct {
+		workerVersion string
+	}
+)
+
+// NewWritePreprocessingLog returns an activity that writes the preprocessing
+// log of a SIP, recording the version of the worker.
+func NewWritePreprocessingLog(workerVersion string) *WritePreprocessingLogActivity {
+	return &WritePreprocessingLogActivity{workerVersion: workerVersion}
+}
+
+// Execute writes the preprocessing log as JSON to PreprocessingLogPath in the
+// SIP. A log already at that path is replaced if it was written by the same
+// workflow run, e.g. by a previous attempt of the activity, or renamed with
+// its run ID as suffix if it was written by another run, e.g. before the SIP
+// was quarantined and restored. The activity fails without retries if there's
+// another file at that path.
+func (a *WritePreprocessingLogActivity) Execute(
+	ctx context.Context,
+	params *WritePreprocessingLogParams,
+) (*WritePreprocessingLogResult, error) {
+	log := params.Log
+	log.WorkerVersion = a.workerVersion
+
+	created, err := writePreprocessingLog(filepath.Join(params.SIPPath, PreprocessingLogPath), log)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, temporal.NewNonRetryableError(fmt.Errorf("%s: %v", WritePreprocessingLogName, err))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", WritePreprocessingLogName, err)
+	}
+
+	return &WritePreprocessingLogResult{MetadataCreated: created}, nil
+}
+
+// writePreprocessingLog writes log to p, creating its parent directory if
+// needed, and reports whether the directory was created.
+func writePreprocessingLog(p string, log eventlog.Log) (bool, error) {
+	dir := filepath.Dir(p)
+	_, err := os.Stat(dir)
+	created := errors.Is(err, fs.ErrNotExist)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return false, err
+	}
+
+	runID, err := logRunID(p)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return false, err
+	case runID != "" && runID == log.RunID:
+	case uuid.Validate(runID) == nil:
+		// Run IDs are UUIDs, which can't escape the metadata directory.
+		rotated := strings.TrimSuffix(p, ".json") + "-" + runID + ".json"
+		if err := os.Rename(p, rotated); err != nil {
+			return false, err
+		}
+	default:
+		return false, fmt.Errorf("%s: %w", PreprocessingLogPath, fs.ErrExist)
+	}
+
+	b, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return false, err
+	}
+
+	return created, os.WriteFile(p, b, 0o600)
+}
+
+// logRunID returns the run ID of the preprocessing log at p, an empty string
+// if the file at p isn't a preprocessing log or an fs.ErrNotExist error if
+// there's no file at p.
+func logRunID(p string) (string, error) {
+	b, err := os.ReadFile(p) // #nosec G304 -- path is relative to the SIP.
+	if err != nil {
+		return "", err
+	}
+
+	var log eventlog.Log
+	if err := json.Unmarshal(b, &log); err != nil {
+		return "", nil
+	}
+
+	return log.RunID, nil
+}
@@ -0,0 +1,208 @@
+package activities_test
+
+import (
+	"testing"
+	"time"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/enums"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+)
+
+const expectedPreprocessingLog = `{
+  "WorkflowID": "workflow-id",
+  "RunID": "run-id",
+  "RelativePath": "transfer",
+  "Profile": "acme",
+  "WorkerVersion": "v1.0.0",
+  "ConfigFingerprint": "sha256:abc",
+  "CreatedAt": "2024-05-01T10:00:00Z",
+  "Events": [
+    {
+      "Name": "Check SIP files",
+      "Message": "Warning: file checks have failed:\nempty file: \"empty.txt\"",
+      "Outcome": "warning",
+      "StartedAt": "2024-05-01T09:00:00Z",
+      "CompletedAt": "2024-05-01T09:30:00Z",
+      "Failures": [
+        {
+          "Path": "empty.txt",
+          "Check": "empty file",
+          "Code": "empty-file",
+          "Message": "empty file: \"empty.txt\"",
+          "PUID": ""
+        }
+      ]
+    }
+  ]
+}`
+
+func TestWritePreprocessingLog(t *testing.T) {
+	t.Parallel()
+
+	sip := fs.NewDir(t, "", fs.WithFile("empty.txt", ""))
+	startedAt := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+	env := ts.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(
+		activities.NewWritePreprocessingLog("v1.0.0").Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.WritePreprocessingLogName},
+	)
+
+	future, err := env.ExecuteActivity(
+		activities.WritePreprocessingLogName,
+		&activities.WritePreprocessingLogParams{
+			SIPPath: sip.Path(),
+			Log: eventlog.Log{
+				WorkflowID:   "workflow-id",
+				RunID:        "run-id",
+				RelativePath: "transfer",
+				Profile:      "acme",
+				CreatedAt:    startedAt.Add(time.Hour),
+
+				ConfigFingerprint: "sha256:abc",
+				Events: []*eventlog.Event{
+					{
+						Name:        "Check SIP files",
+						Message:     "Warning: file checks have failed:\nempty file: \"empty.txt\"",
+						Outcome:     enums.EventOutcomeWarning,
+						StartedAt:   startedAt,
+						CompletedAt: startedAt.Add(30 * time.Minute),
+						Failures: []eventlog.Failure{
+							{
+								Path:    "empty.txt",
+								Check:   "empty file",
+								Code:    "empty-file",
+								Message: "empty file: \"empty.txt\"",
+							},
+						},
+					},
+				},
+			},
+		},
+	)
+	assert.NilError(t, err)
+
+	var res activities.WritePreprocessingLogResult
+	future.Get(&res)
+	assert.DeepEqual(t, res, activities.WritePreprocessingLogResult{MetadataCreated: true})
+	assert.Assert(t, fs.Equal(sip.Path(), fs.Expected(t, fs.MatchAnyFileMode,
+		fs.WithFile("empty.txt", "", fs.MatchAnyFileMode),
+		fs.WithDir("metadata", fs.MatchAnyFileMode,
+			fs.WithFile("preprocessing-log.json", expectedPreprocessingLog, fs.MatchAnyFileMode),
+		),
+	)))
+}
+
+func TestWritePreprocessingLogExisting(t *testing.T) {
+	t.Parallel()
+
+	otherRunLog := `{"WorkflowID": "workflow-id", "RunID": "0c5e6f7a-1b2c-4d3e-8f90-a1b2c3d4e5f6"}`
+
+	tests := []struct {
+		name     string
+		existing string
+		want     activities.WritePreprocessingLogResult
+		wantErr  string
+		wantLog  string
+		rotated  map[string]string
+	}{
+		{
+			name:     "Replaces a log written by the same workflow run",
+			existing: `{"WorkflowID": "workflow-id", "RunID": "run-id"}`,
+			wantLog: `{
+  "WorkflowID": "workflow-id",
+  "RunID": "run-id",
+  "RelativePath": "transfer",
+  "WorkerVersion": "v1.0.0",
+  "ConfigFingerprint": "",
+  "CreatedAt": "0001-01-01T00:00:00Z",
+  "Events": null
+}`,
+		},
+		{
+			name:     "Renames the log of another workflow run",
+			existing: otherRunLog,
+			wantLog: `{
+  "WorkflowID": "workflow-id",
+  "RunID": "run-id",
+  "RelativePath": "transfer",
+  "WorkerVersion": "v1.0.0",
+  "ConfigFingerprint": "",
+  "CreatedAt": "0001-01-01T00:00:00Z",
+  "Events": null
+}`,
+			rotated: map[string]string{
+				"preprocessing-log-0c5e6f7a-1b2c-4d3e-8f90-a1b2c3d4e5f6.json": otherRunLog,
+			},
+		},
+		{
+			name:     "Fails when the SIP has a log with an invalid run ID",
+			existing: `{"WorkflowID": "workflow-id", "RunID": "../../run-id"}`,
+			wantErr:  "write-preprocessing-log: metadata/preprocessing-log.json: file already exists",
+			wantLog:  `{"WorkflowID": "workflow-id", "RunID": "../../run-id"}`,
+		},
+		{
+			name:     "Fails when the SIP has a producer file at the log path",
+			existing: "producer notes",
+			wantErr:  "write-preprocessing-log: metadata/preprocessing-log.json: file already exists",
+			wantLog:  "producer notes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sip := fs.NewDir(t, "",
+				fs.WithDir("metadata",
+					fs.WithFile("preprocessing-log.json", tt.existing),
+				),
+			)
+
+			ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+			env := ts.NewTestActivityEnvironment()
+			env.RegisterActivityWithOptions(
+				activities.NewWritePreprocessingLog("v1.0.0").Execute,
+				temporalsdk_activity.RegisterOptions{Name: activities.WritePreprocessingLogName},
+			)
+
+			future, err := env.ExecuteActivity(
+				activities.WritePreprocessingLogName,
+				&activities.WritePreprocessingLogParams{
+					SIPPath: sip.Path(),
+					Log: eventlog.Log{
+						WorkflowID:   "workflow-id",
+						RunID:        "run-id",
+						RelativePath: "transfer",
+					},
+				},
+			)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NilError(t, err)
+
+				var res activities.WritePreprocessingLogResult
+				future.Get(&res)
+				assert.DeepEqual(t, res, tt.want)
+			}
+
+			metadata := []fs.PathOp{
+				fs.MatchAnyFileMode,
+				fs.WithFile("preprocessing-log.json", tt.wantLog, fs.MatchAnyFileMode),
+			}
+			for name, content := range tt.rotated {
+				metadata = append(metadata, fs.WithFile(name, content, fs.MatchAnyFileMode))
+			}
+			assert.Assert(t, fs.Equal(sip.Path(), fs.Expected(t, fs.MatchAnyFileMode,
+				fs.WithDir("metadata", metadata...),
+			)))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/report"
+)
+
+const WriteValidationReportName = "write-validation-report"
+
+type (
+	WriteValidationReportParams struct {
+		// Path is the path of the report file.
+		Path string
+
+		// Report is the validation report to write, its AllowedFormats are
+		// read from AllowlistPath by the activity.
+		Report report.Report
+
+		// AllowlistPath is the path of the file format allowlist of the SIP
+		// (optional).
+		AllowlistPath string
+	}
+
+	WriteValidationReportResult struct {
+		// Path is the path of the report file.
+		Path string
+	}
+
+	WriteValidationReportActivity struct{}
+)
+
+func NewWriteValidationReport() *WriteValidationReportActivity {
+	return &WriteValidationReportActivity{}
+}
+
+// Execute writes the validation report as HTML to params.Path, replacing an
+// existing report.
+func (a *WriteValidationReportActivity) Execute(
+	ctx context.Context,
+	params *WriteValidationReportParams,
+) (*WriteValidationReportResult, error) {
+	r := params.Report
+	if params.AllowlistPath != "" {
+		formats, err := report.ReadAllowedFormats(params.AllowlistPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: read allowlist: %v", WriteValidationReportName, err)
+		}
+		r.AllowedFormats = formats
+	}
+
+	if err := report.Write(params.Path, r); err != nil {
+		return nil, fmt.Errorf("%s: %v", WriteValidationReportName, err)
+	}
+
+	return &WriteValidationReportResult{Path: params.Path}, nil
+}
@@ -0,0 +1,60 @@
+package activities_test
+
+import (
+	"os"
+	"testing"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/report"
+)
+
+func TestWriteValidationReport(t *testing.T) {
+	t.Parallel()
+
+	dir := fs.NewDir(t, "",
+		fs.WithFile("allowlist.csv", "Format name,Pronom PUID\nPDF/A,fmt/95\n"),
+		fs.WithFile("invalid.csv", "Format name\nPDF/A\n"),
+	)
+
+	ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+	env := ts.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(
+		activities.NewWriteValidationReport().Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.WriteValidationReportName},
+	)
+
+	future, err := env.ExecuteActivity(
+		activities.WriteValidationReportName,
+		&activities.WriteValidationReportParams{
+			Path:          dir.Join("transfer-validation-report.html"),
+			Report:        report.Report{SIPName: "transfer", Outcome: "success"},
+			AllowlistPath: dir.Join("allowlist.csv"),
+		},
+	)
+	assert.NilError(t, err)
+
+	var res activities.WriteValidationReportResult
+	future.Get(&res)
+	assert.DeepEqual(t, res, activities.WriteValidationReportResult{
+		Path: dir.Join("transfer-validation-report.html"),
+	})
+
+	b, err := os.ReadFile(res.Path)
+	assert.NilError(t, err)
+	assert.Assert(t, is.Contains(string(b), "<tr><td>PDF/A</td><td>fmt/95</td></tr>"))
+
+	_, err = env.ExecuteActivity(
+		activities.WriteValidationReportName,
+		&activities.WriteValidationReportParams{
+			Path:          dir.Join("transfer-validation-report.html"),
+			AllowlistPath: dir.Join("invalid.csv"),
+		},
+	)
+	assert.ErrorContains(t, err, `write-validation-report: read allowlist: missing "PRONOM PUID" column`)
+}
@@ -0,0 +1,397 @@
+// Package audit records the preprocessing runs in an embedded SQLite database,
+// so their results can be queried after the workflows are archived in
+// Temporal, and aggregates them into statistics.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // SQLite driver.
+)
+
+// timeLayout is the layout of the stored times, in UTC. It has a fixed width
+// so the times sort as strings.
+const timeLayout = "2006-01-02T15:04:05.000Z"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	workflow_id      TEXT NOT NULL,
+	run_id           TEXT NOT NULL,
+	relative_path    TEXT NOT NULL,
+	sip_id           TEXT NOT NULL,
+	sip_name         TEXT NOT NULL,
+	producer         TEXT NOT NULL,
+	accession_number TEXT NOT NULL,
+	profile          TEXT NOT NULL,
+	language         TEXT NOT NULL,
+	dry_run          INTEGER NOT NULL,
+	outcome          TEXT NOT NULL,
+	started_at       TEXT NOT NULL,
+	completed_at     TEXT NOT NULL,
+	duration_ms      INTEGER NOT NULL,
+	files            INTEGER NOT NULL,
+	size             INTEGER NOT NULL,
+	failures         INTEGER NOT NULL,
+	warnings         INTEGER NOT NULL,
+	PRIMARY KEY (workflow_id, run_id)
+);
+CREATE INDEX IF NOT EXISTS runs_started_at ON runs (started_at);
+CREATE TABLE IF NOT EXISTS tasks (
+	workflow_id  TEXT NOT NULL,
+	run_id       TEXT NOT NULL,
+	position     INTEGER NOT NULL,
+	code         TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	outcome      TEXT NOT NULL,
+	started_at   TEXT NOT NULL,
+	completed_at TEXT NOT NULL,
+	duration_ms  INTEGER NOT NULL,
+	PRIMARY KEY (workflow_id, run_id, position)
+);
+CREATE TABLE IF NOT EXISTS formats (
+	workflow_id TEXT NOT NULL,
+	run_id      TEXT NOT NULL,
+	puid        TEXT NOT NULL,
+	files       INTEGER NOT NULL,
+	size        INTEGER NOT NULL,
+	PRIMARY KEY (workflow_id, run_id, puid)
+);
+`
+
+// Run is the record of a preprocessing workflow run.
+type Run struct {
+	// WorkflowID and RunID identify the workflow run.
+	WorkflowID string
+	RunID      string
+
+	// RelativePath, SIPID, SIPName, Producer, AccessionNumber, Profile,
+	// Language and DryRun are the workflow params, with the resolved SIP
+	// name, profile and language.
+	RelativePath    string
+	SIPID           string
+	SIPName         string
+	Producer        string
+	AccessionNumber string
+	Profile         string
+	Language        string
+	DryRun          bool
+
+	// Outcome is the outcome of the workflow.
+	Outcome string
+
+	StartedAt   time.Time
+	CompletedAt time.Time
+
+	// Files and Size are the number and the total size in bytes of the SIP
+	// files.
+	Files int
+	Size  int64
+
+	// Failures and Warnings are the number of validation failures and
+	// warnings.
+	Failures int
+	Warnings int
+
+	// Tasks lists the preprocessing steps, in execution order.
+	Tasks []Task
+
+	// Formats is the distribution of the SIP file formats.
+	Formats []Format
+}
+
+// Task is the record of a preprocessing step.
+type Task struct {
+	Code        string
+	Name        string
+	Outcome     string
+	StartedAt   time.Time
+	CompletedAt time.Time
+}
+
+// Format is the number and the total size in bytes of the SIP files in a file
+// format.
+type Format struct {
+	PUID  string
+	Files int
+	Size  int64
+}
+
+type DB struct {
+	db *sql.DB
+}
+
+// Open opens the database at path, creating it if needed.
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("audit: open: %v", err)
+	}
+	// SQLite allows a single writer, serialize the connections.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("audit: create schema: %v", err)
+	}
+
+	return &DB{db: db}, nil
+}
+
+func (db *DB) Close() error {
+	return db.db.Close()
+}
+
+// Record records r, replacing an existing record of the same run.
+func (db *DB) Record(ctx context.Context, r Run) (err error) {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("audit: record: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(fmt.Errorf("audit: record: %v", err), tx.Rollback())
+		}
+	}()
+
+	for _, table := range []string{"tasks", "formats"} {
+		// #nosec G202 -- constant table names.
+		if _, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM "+table+" WHERE workflow_id = ? AND run_id = ?",
+			r.WorkflowID,
+			r.RunID,
+		); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT OR REPLACE INTO runs VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.WorkflowID, r.RunID, r.RelativePath, r.SIPID, r.SIPName, r.Producer, r.AccessionNumber, r.Profile,
+		r.Language, r.DryRun, r.Outcome, formatTime(r.StartedAt), formatTime(r.CompletedAt),
+		r.CompletedAt.Sub(r.StartedAt).Milliseconds(), r.Files, r.Size, r.Failures, r.Warnings,
+	); err != nil {
+		return err
+	}
+
+	for i, t := range r.Tasks {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO tasks VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			r.WorkflowID, r.RunID, i, t.Code, t.Name, t.Outcome, formatTime(t.StartedAt),
+			formatTime(t.CompletedAt), t.CompletedAt.Sub(t.StartedAt).Milliseconds(),
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range r.Formats {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO formats VALUES (?, ?, ?, ?, ?)`,
+			r.WorkflowID, r.RunID, f.PUID, f.Files, f.Size,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Filter selects runs. Zero values match every run.
+type Filter struct {
+	Outcome  string
+	Producer string
+	Profile  string
+
+	// Since and Until select the runs started in [Since, Until).
+	Since time.Time
+	Until time.Time
+
+	// Limit is the maximum number of runs returned by Runs.
+	Limit int
+}
+
+// where returns the SQL condition and arguments of f on the runs table
+// aliased as r.
+func (f Filter) where() (string, []any) {
+	conds := []string{"1 = 1"}
+	var args []any
+	for _, c := range []struct {
+		column string
+		value  string
+	}{
+		{"r.outcome", f.Outcome},
+		{"r.producer", f.Producer},
+		{"r.profile", f.Profile},
+	} {
+		if c.value != "" {
+			conds = append(conds, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if !f.Since.IsZero() {
+		conds = append(conds, "r.started_at >= ?")
+		args = append(args, formatTime(f.Since))
+	}
+	if !f.Until.IsZero() {
+		conds = append(conds, "r.started_at < ?")
+		args = append(args, formatTime(f.Until))
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+// Runs returns the runs selected by f, most recent first, without their tasks
+// and formats.
+func (db *DB) Runs(ctx context.Context, f Filter) ([]Run, error) {
+	where, args := f.where()
+	query := `SELECT workflow_id, run_id, relative_path, sip_id, sip_name, producer, accession_number, profile,
+	language, dry_run, outcome, started_at, completed_at, files, size, failures, warnings
+	FROM runs r WHERE ` + where + ` ORDER BY started_at DESC, workflow_id`
+	if f.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(f.Limit)
+	}
+
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("audit: runs: %v", err)
+	}
+	defer rows.Close()
+
+	var runs []Run
+	for rows.Next() {
+		var r Run
+		var startedAt, completedAt string
+		if err := rows.Scan(
+			&r.WorkflowID, &r.RunID, &r.RelativePath, &r.SIPID, &r.SIPName, &r.Producer, &r.AccessionNumber,
+			&r.Profile, &r.Language, &r.DryRun, &r.Outcome, &startedAt, &completedAt, &r.Files, &r.Size,
+			&r.Failures, &r.Warnings,
+		); err != nil {
+			return nil, fmt.Errorf("audit: runs: %v", err)
+		}
+		r.StartedAt, _ = time.Parse(timeLayout, startedAt)
+		r.CompletedAt, _ = time.Parse(timeLayout, completedAt)
+		runs = append(runs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("audit: runs: %v", err)
+	}
+
+	return runs, nil
+}
+
+// Stat aggregates the runs of a group.
+type Stat struct {
+	// Key is the value of the grouping, e.g. the outcome.
+	Key string
+
+	// Runs is the number of runs, or the number of tasks when grouped by step.
+	Runs int
+
+	// Files and Size are the total number and size in bytes of the files.
+	Files int64
+	Size  int64
+
+	// Failures and Warnings are the total number of validation failures and
+	// warnings, or the number of failed tasks and tasks with warnings when
+	// grouped by step.
+	Failures int64
+	Warnings int64
+
+	// MeanDuration is the mean duration of the runs, or of the tasks when
+	// grouped by step.
+	MeanDuration time.Duration
+}
+
+// statQueries are the queries of the statistics by grouping, with a %s verb
+// for the filter condition.
+var statQueries = map[string]string{
+	"day":      runStatsQuery("substr(r.started_at, 1, 10)"),
+	"outcome":  runStatsQuery("r.outcome"),
+	"producer": runStatsQuery("r.producer"),
+	"profile":  runStatsQuery("r.profile"),
+	"step": `SELECT t.code, count(*), 0, 0,
+	sum(t.outcome IN ('validation failure', 'system failure')), sum(t.outcome = 'warning'), avg(t.duration_ms)
+	FROM tasks t JOIN runs r USING (workflow_id, run_id) WHERE %s GROUP BY t.code ORDER BY t.code`,
+	"format": `SELECT f.puid, count(*), sum(f.files), sum(f.size), 0, 0, avg(r.duration_ms)
+	FROM formats f JOIN runs r USING (workflow_id, run_id) WHERE %s GROUP BY f.puid ORDER BY f.puid`,
+}
+
+func runStatsQuery(key string) string {
+	return `SELECT ` + key + `, count(*), sum(r.files), sum(r.size), sum(r.failures), sum(r.warnings),
+	avg(r.duration_ms) FROM runs r WHERE %s GROUP BY 1 ORDER BY 1`
+}
+
+// Groupings lists the groupings of the statistics.
+var Groupings = []string{"day", "outcome", "producer", "profile", "step", "format"}
+
+// Stats returns the statistics of the runs selected by f, grouped by one of
+// Groupings, in key order. f.Limit is ignored.
+func (db *DB) Stats(ctx context.Context, by string, f Filter) ([]Stat, error) {
+	query, ok := statQueries[by]
+	if !ok {
+		return nil, fmt.Errorf(
+			"audit: stats: invalid grouping %q, must be one of (%s)",
+			by,
+			strings.Join(Groupings, ", "),
+		)
+	}
+	where, args := f.where()
+
+	rows, err := db.db.QueryContext(ctx, fmt.Sprintf(query, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("audit: stats: %v", err)
+	}
+	defer rows.Close()
+
+	var stats []Stat
+	for rows.Next() {
+		var s Stat
+		var meanMS float64
+		if err := rows.Scan(&s.Key, &s.Runs, &s.Files, &s.Size, &s.Failures, &s.Warnings, &meanMS); err != nil {
+			return nil, fmt.Errorf("audit: stats: %v", err)
+		}
+		s.MeanDuration = time.Duration(meanMS * float64(time.Millisecond)).Round(time.Millisecond)
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("audit: stats: %v", err)
+	}
+
+	return stats, nil
+}
+
+// WriteCSV writes the statistics grouped by a grouping as CSV to w, with a
+// header row.
+func WriteCSV(w io.Writer, by string, stats []Stat) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{by, "runs", "files", "size", "failures", "warnings", "mean_duration_seconds"})
+	for _, s := range stats {
+		_ = cw.Write([]string{
+			s.Key,
+			strconv.Itoa(s.Runs),
+			strconv.FormatInt(s.Files, 10),
+			strconv.FormatInt(s.Size, 10),
+			strconv.FormatInt(s.Failures, 10),
+			strconv.FormatInt(s.Warnings, 10),
+			strconv.FormatFloat(s.MeanDuration.Seconds(), 'f', 3, 64),
+		})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
@@ -0,0 +1,211 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/audit"
+)
+
+var day = time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+var runs = []audit.Run{
+	{
+		WorkflowID:   "preprocessing-1",
+		RunID:        "run-1",
+		RelativePath: "acme/sip-1",
+		SIPName:      "sip-1",
+		Producer:     "Acme",
+		Profile:      "acme",
+		Language:     "en",
+		Outcome:      "success",
+		StartedAt:    day,
+		CompletedAt:  day.Add(2 * time.Minute),
+		Files:        3,
+		Size:         3000,
+		Tasks: []audit.Task{
+			{
+				Code:        "check-files",
+				Name:        "Check SIP files",
+				Outcome:     "success",
+				StartedAt:   day,
+				CompletedAt: day.Add(time.Minute),
+			},
+			{Code: "bag-sip", Name: "Bag SIP", Outcome: "success", StartedAt: day, CompletedAt: day.Add(time.Minute)},
+		},
+		Formats: []audit.Format{{PUID: "fmt/95", Files: 2, Size: 2000}, {PUID: "x-fmt/16", Files: 1, Size: 1000}},
+	},
+	{
+		WorkflowID:   "preprocessing-2",
+		RunID:        "run-2",
+		RelativePath: "acme/sip-2",
+		SIPName:      "sip-2",
+		Producer:     "Acme",
+		Profile:      "acme",
+		Language:     "en",
+		Outcome:      "content error",
+		StartedAt:    day.Add(time.Hour),
+		CompletedAt:  day.Add(time.Hour + time.Minute),
+		Files:        1,
+		Size:         500,
+		Failures:     2,
+		Warnings:     1,
+		Tasks: []audit.Task{
+			{
+				Code:        "check-files",
+				Name:        "Check SIP files",
+				Outcome:     "validation failure",
+				StartedAt:   day.Add(time.Hour),
+				CompletedAt: day.Add(time.Hour + 3*time.Minute),
+			},
+		},
+		Formats: []audit.Format{{PUID: "fmt/95", Files: 1, Size: 500}},
+	},
+	{
+		WorkflowID:   "preprocessing-3",
+		RunID:        "run-3",
+		RelativePath: "sip-3",
+		SIPName:      "sip-3",
+		Language:     "fr",
+		DryRun:       true,
+		Outcome:      "success",
+		StartedAt:    day.Add(24 * time.Hour),
+		CompletedAt:  day.Add(24*time.Hour + 30*time.Second),
+		Files:        1,
+		Size:         10,
+	},
+}
+
+func openDB(t *testing.T) *audit.DB {
+	t.Helper()
+
+	db, err := audit.Open(filepath.Join(t.TempDir(), "audit.db"))
+	assert.NilError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	for _, r := range runs {
+		assert.NilError(t, db.Record(context.Background(), r))
+	}
+
+	return db
+}
+
+// summary returns r without its tasks and formats.
+func summary(r audit.Run) audit.Run {
+	r.Tasks = nil
+	r.Formats = nil
+	return r
+}
+
+func TestRuns(t *testing.T) {
+	t.Parallel()
+
+	db := openDB(t)
+
+	for _, tt := range []struct {
+		name   string
+		filter audit.Filter
+		want   []audit.Run
+	}{
+		{
+			name: "Returns all the runs, most recent first",
+			want: []audit.Run{summary(runs[2]), summary(runs[1]), summary(runs[0])},
+		},
+		{
+			name:   "Filters by outcome and producer",
+			filter: audit.Filter{Outcome: "success", Producer: "Acme"},
+			want:   []audit.Run{summary(runs[0])},
+		},
+		{
+			name:   "Filters by start time",
+			filter: audit.Filter{Since: day.Add(time.Hour), Until: day.Add(24 * time.Hour)},
+			want:   []audit.Run{summary(runs[1])},
+		},
+		{
+			name:   "Limits the runs",
+			filter: audit.Filter{Profile: "acme", Limit: 1},
+			want:   []audit.Run{summary(runs[1])},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := db.Runs(context.Background(), tt.filter)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	db := openDB(t)
+
+	for _, tt := range []struct {
+		by      string
+		filter  audit.Filter
+		want    string
+		wantErr string
+	}{
+		{
+			by: "day",
+			want: `day,runs,files,size,failures,warnings,mean_duration_seconds
+2024-05-01,2,4,3500,2,1,90.000
+2024-05-02,1,1,10,0,0,30.000
+`,
+		},
+		{
+			by: "outcome",
+			want: `outcome,runs,files,size,failures,warnings,mean_duration_seconds
+content error,1,1,500,2,1,60.000
+success,2,4,3010,0,0,75.000
+`,
+		},
+		{
+			by:     "producer",
+			filter: audit.Filter{Until: day.Add(24 * time.Hour)},
+			want: `producer,runs,files,size,failures,warnings,mean_duration_seconds
+Acme,2,4,3500,2,1,90.000
+`,
+		},
+		{
+			by: "step",
+			want: `step,runs,files,size,failures,warnings,mean_duration_seconds
+bag-sip,1,0,0,0,0,60.000
+check-files,2,0,0,1,0,120.000
+`,
+		},
+		{
+			by: "format",
+			want: `format,runs,files,size,failures,warnings,mean_duration_seconds
+fmt/95,2,3,2500,0,0,90.000
+x-fmt/16,1,1,1000,0,0,120.000
+`,
+		},
+		{
+			by:      "sip",
+			wantErr: `audit: stats: invalid grouping "sip", must be one of (day, outcome, producer, profile, step, format)`,
+		},
+	} {
+		t.Run(tt.by, func(t *testing.T) {
+			t.Parallel()
+
+			stats, err := db.Stats(context.Background(), tt.by, tt.filter)
+			if tt.wantErr != "" {
+				assert.Error(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+
+			var buf bytes.Buffer
+			assert.NilError(t, audit.WriteCSV(&buf, tt.by, stats))
+			assert.Equal(t, buf.String(), tt.want)
+		})
+	}
+}
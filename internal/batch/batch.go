@@ -0,0 +1,84 @@
+// Package batch describes the summary report of a batch preprocessing
+// workflow and writes it to a report directory:
+//
+//	<report path>/<id>.json
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Report summarizes the preprocessing of a batch of SIPs.
+type Report struct {
+	// ID identifies the batch (the batch workflow ID).
+	ID string
+
+	StartedAt   time.Time
+	CompletedAt time.Time
+
+	// Outcomes is the number of SIPs by preprocessing outcome.
+	Outcomes map[string]int
+
+	// SIPs lists the preprocessing result of each SIP, in batch order.
+	SIPs []SIP
+}
+
+// SIP is the preprocessing result of a SIP in a batch.
+type SIP struct {
+	// RelativePath is the path of the SIP relative to the shared path.
+	RelativePath string
+
+	// WorkflowID is the ID of the preprocessing workflow of the SIP.
+	WorkflowID string
+
+	// Outcome is the outcome of the preprocessing workflow.
+	Outcome string
+
+	// Failures and Warnings are the number of validation failures and
+	// warnings.
+	Failures int
+	Warnings int
+
+	// QuarantinePath is the path of the SIP in quarantine, if the SIP was
+	// rejected and moved to quarantine.
+	QuarantinePath string `json:",omitempty"`
+
+	// Error is the error of a preprocessing workflow that failed to run.
+	Error string `json:",omitempty"`
+}
+
+// Add adds the result of a SIP to the report.
+func (r *Report) Add(s SIP) {
+	if r.Outcomes == nil {
+		r.Outcomes = map[string]int{}
+	}
+	r.Outcomes[s.Outcome]++
+	r.SIPs = append(r.SIPs, s)
+}
+
+// Write writes the report as JSON to reportPath, named after the report ID. It
+// returns the path of the report file.
+func Write(reportPath string, r Report) (string, error) {
+	if !filepath.IsLocal(r.ID) {
+		return "", fmt.Errorf("invalid ID: %q", r.ID)
+	}
+
+	if err := os.MkdirAll(reportPath, 0o700); err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	p := filepath.Join(reportPath, r.ID+".json")
+	if err := os.WriteFile(p, b, 0o600); err != nil {
+		return "", fmt.Errorf("write report: %v", err)
+	}
+
+	return p, nil
+}
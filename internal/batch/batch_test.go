@@ -0,0 +1,74 @@
+package batch_test
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/batch"
+)
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 6, 14, 48, 12, 0, time.UTC)
+	r := batch.Report{
+		ID:          "batch-workflow-1",
+		StartedAt:   now,
+		CompletedAt: now.Add(time.Hour),
+	}
+	r.Add(batch.SIP{
+		RelativePath: "batch/sip-a",
+		WorkflowID:   "batch-workflow-1-1",
+		Outcome:      "success",
+	})
+	r.Add(batch.SIP{
+		RelativePath:   "batch/sip-b",
+		WorkflowID:     "batch-workflow-1-2",
+		Outcome:        "content error",
+		Failures:       2,
+		Warnings:       1,
+		QuarantinePath: "/home/preprocessing/quarantine/batch-workflow-1-2/sip-b",
+	})
+	assert.DeepEqual(t, r.Outcomes, map[string]int{"success": 1, "content error": 1})
+
+	dir := fs.NewDir(t, "")
+	p, err := batch.Write(dir.Join("reports"), r)
+	assert.NilError(t, err)
+	assert.Equal(t, p, dir.Join("reports", "batch-workflow-1.json"))
+	assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t,
+		fs.WithDir("reports", fs.WithMode(0o700),
+			fs.WithFile("batch-workflow-1.json", `{
+  "ID": "batch-workflow-1",
+  "StartedAt": "2024-06-06T14:48:12Z",
+  "CompletedAt": "2024-06-06T15:48:12Z",
+  "Outcomes": {
+    "content error": 1,
+    "success": 1
+  },
+  "SIPs": [
+    {
+      "RelativePath": "batch/sip-a",
+      "WorkflowID": "batch-workflow-1-1",
+      "Outcome": "success",
+      "Failures": 0,
+      "Warnings": 0
+    },
+    {
+      "RelativePath": "batch/sip-b",
+      "WorkflowID": "batch-workflow-1-2",
+      "Outcome": "content error",
+      "Failures": 2,
+      "Warnings": 1,
+      "QuarantinePath": "/home/preprocessing/quarantine/batch-workflow-1-2/sip-b"
+    }
+  ]
+}`, fs.WithMode(0o600)),
+		),
+	)))
+
+	_, err = batch.Write(dir.Path(), batch.Report{ID: "../batch"})
+	assert.Error(t, err, `invalid ID: "../batch"`)
+}
@@ -0,0 +1,204 @@
+// Package clamd provides a client for the ClamAV daemon (clamd) that scans
+// streams of data for malware using the INSTREAM command.
+package clamd
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// chunkSize is the size of the INSTREAM data chunks sent to clamd.
+const chunkSize = 64 * 1024
+
+// ErrStreamLimit is returned when a stream exceeds the clamd StreamMaxLength
+// limit (25 MB by default) and can't be scanned. Retrying doesn't help.
+var ErrStreamLimit = errors.New("clamd: file exceeds clamd stream limit (StreamMaxLength)")
+
+// streamLimitReply is the clamd reply to a stream exceeding StreamMaxLength.
+const streamLimitReply = "INSTREAM size limit exceeded. ERROR"
+
+type Config struct {
+	// Address is the clamd socket address, e.g. "tcp://clamd:3310" or
+	// "unix:///var/run/clamav/clamd.ctl". Virus scanning is disabled if
+	// Address is empty (default).
+	Address string
+
+	// Timeout limits the time to scan a single file (default: 10m).
+	Timeout time.Duration
+}
+
+func (c Config) Validate() error {
+	if c.Address == "" {
+		return nil
+	}
+	if _, _, err := parseAddress(c.Address); err != nil {
+		return fmt.Errorf("Address: %v", err)
+	}
+
+	return nil
+}
+
+// Result is the outcome of a stream scan.
+type Result struct {
+	// Infected is true if clamd found malware in the stream.
+	Infected bool
+
+	// Signature is the name of the malware signature found, if any.
+	Signature string
+}
+
+type Client struct {
+	network string
+	address string
+	timeout time.Duration
+	dialer  net.Dialer
+}
+
+func New(cfg Config) (*Client, error) {
+	network, address, err := parseAddress(cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("clamd: %v", err)
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Minute
+	}
+
+	return &Client{network: network, address: address, timeout: timeout}, nil
+}
+
+// Scan streams the data read from r to clamd and returns the scan result.
+func (c *Client) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	conn, err := c.dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("clamd: connect: %v", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("clamd: %v", err)
+		}
+	}
+
+	if err := stream(conn, r); err != nil {
+		// clamd replies and closes the connection as soon as the stream
+		// exceeds its size limit, failing the following writes. Other
+		// failures, e.g. a clamd restart, are worth retrying.
+		if reply, rerr := readReply(conn); rerr == nil && reply == streamLimitReply {
+			return nil, ErrStreamLimit
+		}
+		return nil, fmt.Errorf("clamd: send stream: %w", err)
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return nil, fmt.Errorf("clamd: read reply: %w", err)
+	}
+
+	return parseReply(reply)
+}
+
+// readReply reads a null-terminated clamd reply from r.
+func readReply(r io.Reader) (string, error) {
+	reply, err := bufio.NewReader(r).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return strings.TrimRight(reply, "\x00\n"), nil
+}
+
+// stream writes the INSTREAM command to w followed by the data read from r as
+// length-prefixed chunks and a zero-length terminating chunk.
+func stream(w io.Writer, r io.Reader) error {
+	bw := bufio.NewWriterSize(w, chunkSize+4)
+	if _, err := bw.WriteString("zINSTREAM\x00"); err != nil {
+		return err
+	}
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if err := binary.Write(bw, binary.BigEndian, uint32(n)); err != nil { // #nosec G115 -- n <= chunkSize.
+				return err
+			}
+			if _, err := bw.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := bw.Write([]byte{0, 0, 0, 0}); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// parseReply parses clamd INSTREAM replies, e.g. "stream: OK",
+// "stream: Win.Test.EICAR_HDB-1 FOUND" or "INSTREAM size limit exceeded. ERROR".
+func parseReply(reply string) (*Result, error) {
+	if reply == streamLimitReply {
+		return nil, ErrStreamLimit
+	}
+
+	s, ok := strings.CutPrefix(reply, "stream: ")
+	if !ok {
+		return nil, fmt.Errorf("clamd: unexpected reply: %q", reply)
+	}
+
+	switch {
+	case s == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(s, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(s, " FOUND")}, nil
+	case strings.HasSuffix(s, " ERROR"):
+		return nil, fmt.Errorf("clamd: %s", strings.TrimSuffix(s, " ERROR"))
+	}
+
+	return nil, fmt.Errorf("clamd: unexpected reply: %q", reply)
+}
+
+// parseAddress returns the network and address of a "tcp://host:port" or
+// "unix:///path" URL.
+func parseAddress(s string) (network, address string, err error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid address %q: %v", s, err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		if u.Host == "" {
+			return "", "", fmt.Errorf("invalid address %q: missing host", s)
+		}
+		return "tcp", u.Host, nil
+	case "unix":
+		if u.Path == "" {
+			return "", "", fmt.Errorf("invalid address %q: missing path", s)
+		}
+		return "unix", u.Path, nil
+	}
+
+	return "", "", fmt.Errorf("invalid address %q: scheme must be one of (tcp, unix)", s)
+}
@@ -0,0 +1,149 @@
+package clamd_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/clamd"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/clamd/clamdtest"
+)
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		cfg     clamd.Config
+		wantErr string
+	}{
+		{
+			name: "Accepts an empty address",
+			cfg:  clamd.Config{},
+		},
+		{
+			name: "Accepts a TCP address",
+			cfg:  clamd.Config{Address: "tcp://clamd:3310"},
+		},
+		{
+			name: "Accepts a Unix socket address",
+			cfg:  clamd.Config{Address: "unix:///var/run/clamav/clamd.ctl"},
+		},
+		{
+			name:    "Rejects an unknown scheme",
+			cfg:     clamd.Config{Address: "http://clamd:3310"},
+			wantErr: `Address: invalid address "http://clamd:3310": scheme must be one of (tcp, unix)`,
+		},
+		{
+			name:    "Rejects a TCP address without host",
+			cfg:     clamd.Config{Address: "tcp://"},
+			wantErr: `Address: invalid address "tcp://": missing host`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.Validate()
+			if tc.wantErr != "" {
+				assert.Error(t, err, tc.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		network string
+		content string
+		want    *clamd.Result
+	}{
+		{
+			name:    "Scans clean content over TCP",
+			network: "tcp",
+			content: "I am a clean file.\n",
+			want:    &clamd.Result{},
+		},
+		{
+			name:    "Detects malware over TCP",
+			network: "tcp",
+			content: clamdtest.EICAR,
+			want:    &clamd.Result{Infected: true, Signature: clamdtest.EICARSignature},
+		},
+		{
+			name:    "Detects malware over a Unix socket",
+			network: "unix",
+			content: clamdtest.EICAR,
+			want:    &clamd.Result{Infected: true, Signature: clamdtest.EICARSignature},
+		},
+		{
+			name:    "Detects malware in large content",
+			network: "tcp",
+			content: strings.Repeat("a", 100_000) + clamdtest.EICAR,
+			want:    &clamd.Result{Infected: true, Signature: clamdtest.EICARSignature},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := clamdtest.NewServer(t, tc.network)
+			c, err := clamd.New(clamd.Config{Address: srv.Address})
+			assert.NilError(t, err)
+
+			got, err := c.Scan(context.Background(), strings.NewReader(tc.content))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, got, tc.want)
+		})
+	}
+
+	for _, network := range []string{"tcp", "unix"} {
+		t.Run("Errors when the stream exceeds the clamd limit over "+network, func(t *testing.T) {
+			t.Parallel()
+
+			srv := clamdtest.NewServer(t, network, clamdtest.WithStreamMaxLength(100_000))
+			c, err := clamd.New(clamd.Config{Address: srv.Address})
+			assert.NilError(t, err)
+
+			for _, size := range []int{200_000, 20_000_000} {
+				_, err = c.Scan(context.Background(), strings.NewReader(strings.Repeat("a", size)))
+				assert.ErrorIs(t, err, clamd.ErrStreamLimit)
+			}
+		})
+	}
+
+	for _, network := range []string{"tcp", "unix"} {
+		t.Run("Errors when clamd drops the connection over "+network, func(t *testing.T) {
+			t.Parallel()
+
+			srv := clamdtest.NewServer(t, network,
+				clamdtest.WithConnectionDrop(50_000),
+				clamdtest.WithStreamMaxLength(100_000),
+			)
+			c, err := clamd.New(clamd.Config{Address: srv.Address})
+			assert.NilError(t, err)
+
+			for _, size := range []int{60_000, 200_000, 20_000_000} {
+				_, err = c.Scan(context.Background(), strings.NewReader(strings.Repeat("a", size)))
+				assert.ErrorContains(t, err, "clamd: ")
+				assert.Assert(t, !errors.Is(err, clamd.ErrStreamLimit))
+			}
+		})
+	}
+
+	t.Run("Errors when clamd is unreachable", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := clamd.New(clamd.Config{Address: "unix://" + t.TempDir() + "/missing.sock"})
+		assert.NilError(t, err)
+
+		_, err = c.Scan(context.Background(), strings.NewReader("test"))
+		assert.ErrorContains(t, err, "clamd: connect:")
+	})
+}
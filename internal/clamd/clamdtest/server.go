@@ -0,0 +1,147 @@
+// Package clamdtest provides a fake clamd server for testing.
+package clamdtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+// EICAR is the EICAR anti-malware test file content.
+const EICAR = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// EICARSignature is the signature name reported for EICAR content.
+const EICARSignature = "Win.Test.EICAR_HDB-1"
+
+// Server is a fake clamd server that implements the INSTREAM command. Streams
+// that contain a known pattern are reported as infected.
+type Server struct {
+	// Address is the server address in the format accepted by clamd.Config.
+	Address string
+
+	ln              net.Listener
+	signatures      map[string]string
+	streamMaxLength int
+	dropAfter       int
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithStreamMaxLength limits the stream size like the clamd StreamMaxLength
+// setting: the server replies with a size limit error and closes the
+// connection as soon as a stream exceeds n bytes.
+func WithStreamMaxLength(n int) Option {
+	return func(s *Server) {
+		s.streamMaxLength = n
+	}
+}
+
+// WithConnectionDrop makes the server close the connection without a reply,
+// like a clamd restart or a network reset, as soon as a stream exceeds n bytes.
+func WithConnectionDrop(n int) Option {
+	return func(s *Server) {
+		s.dropAfter = n
+	}
+}
+
+// NewServer starts a fake clamd server listening on network ("tcp" or "unix")
+// that detects EICAR content. The server is closed when the test ends.
+func NewServer(t testing.TB, network string, opts ...Option) *Server {
+	t.Helper()
+
+	var addr string
+	switch network {
+	case "tcp":
+		addr = "127.0.0.1:0"
+	case "unix":
+		addr = filepath.Join(t.TempDir(), "clamd.sock")
+	default:
+		t.Fatalf("clamdtest: unsupported network: %s", network)
+	}
+
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		t.Fatalf("clamdtest: listen: %v", err)
+	}
+
+	s := &Server{
+		Address:    network + "://" + ln.Addr().String(),
+		ln:         ln,
+		signatures: map[string]string{EICAR: EICARSignature},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go s.serve()
+
+	return s
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	cmd, err := r.ReadString(0)
+	if err != nil {
+		return
+	}
+	if cmd != "zINSTREAM\x00" {
+		_, _ = fmt.Fprintf(conn, "%s: Unknown command ERROR\x00", cmd[:len(cmd)-1])
+		return
+	}
+
+	var data bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if s.dropAfter > 0 && data.Len()+int(size) > s.dropAfter {
+			if tc, ok := conn.(*net.TCPConn); ok {
+				_ = tc.SetLinger(0) // Reset the connection.
+			}
+			return
+		}
+		if s.streamMaxLength > 0 && data.Len()+int(size) > s.streamMaxLength {
+			_, _ = conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+			return
+		}
+		if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+			if !errors.Is(err, io.EOF) {
+				_, _ = fmt.Fprintf(conn, "stream: %v ERROR\x00", err)
+			}
+			return
+		}
+	}
+
+	for pattern, name := range s.signatures {
+		if bytes.Contains(data.Bytes(), []byte(pattern)) {
+			_, _ = fmt.Fprintf(conn, "stream: %s FOUND\x00", name)
+			return
+		}
+	}
+
+	_, _ = conn.Write([]byte("stream: OK\x00"))
+}
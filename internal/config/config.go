@@ -1,14 +1,25 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/artefactual-sdps/temporal-activities/bagcreate"
 	"github.com/artefactual-sdps/temporal-activities/ffvalidate"
 	"github.com/spf13/viper"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/clamd"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/webhook"
 )
 
 type ConfigurationValidator interface {
@@ -32,11 +43,55 @@ type Configuration struct {
 	// Enduro and preservation processing.
 	SharedPath string
 
-	Temporal Temporal
-	Worker   WorkerConfig
+	// QuarantinePath is a directory where SIPs rejected with a content or
+	// system error are moved to, along with a failure report (optional).
+	// Rejected SIPs are left in SharedPath if QuarantinePath is empty.
+	QuarantinePath string
+
+	// Language is the language of the event names and messages, from "en"
+	// (default), "de" and "fr". SIPs can select another language with the
+	// Language workflow param.
+	Language string
+
+	Temporal   Temporal
+	Worker     WorkerConfig
+	Validation ValidationConfig
+	Review     ReviewConfig
+	Batch      BatchConfig
+
+	// ValidationReport sets the HTML validation report written next to each
+	// SIP.
+	ValidationReport ValidationReportConfig
+
+	// Audit sets the local database of the preprocessing runs.
+	Audit AuditConfig
+
+	// Signing sets the detached signature of the preprocessed SIPs.
+	Signing SigningConfig
+
+	// Webhooks lists the HTTP endpoints notified when preprocessing finishes,
+	// with a signed JSON payload (optional).
+	Webhooks []webhook.Endpoint
+
+	// Activities sets the timeouts and retry policy of the workflow
+	// activities, by activity name (e.g. "bag-create"). Unset values use the
+	// defaults of each activity.
+	Activities map[string]ActivityConfig
+
+	// DefaultProfile is the name of the profile used by the SIPs started
+	// without a profile (optional). SIPs started without a profile use the top
+	// level settings if DefaultProfile is empty.
+	DefaultProfile string
+
+	// Profiles are the processing profiles selectable per SIP, by profile
+	// name. Profile names are made of lowercase letters, digits, "-" and "_".
+	Profiles map[string]ProfileConfig
 
 	Bagit      bagcreate.Config
 	FileFormat ffvalidate.Config
+	Fixity     FixityConfig
+	Structure  StructureConfig
+	ClamAV     clamd.Config
 }
 
 type Temporal struct {
@@ -54,6 +109,15 @@ type Temporal struct {
 	// WorkflowName is the name of the preprocessing Temporal workflow
 	// (required).
 	WorkflowName string
+
+	// BatchWorkflowName is the name of the batch preprocessing Temporal
+	// workflow (default: "batch-preprocessing").
+	BatchWorkflowName string
+
+	// SearchAttributes enables the custom search attributes of the
+	// preprocessing workflow. They must be registered in Namespace first, the
+	// workflow tasks fail otherwise (default: false).
+	SearchAttributes bool
 }
 
 type WorkerConfig struct {
@@ -62,6 +126,268 @@ type WorkerConfig struct {
 	MaxConcurrentSessions int
 }
 
+type ValidationConfig struct {
+	// CollectAll runs every validation step before rejecting a SIP with
+	// content errors, so all the failures are reported at once, instead of
+	// stopping at the first failing step (default: false).
+	CollectAll bool
+
+	// CheckFiles runs the check-files step: the empty files, file names and
+	// deprecated formats checks and the SIP statistics. Its warnings give a
+	// "success with warnings" outcome, unknown to older Enduro versions, so
+	// it's opt-in (default: false).
+	CheckFiles bool
+
+	// DeprecatedFormats lists the PRONOM PUIDs of the file formats reported
+	// by the "deprecatedFormats" check (optional).
+	DeprecatedFormats []string
+
+	// FormatStatistics breaks the SIP statistics down by file format. The
+	// check-files step then identifies the format of each file, on top of the
+	// file formats validation (default: false).
+	FormatStatistics bool
+
+	// Checks sets whether each validation check blocks ingest or only warns.
+	Checks ChecksConfig
+
+	// DisabledSteps lists the validation steps to skip, from "checksums",
+	// "viruses", "structure", "files" and "fileFormats" (optional).
+	DisabledSteps []string
+}
+
+// Validation steps that can be disabled.
+const (
+	StepChecksums   = "checksums"
+	StepViruses     = "viruses"
+	StepStructure   = "structure"
+	StepFiles       = "files"
+	StepFileFormats = "fileFormats"
+)
+
+var steps = []string{StepChecksums, StepViruses, StepStructure, StepFiles, StepFileFormats}
+
+// CheckMode is the mode of a validation check.
+type CheckMode string
+
+const (
+	// CheckModeBlock rejects the SIP with a content error when the check
+	// fails. An empty mode blocks.
+	CheckModeBlock CheckMode = "block"
+
+	// CheckModeWarn reports the check failures as warnings without rejecting
+	// the SIP.
+	CheckModeWarn CheckMode = "warn"
+)
+
+// ChecksConfig sets the mode of each validation check.
+type ChecksConfig struct {
+	// Checksums is the mode of producer-supplied checksum verification
+	// (default: "block").
+	Checksums CheckMode
+
+	// Viruses is the mode of the virus scan (default: "block").
+	Viruses CheckMode
+
+	// FileFormats is the mode of the allowed file format validation
+	// (default: "block").
+	FileFormats CheckMode
+
+	// Structure is the mode of the SIP structure validation (default:
+	// "block").
+	Structure CheckMode
+
+	// EmptyFiles is the mode of the empty files check (default: "warn").
+	EmptyFiles CheckMode
+
+	// FileNames is the mode of the unusual file names check (default:
+	// "warn").
+	FileNames CheckMode
+
+	// DeprecatedFormats is the mode of the deprecated file formats check
+	// (default: "warn").
+	DeprecatedFormats CheckMode
+}
+
+type ReviewConfig struct {
+	// Enabled pauses the workflow before bagging a SIP with validation
+	// warnings until an archivist approves or rejects it (default: false).
+	Enabled bool
+
+	// Timeout is how long to wait for a review decision, with 0 waiting
+	// indefinitely (default: 0).
+	Timeout time.Duration
+
+	// TimeoutDecision is the decision made when the review times out: "reject"
+	// or "approve" (default: "reject").
+	TimeoutDecision string
+}
+
+const (
+	ReviewDecisionApprove = "approve"
+	ReviewDecisionReject  = "reject"
+)
+
+type ValidationReportConfig struct {
+	// Enabled writes a human-readable HTML validation report next to each
+	// preprocessed SIP, named "<SIP name>-validation-report.html" (default:
+	// false). Reports are written in the SIP language.
+	Enabled bool
+}
+
+type AuditConfig struct {
+	// DatabasePath is the path of a SQLite database where each preprocessing
+	// run is recorded, created if needed (optional). Runs aren't recorded if
+	// DatabasePath is empty.
+	DatabasePath string
+}
+
+type SigningConfig struct {
+	// KeyPath is the path of a PEM encoded PKCS #8 Ed25519 private key used to
+	// sign the tag manifests and premis.xml of each bagged SIP (optional).
+	// SIPs aren't signed if KeyPath is empty.
+	KeyPath string
+}
+
+type BatchConfig struct {
+	// MaxConcurrency is the maximum number of SIPs preprocessed at the same
+	// time by a batch workflow, unless set when starting the batch (default:
+	// 5).
+	MaxConcurrency int
+
+	// ReportPath is a directory where the summary report of each batch is
+	// written to, named after the batch workflow ID (optional). Reports are
+	// only returned in the batch workflow result if ReportPath is empty.
+	ReportPath string
+}
+
+// ActivityConfig sets the execution options of an activity. Zero values use
+// the activity defaults.
+type ActivityConfig struct {
+	// Timeout is the maximum time of a single activity attempt.
+	Timeout time.Duration
+
+	// TimeoutPerGB is added to Timeout for every GB (10^9 bytes) of SIP files,
+	// for the activities processing every SIP file.
+	TimeoutPerGB time.Duration
+
+	// HeartbeatTimeout is the maximum time between activity heartbeats, with
+	// 0 disabling heartbeat checks.
+	HeartbeatTimeout time.Duration
+
+	Retry RetryConfig
+}
+
+// RetryConfig sets the retry policy of an activity. Zero values use the
+// activity defaults.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of attempts, with 1 disabling retries.
+	MaxAttempts int
+
+	// InitialInterval is the time to wait before the first retry.
+	InitialInterval time.Duration
+
+	// BackoffCoefficient multiplies the wait time after each retry.
+	BackoffCoefficient float64
+
+	// MaximumInterval caps the wait time between retries.
+	MaximumInterval time.Duration
+
+	// NonRetryableErrors lists the application error types that fail the
+	// activity without retrying.
+	NonRetryableErrors []string
+}
+
+// StructureConfig sets the rules of the SIP structure validation, which is
+// skipped if no rules are set (default). Patterns use the filepath.Match syntax
+// and match the slash-separated path of a file or directory relative to the
+// SIP, or its base name if the pattern has no slash.
+type StructureConfig struct {
+	// RequiredPaths lists the patterns that must match at least one path of
+	// the SIP, e.g. "metadata/*.xml".
+	RequiredPaths []string
+
+	// ForbiddenPaths lists the patterns that must not match any path of the
+	// SIP, e.g. "Thumbs.db".
+	ForbiddenPaths []string
+}
+
+// ProfileConfig is a processing profile, overriding the top level settings of
+// the SIPs processed with the profile. Unset values use the top level
+// settings.
+type ProfileConfig struct {
+	// Bagit sets the bag settings.
+	Bagit bagcreate.Config
+
+	// FileFormat sets the allowed file formats.
+	FileFormat ffvalidate.Config
+
+	// Structure sets the SIP structure rules.
+	Structure StructureConfig
+
+	// Checks sets the validation check modes.
+	Checks ChecksConfig
+
+	// DisabledSteps lists the validation steps to skip, see
+	// ValidationConfig.DisabledSteps.
+	DisabledSteps []string
+}
+
+// profileNameRegex matches the valid profile names.
+var profileNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// WithProfile returns the configuration of the SIPs processed with the named
+// profile.
+func (c Configuration) WithProfile(name string) (Configuration, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return c, fmt.Errorf("unknown profile: %q", name)
+	}
+
+	if p.Bagit.ChecksumAlgorithm != "" {
+		c.Bagit = p.Bagit
+	}
+	if p.FileFormat.AllowlistPath != "" {
+		c.FileFormat = p.FileFormat
+	}
+	if p.Structure.RequiredPaths != nil {
+		c.Structure.RequiredPaths = p.Structure.RequiredPaths
+	}
+	if p.Structure.ForbiddenPaths != nil {
+		c.Structure.ForbiddenPaths = p.Structure.ForbiddenPaths
+	}
+	if p.DisabledSteps != nil {
+		c.Validation.DisabledSteps = p.DisabledSteps
+	}
+
+	checks := &c.Validation.Checks
+	for _, m := range []struct {
+		dst *CheckMode
+		src CheckMode
+	}{
+		{&checks.Checksums, p.Checks.Checksums},
+		{&checks.Viruses, p.Checks.Viruses},
+		{&checks.FileFormats, p.Checks.FileFormats},
+		{&checks.Structure, p.Checks.Structure},
+		{&checks.EmptyFiles, p.Checks.EmptyFiles},
+		{&checks.FileNames, p.Checks.FileNames},
+		{&checks.DeprecatedFormats, p.Checks.DeprecatedFormats},
+	} {
+		if m.src != "" {
+			*m.dst = m.src
+		}
+	}
+
+	return c, nil
+}
+
+type FixityConfig struct {
+	// ManifestNames lists the file names of the producer-supplied checksum
+	// manifests to verify (e.g. "checksums.md5", "manifest.csv"). Manifests
+	// are found anywhere in the SIP by case-insensitive name. Checksum
+	// verification is skipped if ManifestNames is empty (default).
+	ManifestNames []string
+}
+
 func (c Configuration) Validate() error {
 	var errs error
 
@@ -84,10 +410,167 @@ func (c Configuration) Validate() error {
 		))
 	}
 
+	// Verify that Batch.MaxConcurrency is >= 1.
+	if c.Batch.MaxConcurrency < 1 {
+		errs = errors.Join(errs, fmt.Errorf(
+			"Batch.MaxConcurrency: %d is less than the minimum value (1)",
+			c.Batch.MaxConcurrency,
+		))
+	}
+
 	if err := c.Bagit.Validate(); err != nil {
 		errs = errors.Join(errs, fmt.Errorf("Bagit.%v", err))
 	}
 
+	errs = errors.Join(errs, c.Validation.Checks.validate("Validation.Checks"))
+	errs = errors.Join(errs, validateSteps("Validation.DisabledSteps", c.Validation.DisabledSteps))
+
+	if c.Language != "" && !messages.IsSupported(c.Language) {
+		errs = errors.Join(errs, fmt.Errorf(
+			"Language: unsupported language %q, must be one of (%s)",
+			c.Language,
+			strings.Join(messages.Languages(), ", "),
+		))
+	}
+
+	switch c.Review.TimeoutDecision {
+	case "", ReviewDecisionApprove, ReviewDecisionReject:
+	default:
+		errs = errors.Join(errs, fmt.Errorf(
+			"Review.TimeoutDecision: invalid value %q, must be one of (approve, reject)",
+			c.Review.TimeoutDecision,
+		))
+	}
+
+	// Verify the activity options, in name order for stable errors.
+	for _, name := range slices.Sorted(maps.Keys(c.Activities)) {
+		errs = errors.Join(errs, c.Activities[name].validate("Activities."+name))
+	}
+
+	if err := c.ClamAV.Validate(); err != nil {
+		errs = errors.Join(errs, fmt.Errorf("ClamAV.%v", err))
+	}
+
+	for i, e := range c.Webhooks {
+		if err := e.Validate(); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("Webhooks[%d].%v", i, err))
+		}
+		if slices.IndexFunc(c.Webhooks[:i], func(o webhook.Endpoint) bool { return o.URL == e.URL }) != -1 {
+			errs = errors.Join(errs, fmt.Errorf("Webhooks[%d].URL: duplicate URL %q", i, e.URL))
+		}
+	}
+
+	// Verify the profiles, in name order for stable errors.
+	if _, ok := c.Profiles[c.DefaultProfile]; c.DefaultProfile != "" && !ok {
+		errs = errors.Join(errs, fmt.Errorf("DefaultProfile: unknown profile %q", c.DefaultProfile))
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Profiles)) {
+		errs = errors.Join(errs, c.Profiles[name].validate(name))
+	}
+
+	return errs
+}
+
+func (c ChecksConfig) validate(prefix string) error {
+	var errs error
+
+	// Verify that the validation check modes are valid.
+	for _, check := range []struct {
+		name string
+		mode CheckMode
+	}{
+		{"Checksums", c.Checksums},
+		{"Viruses", c.Viruses},
+		{"FileFormats", c.FileFormats},
+		{"Structure", c.Structure},
+		{"EmptyFiles", c.EmptyFiles},
+		{"FileNames", c.FileNames},
+		{"DeprecatedFormats", c.DeprecatedFormats},
+	} {
+		switch check.mode {
+		case "", CheckModeBlock, CheckModeWarn:
+		default:
+			errs = errors.Join(errs, fmt.Errorf(
+				"%s.%s: invalid value %q, must be one of (block, warn)",
+				prefix,
+				check.name,
+				check.mode,
+			))
+		}
+	}
+
+	return errs
+}
+
+func validateSteps(prefix string, names []string) error {
+	var errs error
+	for _, name := range names {
+		if !slices.Contains(steps, name) {
+			errs = errors.Join(errs, fmt.Errorf(
+				"%s: invalid value %q, must be one of (%s)",
+				prefix,
+				name,
+				strings.Join(steps, ", "),
+			))
+		}
+	}
+
+	return errs
+}
+
+func (c ProfileConfig) validate(name string) error {
+	var errs error
+
+	prefix := "Profiles." + name
+	if !profileNameRegex.MatchString(name) {
+		errs = errors.Join(errs, fmt.Errorf(
+			"%s: invalid name, must be lowercase letters, digits, \"-\" and \"_\"",
+			prefix,
+		))
+	}
+	if c.Bagit.ChecksumAlgorithm != "" {
+		if err := c.Bagit.Validate(); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s.Bagit.%v", prefix, err))
+		}
+	}
+	errs = errors.Join(errs, c.Checks.validate(prefix+".Checks"))
+	errs = errors.Join(errs, validateSteps(prefix+".DisabledSteps", c.DisabledSteps))
+
+	return errs
+}
+
+func (c ActivityConfig) validate(prefix string) error {
+	var errs error
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"Timeout", c.Timeout},
+		{"TimeoutPerGB", c.TimeoutPerGB},
+		{"HeartbeatTimeout", c.HeartbeatTimeout},
+		{"Retry.InitialInterval", c.Retry.InitialInterval},
+		{"Retry.MaximumInterval", c.Retry.MaximumInterval},
+	} {
+		if d.value < 0 {
+			errs = errors.Join(errs, fmt.Errorf("%s.%s: %s is less than the minimum value (0s)", prefix, d.name, d.value))
+		}
+	}
+	if c.Retry.MaxAttempts < 0 {
+		errs = errors.Join(errs, fmt.Errorf(
+			"%s.Retry.MaxAttempts: %d is less than the minimum value (0)",
+			prefix,
+			c.Retry.MaxAttempts,
+		))
+	}
+	if c.Retry.BackoffCoefficient != 0 && c.Retry.BackoffCoefficient < 1 {
+		errs = errors.Join(errs, fmt.Errorf(
+			"%s.Retry.BackoffCoefficient: %g is less than the minimum value (1)",
+			prefix,
+			c.Retry.BackoffCoefficient,
+		))
+	}
+
 	return errs
 }
 
@@ -103,7 +586,13 @@ func Read(config *Configuration, configFile string) (found bool, configFileUsed
 	v.AutomaticEnv()
 
 	// Defaults.
+	v.SetDefault("Temporal.BatchWorkflowName", "batch-preprocessing")
 	v.SetDefault("Worker.MaxConcurrentSessions", 1)
+	v.SetDefault("Batch.MaxConcurrency", 5)
+	v.SetDefault("Validation.Checks.EmptyFiles", CheckModeWarn)
+	v.SetDefault("Validation.Checks.FileNames", CheckModeWarn)
+	v.SetDefault("Validation.Checks.DeprecatedFormats", CheckModeWarn)
+	v.SetDefault("Review.TimeoutDecision", ReviewDecisionReject)
 
 	if configFile != "" {
 		// Viper will not return a viper.ConfigFileNotFoundError error when
@@ -137,6 +626,16 @@ func Read(config *Configuration, configFile string) (found bool, configFileUsed
 	return true, v.ConfigFileUsed(), nil
 }
 
+// Fingerprint returns the SHA-256 checksum of the configuration, e.g.
+// "sha256:9f86d0...", to identify the configuration a SIP was processed with.
+func (c Configuration) Fingerprint() string {
+	// Marshaling can't fail, the configuration only has plain values.
+	b, _ := json.Marshal(c)
+	sum := sha256.Sum256(b)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
 func errRequired(name string) error {
 	return fmt.Errorf("%s: missing required value", name)
 }
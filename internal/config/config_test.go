@@ -1,28 +1,75 @@
 package config_test
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/artefactual-sdps/temporal-activities/bagcreate"
+	"github.com/artefactual-sdps/temporal-activities/ffvalidate"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/fs"
 
 	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/webhook"
 )
 
 const testConfig = `# Config
 debug = true
 verbosity = 2
 sharedPath = "/home/preprocessing/shared"
+defaultProfile = "acme"
+language = "de"
 [temporal]
 address = "host:port"
 namespace = "default"
 taskQueue = "preprocessing"
 workflowName = "preprocessing"
+searchAttributes = true
 [worker]
 maxConcurrentSessions = 1
 [bagit]
 checksumAlgorithm = "md5"
+[fixity]
+manifestNames = ["checksums.md5", "manifest.csv"]
+[validation]
+checkFiles = true
+deprecatedFormats = ["fmt/39"]
+formatStatistics = true
+[validation.checks]
+checksums = "warn"
+fileNames = "block"
+[review]
+enabled = true
+timeout = "72h"
+[validationReport]
+enabled = true
+[audit]
+databasePath = "/home/preprocessing/audit.db"
+[signing]
+keyPath = "/home/preprocessing/signing.pem"
+[[webhooks]]
+url = "https://enduro.example.com/hooks/preprocessing"
+secret = "s3cr3t"
+[batch]
+maxConcurrency = 10
+reportPath = "/home/preprocessing/batches"
+[activities.bag-create]
+timeout = "48h"
+timeoutPerGB = "20m"
+[activities.bag-create.retry]
+maxAttempts = 2
+nonRetryableErrors = ["PermissionError"]
+[profiles.acme]
+disabledSteps = ["viruses"]
+[profiles.acme.bagit]
+checksumAlgorithm = "sha256"
+[profiles.acme.fileFormat]
+allowlistPath = "/home/preprocessing/acme_formats.csv"
+[profiles.acme.structure]
+requiredPaths = ["metadata/*.xml"]
+[profiles.acme.checks]
+structure = "warn"
 `
 
 func TestConfig(t *testing.T) {
@@ -48,11 +95,14 @@ func TestConfig(t *testing.T) {
 				Debug:      true,
 				Verbosity:  2,
 				SharedPath: "/home/preprocessing/shared",
+				Language:   "de",
 				Temporal: config.Temporal{
-					Address:      "host:port",
-					Namespace:    "default",
-					TaskQueue:    "preprocessing",
-					WorkflowName: "preprocessing",
+					Address:           "host:port",
+					Namespace:         "default",
+					TaskQueue:         "preprocessing",
+					WorkflowName:      "preprocessing",
+					BatchWorkflowName: "batch-preprocessing",
+					SearchAttributes:  true,
 				},
 				Worker: config.WorkerConfig{
 					MaxConcurrentSessions: 1,
@@ -60,6 +110,61 @@ func TestConfig(t *testing.T) {
 				Bagit: bagcreate.Config{
 					ChecksumAlgorithm: "md5",
 				},
+				Fixity: config.FixityConfig{
+					ManifestNames: []string{"checksums.md5", "manifest.csv"},
+				},
+				Validation: config.ValidationConfig{
+					CheckFiles:        true,
+					DeprecatedFormats: []string{"fmt/39"},
+					FormatStatistics:  true,
+					Checks: config.ChecksConfig{
+						Checksums:         config.CheckModeWarn,
+						EmptyFiles:        config.CheckModeWarn,
+						FileNames:         config.CheckModeBlock,
+						DeprecatedFormats: config.CheckModeWarn,
+					},
+				},
+				Review: config.ReviewConfig{
+					Enabled:         true,
+					Timeout:         72 * time.Hour,
+					TimeoutDecision: config.ReviewDecisionReject,
+				},
+				Batch: config.BatchConfig{
+					MaxConcurrency: 10,
+					ReportPath:     "/home/preprocessing/batches",
+				},
+				ValidationReport: config.ValidationReportConfig{
+					Enabled: true,
+				},
+				Audit: config.AuditConfig{
+					DatabasePath: "/home/preprocessing/audit.db",
+				},
+				Signing: config.SigningConfig{
+					KeyPath: "/home/preprocessing/signing.pem",
+				},
+				Webhooks: []webhook.Endpoint{
+					{URL: "https://enduro.example.com/hooks/preprocessing", Secret: "s3cr3t"},
+				},
+				Activities: map[string]config.ActivityConfig{
+					"bag-create": {
+						Timeout:      48 * time.Hour,
+						TimeoutPerGB: 20 * time.Minute,
+						Retry: config.RetryConfig{
+							MaxAttempts:        2,
+							NonRetryableErrors: []string{"PermissionError"},
+						},
+					},
+				},
+				DefaultProfile: "acme",
+				Profiles: map[string]config.ProfileConfig{
+					"acme": {
+						Bagit:         bagcreate.Config{ChecksumAlgorithm: "sha256"},
+						FileFormat:    ffvalidate.Config{AllowlistPath: "/home/preprocessing/acme_formats.csv"},
+						Structure:     config.StructureConfig{RequiredPaths: []string{"metadata/*.xml"}},
+						Checks:        config.ChecksConfig{Structure: config.CheckModeWarn},
+						DisabledSteps: []string{"viruses"},
+					},
+				},
 			},
 		},
 		{
@@ -84,6 +189,20 @@ maxConcurrentSessions = -1
 			wantFound: true,
 			wantErr:   `invalid configuration: Worker.MaxConcurrentSessions: -1 is less than the minimum value (1)`,
 		},
+		{
+			name:       "Errors when Batch.MaxConcurrency is less than 1",
+			configFile: "preprocessing.toml",
+			toml: `# Config
+sharedPath = "/home/preprocessing/shared"
+[temporal]
+taskQueue = "preprocessing"
+workflowName = "preprocessing"
+[batch]
+maxConcurrency = 0
+`,
+			wantFound: true,
+			wantErr:   `invalid configuration: Batch.MaxConcurrency: 0 is less than the minimum value (1)`,
+		},
 		{
 			name:       "Errors when bagit checksumAlgorithm is invalid",
 			configFile: "preprocessing.toml",
@@ -98,6 +217,124 @@ checksumAlgorithm = "unknown"
 			wantFound: true,
 			wantErr:   `invalid configuration: Bagit.ChecksumAlgorithm: invalid value "unknown", must be one of (md5, sha1, sha256, sha512)`,
 		},
+		{
+			name:       "Errors when clamav address is invalid",
+			configFile: "preprocessing.toml",
+			toml: `# Config
+sharedPath = "/home/preprocessing/shared"
+[temporal]
+taskQueue = "preprocessing"
+workflowName = "preprocessing"
+[clamav]
+address = "localhost:3310"
+`,
+			wantFound: true,
+			wantErr:   `invalid configuration: ClamAV.Address: invalid address "localhost:3310": scheme must be one of (tcp, unix)`,
+		},
+		{
+			name:       "Errors when a validation check mode is invalid",
+			configFile: "preprocessing.toml",
+			toml: `# Config
+sharedPath = "/home/preprocessing/shared"
+[temporal]
+taskQueue = "preprocessing"
+workflowName = "preprocessing"
+[validation.checks]
+viruses = "ignore"
+`,
+			wantFound: true,
+			wantErr:   `invalid configuration: Validation.Checks.Viruses: invalid value "ignore", must be one of (block, warn)`,
+		},
+		{
+			name:       "Errors when the review timeout decision is invalid",
+			configFile: "preprocessing.toml",
+			toml: `# Config
+sharedPath = "/home/preprocessing/shared"
+[temporal]
+taskQueue = "preprocessing"
+workflowName = "preprocessing"
+[review]
+timeoutDecision = "ignore"
+`,
+			wantFound: true,
+			wantErr:   `invalid configuration: Review.TimeoutDecision: invalid value "ignore", must be one of (approve, reject)`,
+		},
+		{
+			name:       "Errors when the language is not supported",
+			configFile: "preprocessing.toml",
+			toml: `# Config
+sharedPath = "/home/preprocessing/shared"
+language = "es"
+[temporal]
+taskQueue = "preprocessing"
+workflowName = "preprocessing"
+`,
+			wantFound: true,
+			wantErr:   `invalid configuration: Language: unsupported language "es", must be one of (de, en, fr)`,
+		},
+		{
+			name:       "Errors when webhooks are invalid",
+			configFile: "preprocessing.toml",
+			toml: `# Config
+sharedPath = "/home/preprocessing/shared"
+[temporal]
+taskQueue = "preprocessing"
+workflowName = "preprocessing"
+[[webhooks]]
+url = "ftp://enduro.example.com/hooks"
+secret = "s3cr3t"
+[[webhooks]]
+url = "https://enduro.example.com/hooks"
+[[webhooks]]
+url = "https://enduro.example.com/hooks"
+secret = "s3cr3t"
+`,
+			wantFound: true,
+			wantErr: `invalid configuration: Webhooks[0].URL: invalid value "ftp://enduro.example.com/hooks", must be an http or https URL
+Webhooks[1].Secret: missing required value
+Webhooks[2].URL: duplicate URL "https://enduro.example.com/hooks"`,
+		},
+		{
+			name:       "Errors when activity options are invalid",
+			configFile: "preprocessing.toml",
+			toml: `# Config
+sharedPath = "/home/preprocessing/shared"
+[temporal]
+taskQueue = "preprocessing"
+workflowName = "preprocessing"
+[activities.bag-create]
+timeout = "-1h"
+[activities.add-premis-event.retry]
+maxAttempts = -1
+backoffCoefficient = 0.5
+`,
+			wantFound: true,
+			wantErr: `invalid configuration: Activities.add-premis-event.Retry.MaxAttempts: -1 is less than the minimum value (0)
+Activities.add-premis-event.Retry.BackoffCoefficient: 0.5 is less than the minimum value (1)
+Activities.bag-create.Timeout: -1h0m0s is less than the minimum value (0s)`,
+		},
+		{
+			name:       "Errors when profiles are invalid",
+			configFile: "preprocessing.toml",
+			toml: `# Config
+sharedPath = "/home/preprocessing/shared"
+defaultProfile = "missing"
+[temporal]
+taskQueue = "preprocessing"
+workflowName = "preprocessing"
+[profiles.acme]
+disabledSteps = ["review"]
+[profiles.acme.bagit]
+checksumAlgorithm = "crc32"
+[profiles.acme.checks]
+structure = "ignore"
+`,
+			wantFound: true,
+			wantErr: `invalid configuration: DefaultProfile: unknown profile "missing"
+Profiles.acme.Bagit.ChecksumAlgorithm: invalid value "crc32", must be one of (md5, sha1, sha256, sha512)
+Profiles.acme.Checks.Structure: invalid value "ignore", must be one of (block, warn)
+Profiles.acme.DisabledSteps: invalid value "review", must be one of (checksums, viruses, structure, files, fileFormats)`,
+		},
 		{
 			name:       "Errors when TOML is invalid",
 			configFile: "preprocessing.toml",
@@ -147,3 +384,61 @@ checksumAlgorithm = "unknown"
 		})
 	}
 }
+
+func TestWithProfile(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Configuration{
+		Bagit:      bagcreate.Config{ChecksumAlgorithm: "md5"},
+		FileFormat: ffvalidate.Config{AllowlistPath: "/home/preprocessing/formats.csv"},
+		Structure:  config.StructureConfig{ForbiddenPaths: []string{"Thumbs.db"}},
+		Validation: config.ValidationConfig{
+			Checks: config.ChecksConfig{EmptyFiles: config.CheckModeWarn, FileNames: config.CheckModeWarn},
+		},
+		Profiles: map[string]config.ProfileConfig{
+			"acme": {
+				FileFormat:    ffvalidate.Config{AllowlistPath: "/home/preprocessing/acme_formats.csv"},
+				Structure:     config.StructureConfig{RequiredPaths: []string{"metadata/*.xml"}},
+				Checks:        config.ChecksConfig{EmptyFiles: config.CheckModeBlock},
+				DisabledSteps: []string{"viruses"},
+			},
+		},
+	}
+
+	got, err := cfg.WithProfile("acme")
+	assert.NilError(t, err)
+
+	want := cfg
+	want.FileFormat = ffvalidate.Config{AllowlistPath: "/home/preprocessing/acme_formats.csv"}
+	want.Structure = config.StructureConfig{
+		RequiredPaths:  []string{"metadata/*.xml"},
+		ForbiddenPaths: []string{"Thumbs.db"},
+	}
+	want.Validation = config.ValidationConfig{
+		Checks:        config.ChecksConfig{EmptyFiles: config.CheckModeBlock, FileNames: config.CheckModeWarn},
+		DisabledSteps: []string{"viruses"},
+	}
+	assert.DeepEqual(t, got, want)
+
+	_, err = cfg.WithProfile("other")
+	assert.Error(t, err, `unknown profile: "other"`)
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Configuration{SharedPath: "/home/preprocessing/shared"}
+	fingerprint := cfg.Fingerprint()
+	assert.Assert(t, strings.HasPrefix(fingerprint, "sha256:"))
+	assert.Equal(t, len(fingerprint), len("sha256:")+64)
+	assert.Equal(t, cfg.Fingerprint(), fingerprint)
+
+	cfg.Bagit.ChecksumAlgorithm = "md5"
+	assert.Assert(t, cfg.Fingerprint() != fingerprint)
+
+	// Webhook secrets are left out of the fingerprint.
+	cfg.Webhooks = []webhook.Endpoint{{URL: "https://enduro.example.com/hooks", Secret: "a"}}
+	fingerprint = cfg.Fingerprint()
+	cfg.Webhooks[0].Secret = "b"
+	assert.Equal(t, cfg.Fingerprint(), fingerprint)
+}
@@ -5,5 +5,8 @@ package enums
 // success
 // system failure
 // validation failure
+// skipped
+// warning
+// cancelled
 // ).
 type EventOutcome string
@@ -16,6 +16,9 @@ const (
 	EventOutcomeSuccess           EventOutcome = "success"
 	EventOutcomeSystemFailure     EventOutcome = "system failure"
 	EventOutcomeValidationFailure EventOutcome = "validation failure"
+	EventOutcomeSkipped           EventOutcome = "skipped"
+	EventOutcomeWarning           EventOutcome = "warning"
+	EventOutcomeCancelled         EventOutcome = "cancelled"
 )
 
 var ErrInvalidEventOutcome = fmt.Errorf("not a valid EventOutcome, try [%s]", strings.Join(_EventOutcomeNames, ", "))
@@ -25,6 +28,9 @@ var _EventOutcomeNames = []string{
 	string(EventOutcomeSuccess),
 	string(EventOutcomeSystemFailure),
 	string(EventOutcomeValidationFailure),
+	string(EventOutcomeSkipped),
+	string(EventOutcomeWarning),
+	string(EventOutcomeCancelled),
 }
 
 // EventOutcomeNames returns a list of possible string values of EventOutcome.
@@ -51,6 +57,9 @@ var _EventOutcomeValue = map[string]EventOutcome{
 	"success":            EventOutcomeSuccess,
 	"system failure":     EventOutcomeSystemFailure,
 	"validation failure": EventOutcomeValidationFailure,
+	"skipped":            EventOutcomeSkipped,
+	"warning":            EventOutcomeWarning,
+	"cancelled":          EventOutcomeCancelled,
 }
 
 // ParseEventOutcome attempts to convert a string to a EventOutcome.
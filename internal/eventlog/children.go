@@ -0,0 +1,111 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+)
+
+// Children are the child events of an event. They are encoded to JSON as
+// arrays of values instead of objects, so events with thousands of children
+// don't bloat the Temporal payloads:
+//
+//	[name, outcome, started at, duration, message, failures, children, code,
+//	 message code, params]
+//
+// with the start time in Unix milliseconds, the duration in milliseconds (null
+// if the child hasn't completed) and the trailing empty values omitted, e.g.:
+//
+//	["file.txt", "success", 1717685292000, 12]
+//
+// Child event times have a millisecond precision.
+type Children []*Event
+
+func (c Children) MarshalJSON() ([]byte, error) {
+	if c == nil {
+		return []byte("null"), nil
+	}
+
+	rows := make([][]any, len(c))
+	for i, e := range c {
+		var started int64
+		if !e.StartedAt.IsZero() {
+			started = e.StartedAt.UnixMilli()
+		}
+		var duration *int64
+		if !e.CompletedAt.IsZero() {
+			d := e.CompletedAt.Sub(e.StartedAt).Milliseconds()
+			duration = &d
+		}
+
+		row := []any{
+			e.Name, e.Outcome, started, duration, e.Message, e.Failures, e.Children, e.Code, e.MessageCode, e.Params,
+		}
+		n := len(row)
+		for n > 4 && isEmpty(row[n-1]) {
+			n--
+		}
+		rows[i] = row[:n]
+	}
+
+	return json.Marshal(rows)
+}
+
+func (c *Children) UnmarshalJSON(b []byte) error {
+	var rows [][]json.RawMessage
+	if err := json.Unmarshal(b, &rows); err != nil {
+		return err
+	}
+	if rows == nil {
+		*c = nil
+		return nil
+	}
+
+	children := make(Children, len(rows))
+	for i, row := range rows {
+		if len(row) < 4 || len(row) > 10 {
+			return fmt.Errorf("invalid child event: %d values", len(row))
+		}
+
+		e := &Event{}
+		var started int64
+		var duration *int64
+		values := []any{
+			&e.Name, &e.Outcome, &started, &duration, &e.Message, &e.Failures, &e.Children, &e.Code, &e.MessageCode,
+			&e.Params,
+		}
+		for j, v := range row {
+			if err := json.Unmarshal(v, values[j]); err != nil {
+				return fmt.Errorf("invalid child event: %v", err)
+			}
+		}
+		if started != 0 {
+			e.StartedAt = time.UnixMilli(started).UTC()
+		}
+		if duration != nil {
+			e.CompletedAt = e.StartedAt.Add(time.Duration(*duration) * time.Millisecond)
+		}
+		children[i] = e
+	}
+	*c = children
+
+	return nil
+}
+
+// isEmpty reports whether v is an empty optional value of a child event row.
+func isEmpty(v any) bool {
+	switch v := v.(type) {
+	case string:
+		return v == ""
+	case []Failure:
+		return len(v) == 0
+	case Children:
+		return len(v) == 0
+	case messages.Params:
+		return len(v) == 0
+	}
+
+	return false
+}
@@ -1,18 +1,111 @@
 package eventlog
 
 import (
+	"cmp"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/artefactual-sdps/preprocessing-demo/internal/enums"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
 )
 
 type Event struct {
-	Name        string
+	// Code is a stable identifier of the kind of event, e.g.
+	// "validate-file-formats", and Name its name in the SIP language.
+	Code string `json:",omitempty"`
+	Name string
+
+	// MessageCode is a stable identifier of the event message, e.g.
+	// "content-error", and Params the values of its parameters. Message is the
+	// message rendered in the SIP language, see package messages.
+	MessageCode string          `json:",omitempty"`
+	Params      messages.Params `json:",omitempty"`
 	Message     string
+
 	Outcome     enums.EventOutcome
 	StartedAt   time.Time
 	CompletedAt time.Time
+
+	// Failures lists the validation failures of the event, if any. Message
+	// includes the failure messages for backward compatibility.
+	Failures []Failure
+
+	// Children lists the child events, e.g. one per file or sub-check of a
+	// preprocessing step, if any.
+	Children Children `json:",omitempty"`
+}
+
+// Failure is a validation failure, usually of a single file.
+type Failure struct {
+	// Path is the path of the file relative to the SIP.
+	Path string
+
+	// Check is the name of the validation check, e.g. "file format".
+	Check string
+
+	// Code is a stable identifier of the kind of failure, e.g.
+	// "format-not-allowed".
+	Code string
+
+	// MessageCode identifies the message of the failure in the message
+	// catalog if it isn't Code, e.g. "checksum-manifest-invalid-csv".
+	MessageCode string `json:",omitempty"`
+
+	// Params are the values of the failure message parameters, e.g. "path".
+	Params messages.Params `json:",omitempty"`
+
+	// Message is a human readable description of the failure.
+	Message string
+
+	// PUID is the PRONOM identifier of the file format (optional).
+	PUID string
+}
+
+// Localize returns the failure with its message rendered in lang from the
+// message catalog. Failures without params are returned unchanged.
+func (f Failure) Localize(lang string) Failure {
+	if f.Params == nil {
+		return f
+	}
+	f.Message = messages.Failure(lang, cmp.Or(f.MessageCode, f.Code), f.Params)
+
+	return f
+}
+
+// Log is the machine-readable log of the preprocessing of a SIP, stored in the
+// SIP so the record of its preprocessing travels with the package.
+type Log struct {
+	// WorkflowID and RunID identify the preprocessing workflow execution.
+	WorkflowID string
+	RunID      string
+
+	// RelativePath is the path of the SIP relative to the shared path.
+	RelativePath string
+
+	// SIPID is the UUID of the SIP in Enduro, if known.
+	SIPID string `json:",omitempty"`
+
+	// Language is the language of the event names and messages.
+	Language string `json:",omitempty"`
+
+	// Profile is the name of the processing profile of the SIP, if any.
+	Profile string `json:",omitempty"`
+
+	// WorkerVersion is the version of the preprocessing worker.
+	WorkerVersion string
+
+	// ConfigFingerprint identifies the configuration the SIP was processed
+	// with, including the settings of its profile, see
+	// config.Configuration.Fingerprint.
+	ConfigFingerprint string
+
+	// CreatedAt is the time the log was created.
+	CreatedAt time.Time
+
+	// Events lists the preprocessing steps, in execution order.
+	Events []*Event
 }
 
 func NewEvent(t time.Time, name string) *Event {
@@ -31,10 +124,86 @@ func (e *Event) Complete(t time.Time, outcome enums.EventOutcome, msg string, a
 	return e
 }
 
+// Fail completes the event with a validation failure outcome, the given
+// failures and a message followed by the failure messages, one per line.
+func (e *Event) Fail(t time.Time, failures []Failure, msg string, a ...any) *Event {
+	return e.completeWithFailures(t, enums.EventOutcomeValidationFailure, failures, msg, a...)
+}
+
+// Warn completes the event with a warning outcome, the given non-blocking
+// failures and a message followed by the failure messages, one per line.
+func (e *Event) Warn(t time.Time, failures []Failure, msg string, a ...any) *Event {
+	return e.completeWithFailures(t, enums.EventOutcomeWarning, failures, msg, a...)
+}
+
+func (e *Event) completeWithFailures(
+	t time.Time,
+	outcome enums.EventOutcome,
+	failures []Failure,
+	msg string,
+	a ...any,
+) *Event {
+	lines := make([]string, 0, len(failures)+1)
+	lines = append(lines, fmt.Sprintf(msg, a...)+":")
+	for _, f := range failures {
+		lines = append(lines, f.Message)
+	}
+
+	e.Complete(t, outcome, "%s", strings.Join(lines, "\n"))
+	e.Failures = failures
+
+	return e
+}
+
 func (e *Event) Succeed(t time.Time, msg string, a ...any) *Event {
 	return e.Complete(t, enums.EventOutcomeSuccess, msg, a...)
 }
 
+func (e *Event) Skip(t time.Time, msg string, a ...any) *Event {
+	return e.Complete(t, enums.EventOutcomeSkipped, msg, a...)
+}
+
+// NewChild adds a child event to the event and returns it.
+func (e *Event) NewChild(t time.Time, name string) *Event {
+	child := NewEvent(t, name)
+	e.Children = append(e.Children, child)
+
+	return child
+}
+
+// outcomeSeverity lists the event outcomes from the most to the least severe.
+var outcomeSeverity = []enums.EventOutcome{
+	enums.EventOutcomeSystemFailure,
+	enums.EventOutcomeValidationFailure,
+	enums.EventOutcomeCancelled,
+	enums.EventOutcomeUnspecified,
+	enums.EventOutcomeWarning,
+	enums.EventOutcomeSuccess,
+	enums.EventOutcomeSkipped,
+}
+
+// AggregateOutcome returns the outcome of a parent event from the outcomes of
+// its children: the most severe outcome of the children, from system failure,
+// validation failure, cancelled, unspecified (a child hasn't completed),
+// warning, success to skipped. A parent whose children are all skipped is
+// skipped, a parent without children succeeds.
+func AggregateOutcome(children []*Event) enums.EventOutcome {
+	if len(children) == 0 {
+		return enums.EventOutcomeSuccess
+	}
+
+	i := len(outcomeSeverity) - 1
+	for _, c := range children {
+		i = min(i, slices.Index(outcomeSeverity, c.Outcome))
+	}
+	if i < 0 {
+		// Unknown outcomes are not expected, consider them failures.
+		return enums.EventOutcomeSystemFailure
+	}
+
+	return outcomeSeverity[i]
+}
+
 func (e *Event) IsSuccess() bool {
 	return e.Outcome == enums.EventOutcomeSuccess
 }
@@ -1,6 +1,7 @@
 package eventlog_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/artefactual-sdps/preprocessing-demo/internal/enums"
 	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
 )
 
 func TestEvent(t *testing.T) {
@@ -63,4 +65,180 @@ func TestEvent(t *testing.T) {
 		})
 		assert.Equal(t, event.IsSuccess(), false)
 	})
+
+	t.Run("Event fails with validation failures", func(t *testing.T) {
+		t.Parallel()
+
+		failures := []eventlog.Failure{
+			{
+				Path:    "content/file1.png",
+				Check:   "file format",
+				Code:    "format-not-allowed",
+				Message: `file format "fmt/11" not allowed: "content/file1.png"`,
+				PUID:    "fmt/11",
+			},
+			{
+				Path:    "content/file2.txt",
+				Check:   "checksum",
+				Code:    "checksum-mismatch",
+				Message: `md5 checksum mismatch: "content/file2.txt"`,
+			},
+		}
+
+		event := eventlog.NewEvent(started, "test event")
+		event.Fail(completed, failures, "Content error: %s has failed", "validation")
+		assert.DeepEqual(t, event, &eventlog.Event{
+			Name: "test event",
+			Message: `Content error: validation has failed:
+file format "fmt/11" not allowed: "content/file1.png"
+md5 checksum mismatch: "content/file2.txt"`,
+			Outcome:     enums.EventOutcomeValidationFailure,
+			StartedAt:   started,
+			CompletedAt: completed,
+			Failures:    failures,
+		})
+		assert.Equal(t, event.IsSuccess(), false)
+	})
+
+	t.Run("Event completes with warnings", func(t *testing.T) {
+		t.Parallel()
+
+		failures := []eventlog.Failure{
+			{
+				Path:    "content/empty.txt",
+				Check:   "empty file",
+				Code:    "empty-file",
+				Message: `empty file: "content/empty.txt"`,
+			},
+		}
+
+		event := eventlog.NewEvent(started, "test event")
+		event.Warn(completed, failures, "Warning: %s", "empty files found")
+		assert.DeepEqual(t, event, &eventlog.Event{
+			Name: "test event",
+			Message: `Warning: empty files found:
+empty file: "content/empty.txt"`,
+			Outcome:     enums.EventOutcomeWarning,
+			StartedAt:   started,
+			CompletedAt: completed,
+			Failures:    failures,
+		})
+		assert.Equal(t, event.IsSuccess(), false)
+	})
+
+	t.Run("Event is skipped", func(t *testing.T) {
+		t.Parallel()
+
+		event := eventlog.NewEvent(started, "test event")
+		event.Skip(completed, "Dry run: %s would have been created", "premis.xml")
+		assert.DeepEqual(t, event, &eventlog.Event{
+			Name:        "test event",
+			Message:     "Dry run: premis.xml would have been created",
+			Outcome:     enums.EventOutcomeSkipped,
+			StartedAt:   started,
+			CompletedAt: completed,
+		})
+		assert.Equal(t, event.IsSuccess(), false)
+	})
+}
+
+func TestAggregateOutcome(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		outcomes []enums.EventOutcome
+		want     enums.EventOutcome
+	}{
+		{
+			name: "Succeeds without children",
+			want: enums.EventOutcomeSuccess,
+		},
+		{
+			name:     "Succeeds when children succeed or are skipped",
+			outcomes: []enums.EventOutcome{enums.EventOutcomeSuccess, enums.EventOutcomeSkipped},
+			want:     enums.EventOutcomeSuccess,
+		},
+		{
+			name:     "Is skipped when all the children are skipped",
+			outcomes: []enums.EventOutcome{enums.EventOutcomeSkipped, enums.EventOutcomeSkipped},
+			want:     enums.EventOutcomeSkipped,
+		},
+		{
+			name:     "Warns when a child warns",
+			outcomes: []enums.EventOutcome{enums.EventOutcomeSuccess, enums.EventOutcomeWarning},
+			want:     enums.EventOutcomeWarning,
+		},
+		{
+			name:     "Is unspecified when a child hasn't completed",
+			outcomes: []enums.EventOutcome{enums.EventOutcomeWarning, enums.EventOutcomeUnspecified},
+			want:     enums.EventOutcomeUnspecified,
+		},
+		{
+			name: "Fails with the most severe failure",
+			outcomes: []enums.EventOutcome{
+				enums.EventOutcomeValidationFailure,
+				enums.EventOutcomeSystemFailure,
+				enums.EventOutcomeCancelled,
+			},
+			want: enums.EventOutcomeSystemFailure,
+		},
+		{
+			name:     "Fails with a system failure when an outcome is unknown",
+			outcomes: []enums.EventOutcome{enums.EventOutcomeSuccess, "unknown"},
+			want:     enums.EventOutcomeSystemFailure,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			event := eventlog.NewEvent(time.Now(), "parent")
+			for i, outcome := range tc.outcomes {
+				event.NewChild(time.Now(), fmt.Sprintf("child %d", i)).Outcome = outcome
+			}
+			assert.Equal(t, eventlog.AggregateOutcome(event.Children), tc.want)
+		})
+	}
+}
+
+func TestChildrenJSON(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2024, 6, 6, 14, 48, 12, 0, time.UTC)
+	event := eventlog.NewEvent(started, "Validate SIP file formats")
+	event.NewChild(started, "file1.txt").Succeed(started.Add(12*time.Millisecond), "")
+	event.NewChild(started, "file2.png").Fail(
+		started.Add(time.Second),
+		[]eventlog.Failure{
+			{Path: "file2.png", Check: "file format", Code: "format-not-allowed", Message: "not allowed"},
+		},
+		"Content error",
+	)
+	sub := event.NewChild(started, "content")
+	skipped := sub.NewChild(started, "file3.txt").Skip(started, "Skipped")
+	skipped.Code = "check-file"
+	skipped.MessageCode = "file-skipped"
+	skipped.Params = messages.Params{"path": "content/file3.txt"}
+	event.NewChild(started, "file4.txt")
+	event.Complete(started.Add(2*time.Second), eventlog.AggregateOutcome(event.Children), "Done")
+
+	b, err := json.Marshal(event)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), `{"Name":"Validate SIP file formats","Message":"Done","Outcome":"validation failure",`+
+		`"StartedAt":"2024-06-06T14:48:12Z","CompletedAt":"2024-06-06T14:48:14Z","Failures":null,"Children":[`+
+		`["file1.txt","success",1717685292000,12],`+
+		`["file2.png","validation failure",1717685292000,1000,"Content error:\nnot allowed",`+
+		`[{"Path":"file2.png","Check":"file format","Code":"format-not-allowed","Message":"not allowed","PUID":""}]],`+
+		`["content","unspecified",1717685292000,null,"",null,[`+
+		`["file3.txt","skipped",1717685292000,0,"Skipped",null,null,"check-file","file-skipped",`+
+		`{"path":"content/file3.txt"}]]],`+
+		`["file4.txt","unspecified",1717685292000,null]]}`)
+
+	var got eventlog.Event
+	err = json.Unmarshal(b, &got)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &got, event)
+
+	err = json.Unmarshal([]byte(`{"Children":[["file1.txt","success"]]}`), &got)
+	assert.Error(t, err, "invalid child event: 2 values")
 }
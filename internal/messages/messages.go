@@ -0,0 +1,159 @@
+// Package messages renders the names and messages of the preprocessing events
+// and the validation failure messages from a message catalog, so they can be
+// shown in the language of the SIP producer.
+//
+// The catalog has a JSON file per language, embedded in the binary:
+//
+//	catalog/<language>.json
+//
+// with the texts of the events, event messages, failures, validation report
+// labels and failure remediations by stable code. Texts have parameters
+// written as "{name}", or "{name:q}" for a Go quoted value. A "{name:m}"
+// parameter is the code of another event message, rendered with the same
+// parameters, e.g. "Content error: {reason:m}".
+package messages
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is the language of the messages when none is selected, and
+// the fallback of the texts missing from a catalog.
+const DefaultLanguage = "en"
+
+// Params are the values of the parameters of a message, by parameter name.
+type Params map[string]string
+
+// Catalog are the texts of a language, by code.
+type Catalog struct {
+	// Events are the event names.
+	Events map[string]string
+
+	// Messages are the event messages.
+	Messages map[string]string
+
+	// Failures are the validation failure messages.
+	Failures map[string]string
+
+	// Labels are the texts of the validation report, e.g. the table headers.
+	Labels map[string]string
+
+	// Remediations are the suggested remediations of the validation failures,
+	// by failure code.
+	Remediations map[string]string
+}
+
+//go:embed catalog/*.json
+var catalogFS embed.FS
+
+// catalogs are the embedded catalogs, by language.
+var catalogs = mustLoad()
+
+func mustLoad() map[string]Catalog {
+	files, err := catalogFS.ReadDir("catalog")
+	if err != nil {
+		panic(err)
+	}
+
+	catalogs := make(map[string]Catalog, len(files))
+	for _, f := range files {
+		b, err := catalogFS.ReadFile(path.Join("catalog", f.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var c Catalog
+		if err := json.Unmarshal(b, &c); err != nil {
+			panic(fmt.Sprintf("messages: invalid catalog %q: %v", f.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(f.Name(), ".json")] = c
+	}
+
+	return catalogs
+}
+
+// Languages returns the languages of the catalogs, in alphabetical order.
+func Languages() []string {
+	return slices.Sorted(maps.Keys(catalogs))
+}
+
+// IsSupported reports whether there is a catalog for lang.
+func IsSupported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Lookup returns the catalog of lang.
+func Lookup(lang string) (Catalog, bool) {
+	c, ok := catalogs[lang]
+	return c, ok
+}
+
+// EventName returns the name of the event with code in lang.
+func EventName(lang, code string) string {
+	return render(lang, func(c Catalog) map[string]string { return c.Events }, code, nil)
+}
+
+// Message returns the event message with code in lang.
+func Message(lang, code string, params Params) string {
+	return render(lang, func(c Catalog) map[string]string { return c.Messages }, code, params)
+}
+
+// Failure returns the validation failure message with code in lang.
+func Failure(lang, code string, params Params) string {
+	return render(lang, func(c Catalog) map[string]string { return c.Failures }, code, params)
+}
+
+// Label returns the validation report text with code in lang.
+func Label(lang, code string) string {
+	return render(lang, func(c Catalog) map[string]string { return c.Labels }, code, nil)
+}
+
+// Remediation returns the suggested remediation of the failures with code in
+// lang, or an empty string if there is none.
+func Remediation(lang, code string) string {
+	if _, ok := catalogs[DefaultLanguage].Remediations[code]; !ok {
+		return ""
+	}
+
+	return render(lang, func(c Catalog) map[string]string { return c.Remediations }, code, nil)
+}
+
+// paramRegexp matches the parameters of a text.
+var paramRegexp = regexp.MustCompile(`\{([a-zA-Z]+)(?::([qm]))?\}`)
+
+// render returns the text with code of the texts section of the lang catalog,
+// falling back to the default language and then to the code itself. Unknown
+// parameters are left as is.
+func render(lang string, texts func(Catalog) map[string]string, code string, params Params) string {
+	text, ok := texts(catalogs[lang])[code]
+	if !ok {
+		text, ok = texts(catalogs[DefaultLanguage])[code]
+	}
+	if !ok {
+		return code
+	}
+
+	return paramRegexp.ReplaceAllStringFunc(text, func(s string) string {
+		m := paramRegexp.FindStringSubmatch(s)
+		v, ok := params[m[1]]
+		if !ok {
+			return s
+		}
+		switch m[2] {
+		case "q":
+			return strconv.Quote(v)
+		case "m":
+			return Message(lang, v, params)
+		}
+
+		return v
+	})
+}
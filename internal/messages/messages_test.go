@@ -0,0 +1,122 @@
+package messages_test
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+)
+
+func TestCatalogs(t *testing.T) {
+	t.Parallel()
+
+	assert.DeepEqual(t, messages.Languages(), []string{"de", "en", "fr"})
+
+	params := regexp.MustCompile(`\{[a-zA-Z]+(?::[qm])?\}`)
+	en, _ := messages.Lookup(messages.DefaultLanguage)
+	for _, lang := range messages.Languages() {
+		c, ok := messages.Lookup(lang)
+		assert.Assert(t, ok)
+
+		for _, section := range []struct {
+			name    string
+			texts   map[string]string
+			enTexts map[string]string
+		}{
+			{"Events", c.Events, en.Events},
+			{"Messages", c.Messages, en.Messages},
+			{"Failures", c.Failures, en.Failures},
+			{"Labels", c.Labels, en.Labels},
+			{"Remediations", c.Remediations, en.Remediations},
+		} {
+			assert.Assert(t, is.DeepEqual(
+				slices.Sorted(maps.Keys(section.texts)),
+				slices.Sorted(maps.Keys(section.enTexts)),
+			), "%s %s", lang, section.name)
+			for code, text := range section.texts {
+				got := params.FindAllString(text, -1)
+				want := params.FindAllString(section.enTexts[code], -1)
+				slices.Sort(got)
+				slices.Sort(want)
+				assert.Assert(t, is.DeepEqual(got, want), "%s %s %s", lang, section.name, code)
+			}
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		render func() string
+		want   string
+	}{
+		{
+			name:   "Renders an event name",
+			render: func() string { return messages.EventName("de", "bag-sip") },
+			want:   "SIP als Bag verpacken",
+		},
+		{
+			name: "Renders a message with params",
+			render: func() string {
+				return messages.Message("fr", "viruses-not-found", messages.Params{"files": "3"})
+			},
+			want: "Aucun virus trouvé dans 3 fichiers",
+		},
+		{
+			name: "Renders a message with a nested message",
+			render: func() string {
+				return messages.Message("en", "content-error", messages.Params{
+					"reason":   "sip-rejected-comment",
+					"reviewer": "alice",
+					"comment":  "incomplete",
+				})
+			},
+			want: "Content error: SIP rejected by alice: incomplete",
+		},
+		{
+			name: "Renders a failure with quoted params",
+			render: func() string {
+				return messages.Failure("de", "empty-file", messages.Params{"path": `a "b".txt`})
+			},
+			want: `Leere Datei: "a \"b\".txt"`,
+		},
+		{
+			name:   "Renders a remediation",
+			render: func() string { return messages.Remediation("fr", "forbidden-path") },
+			want:   "Retirez le fichier du SIP.",
+		},
+		{
+			name:   "Renders no remediation for an unknown failure",
+			render: func() string { return messages.Remediation("en", "unknown") },
+			want:   "",
+		},
+		{
+			name:   "Falls back to the default language",
+			render: func() string { return messages.EventName("es", "bag-sip") },
+			want:   "Bag SIP",
+		},
+		{
+			name:   "Falls back to the code",
+			render: func() string { return messages.Message("en", "unknown", nil) },
+			want:   "unknown",
+		},
+		{
+			name:   "Leaves missing params as is",
+			render: func() string { return messages.Failure("en", "empty-file", messages.Params{}) },
+			want:   "empty file: {path:q}",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.render(), tc.want)
+		})
+	}
+}
@@ -29,6 +29,18 @@ type Object struct {
 	EventIdentifiers []ObjectEventIdentifier
 }
 
+// IntellectualEntity is the SIP as a whole, identified by one or more
+// identifiers (e.g. its UUID and accession number).
+type IntellectualEntity struct {
+	Identifiers  []ObjectIdentifier
+	OriginalName string
+}
+
+type ObjectIdentifier struct {
+	IdType  string
+	IdValue string
+}
+
 type EventSummary struct {
 	IdType        string
 	IdValue       string
@@ -128,6 +140,22 @@ func AppendObjectXML(doc *etree.Document, object Object) error {
 	return nil
 }
 
+// AppendIntellectualEntityXML adds an intellectual entity object to the
+// document, unless an object with the same first identifier exists.
+func AppendIntellectualEntityXML(doc *etree.Document, entity IntellectualEntity) error {
+	el, err := getRoot(doc)
+	if err != nil {
+		return err
+	}
+	if len(entity.Identifiers) == 0 {
+		return errors.New("intellectual entity has no identifiers")
+	}
+
+	addIntellectualEntityElementIfNeeded(el, entity)
+
+	return nil
+}
+
 func AppendEventXMLForEachObject(doc *etree.Document, eventSummary EventSummary, agent Agent) error {
 	PREMISEl, err := getRoot(doc)
 	if err != nil {
@@ -170,6 +198,26 @@ func LinkEventToObject(objectEl *etree.Element, eventFull Event) {
 	linkEventIdValueEl.CreateText(eventFull.Summary.IdValue)
 }
 
+// FileOriginalNames returns the original names of the file objects in doc.
+func FileOriginalNames(doc *etree.Document) (map[string]bool, error) {
+	el, err := getRoot(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for _, objectEl := range el.SelectElements("premis:object") {
+		if objectEl.SelectAttrValue("xsi:type", "") != "premis:file" {
+			continue
+		}
+		if nameEl := objectEl.SelectElement("premis:originalName"); nameEl != nil {
+			names[nameEl.Text()] = true
+		}
+	}
+
+	return names, nil
+}
+
 func FilesWithinDirectory(contentPath string) ([]string, error) {
 	var subpaths []string
 
@@ -252,6 +300,35 @@ func addObjectElementIfNeeded(PREMISEl *etree.Element, object Object) {
 	originalNameEl.CreateText(object.OriginalName)
 }
 
+func addIntellectualEntityElementIfNeeded(PREMISEl *etree.Element, entity IntellectualEntity) {
+	if checkForDuplicateElementData(PREMISEl, "object", map[string]string{
+		"premis:objectIdentifier/premis:objectIdentifierType":  entity.Identifiers[0].IdType,
+		"premis:objectIdentifier/premis:objectIdentifierValue": entity.Identifiers[0].IdValue,
+	}) {
+		return
+	}
+
+	objectEl := PREMISEl.CreateElement("premis:object")
+	objectEl.CreateAttr("xsi:type", "premis:intellectualEntity")
+
+	// Add object identifier elements.
+	for _, id := range entity.Identifiers {
+		objectIdEl := objectEl.CreateElement("premis:objectIdentifier")
+
+		objectIdentifierTypeEl := objectIdEl.CreateElement("premis:objectIdentifierType")
+		objectIdentifierTypeEl.CreateText(id.IdType)
+
+		objectIdentifierValueEl := objectIdEl.CreateElement("premis:objectIdentifierValue")
+		objectIdentifierValueEl.CreateText(id.IdValue)
+	}
+
+	// Add original name element.
+	if entity.OriginalName != "" {
+		originalNameEl := objectEl.CreateElement("premis:originalName")
+		originalNameEl.CreateText(entity.OriginalName)
+	}
+}
+
 func addEventElement(PREMISEl *etree.Element, event Event) {
 	eventEl := PREMISEl.CreateElement("premis:event")
 
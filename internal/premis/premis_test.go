@@ -85,6 +85,22 @@ const premisAgentAddContent = `<?xml version="1.0" encoding="UTF-8"?>
 </premis:premis>
 `
 
+const premisIntellectualEntityAddContent = `<?xml version="1.0" encoding="UTF-8"?>
+<premis:premis xmlns:premis="http://www.loc.gov/premis/v3" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.loc.gov/premis/v3 https://www.loc.gov/standards/premis/premis.xsd" version="3.0">
+  <premis:object xsi:type="premis:intellectualEntity">
+    <premis:objectIdentifier>
+      <premis:objectIdentifierType>UUID</premis:objectIdentifierType>
+      <premis:objectIdentifierValue>6f3bb3e0-3a4e-4c8f-9d3e-5e7b1c1f7e6a</premis:objectIdentifierValue>
+    </premis:objectIdentifier>
+    <premis:objectIdentifier>
+      <premis:objectIdentifierType>accession number</premis:objectIdentifierType>
+      <premis:objectIdentifierValue>2024-001</premis:objectIdentifierValue>
+    </premis:objectIdentifier>
+    <premis:originalName>test_transfer</premis:originalName>
+  </premis:object>
+</premis:premis>
+`
+
 func TestParseFile(t *testing.T) {
 	t.Parallel()
 
@@ -161,6 +177,34 @@ func TestAppendPREMISObjectXML(t *testing.T) {
 	assert.Equal(t, xml, premisObjectAddContent)
 }
 
+func TestAppendPREMISIntellectualEntityXML(t *testing.T) {
+	t.Parallel()
+
+	doc, err := premis.NewDoc()
+	assert.NilError(t, err)
+
+	entity := premis.IntellectualEntity{
+		Identifiers: []premis.ObjectIdentifier{
+			{IdType: "UUID", IdValue: "6f3bb3e0-3a4e-4c8f-9d3e-5e7b1c1f7e6a"},
+			{IdType: "accession number", IdValue: "2024-001"},
+		},
+		OriginalName: "test_transfer",
+	}
+
+	// The entity is only added once.
+	err = premis.AppendIntellectualEntityXML(doc, entity)
+	assert.NilError(t, err)
+	err = premis.AppendIntellectualEntityXML(doc, entity)
+	assert.NilError(t, err)
+
+	xml, err := premis.WriteIndentedToString(doc)
+	assert.NilError(t, err)
+	assert.Equal(t, xml, premisIntellectualEntityAddContent)
+
+	err = premis.AppendIntellectualEntityXML(doc, premis.IntellectualEntity{OriginalName: "test_transfer"})
+	assert.Error(t, err, "intellectual entity has no identifiers")
+}
+
 func TestAppendPREMISEventXML(t *testing.T) {
 	t.Parallel()
 
@@ -224,6 +268,31 @@ func TestAppendPREMISAgentXML(t *testing.T) {
 	assert.Equal(t, xml, premisAgentAddContent)
 }
 
+func TestFileOriginalNames(t *testing.T) {
+	t.Parallel()
+
+	doc, err := premis.NewDoc()
+	assert.NilError(t, err)
+
+	err = premis.AppendObjectXML(doc, premis.Object{
+		IdType:       "UUID",
+		IdValue:      "c74a85b7-919b-409e-8209-9c7ebe0e7945",
+		OriginalName: "data/objects/test_transfer/content/cat.jpg",
+	})
+	assert.NilError(t, err)
+
+	// The intellectual entity isn't a file.
+	err = premis.AppendIntellectualEntityXML(doc, premis.IntellectualEntity{
+		Identifiers:  []premis.ObjectIdentifier{{IdType: "UUID", IdValue: "6f3bb3e0-3a4e-4c8f-9d3e-5e7b1c1f7e6a"}},
+		OriginalName: "test_transfer",
+	})
+	assert.NilError(t, err)
+
+	names, err := premis.FileOriginalNames(doc)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names, map[string]bool{"data/objects/test_transfer/content/cat.jpg": true})
+}
+
 func TestFilesWithinDirectory(t *testing.T) {
 	t.Parallel()
 
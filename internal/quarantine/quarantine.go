@@ -0,0 +1,148 @@
+// Package quarantine moves rejected SIPs out of the shared path into a
+// quarantine directory, along with a failure report, and restores them.
+//
+// Each quarantined SIP is stored in its own directory, named after the ID of
+// the workflow that rejected it:
+//
+//	<quarantine path>/<id>/failure-report.json
+//	<quarantine path>/<id>/<SIP name>
+package quarantine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	cp "github.com/otiai10/copy"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+)
+
+// ReportName is the file name of the failure report.
+const ReportName = "failure-report.json"
+
+const dirMode fs.FileMode = 0o700
+
+// Report describes why a SIP has been quarantined.
+type Report struct {
+	// ID identifies the quarantined SIP (the workflow ID).
+	ID string
+
+	// RelativePath is the original path of the SIP relative to the shared
+	// path, where it's restored to.
+	RelativePath string
+
+	// Outcome is the outcome of the preprocessing workflow.
+	Outcome string
+
+	// QuarantinedAt is the time the SIP was quarantined.
+	QuarantinedAt time.Time
+
+	// Failures lists the validation failures.
+	Failures []eventlog.Failure
+
+	// PreservationTasks are the preprocessing workflow events.
+	PreservationTasks []*eventlog.Event
+
+	// Params are the JSON encoded params of the preprocessing workflow, used
+	// to start it again with the same params once the SIP is restored.
+	Params json.RawMessage `json:",omitempty"`
+}
+
+// Quarantine moves the SIP at sipPath into its own directory in quarantinePath
+// and writes the report next to it. It returns the new path of the SIP.
+func Quarantine(sipPath, quarantinePath string, report Report) (string, error) {
+	if !filepath.IsLocal(report.ID) {
+		return "", fmt.Errorf("invalid ID: %q", report.ID)
+	}
+
+	dir := filepath.Join(quarantinePath, report.ID)
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return "", err
+	}
+
+	dest := filepath.Join(dir, filepath.Base(sipPath))
+	if err := move(sipPath, dest); err != nil {
+		return "", fmt.Errorf("move SIP: %v", err)
+	}
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ReportName), b, 0o600); err != nil {
+		return "", fmt.Errorf("write report: %v", err)
+	}
+
+	return dest, nil
+}
+
+// ReadReport reads the report of the SIP quarantined with the given ID.
+func ReadReport(quarantinePath, id string) (*Report, error) {
+	if !filepath.IsLocal(id) {
+		return nil, fmt.Errorf("invalid ID: %q", id)
+	}
+
+	b, err := os.ReadFile(filepath.Join(quarantinePath, id, ReportName)) // #nosec G304 -- id is a local path.
+	if err != nil {
+		return nil, err
+	}
+
+	var r Report
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("invalid report: %v", err)
+	}
+
+	return &r, nil
+}
+
+// Restore moves the SIP quarantined with the given ID back to its original
+// location in sharedPath, and removes it from quarantine. It returns the
+// quarantine report.
+func Restore(quarantinePath, id, sharedPath string) (*Report, error) {
+	r, err := ReadReport(quarantinePath, id)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsLocal(r.RelativePath) {
+		return nil, fmt.Errorf("invalid relative path: %q", r.RelativePath)
+	}
+
+	dest := filepath.Join(sharedPath, r.RelativePath)
+	if _, err := os.Lstat(dest); err == nil {
+		return nil, fmt.Errorf("restore SIP: %s already exists", dest)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), dirMode); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(quarantinePath, id)
+	if err := move(filepath.Join(dir, filepath.Base(r.RelativePath)), dest); err != nil {
+		return nil, fmt.Errorf("restore SIP: %v", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// move renames src to dest, falling back to copying and removing src when
+// they are on different file systems.
+func move(src, dest string) error {
+	err := os.Rename(src, dest)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := cp.Copy(src, dest); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(src)
+}
@@ -0,0 +1,142 @@
+package quarantine_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/enums"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/quarantine"
+)
+
+func TestQuarantine(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 6, 14, 48, 12, 0, time.UTC)
+	report := quarantine.Report{
+		ID:            "preprocessing-workflow-1",
+		RelativePath:  "transfers/sip",
+		Outcome:       "content error",
+		QuarantinedAt: now,
+		Failures: []eventlog.Failure{
+			{
+				Path:    "file.png",
+				Check:   "file format",
+				Code:    "format-not-allowed",
+				Message: `file format "fmt/11" not allowed: "file.png"`,
+				PUID:    "fmt/11",
+			},
+		},
+		PreservationTasks: []*eventlog.Event{
+			{
+				Name:        "Validate SIP file formats",
+				Message:     "Content error: file format validation has failed",
+				Outcome:     enums.EventOutcomeValidationFailure,
+				StartedAt:   now,
+				CompletedAt: now,
+			},
+		},
+	}
+
+	t.Run("Quarantines and restores a SIP", func(t *testing.T) {
+		t.Parallel()
+
+		shared := fs.NewDir(t, "",
+			fs.WithDir("transfers",
+				fs.WithDir("sip",
+					fs.WithFile("file.png", "not a PNG"),
+				),
+			),
+		)
+		qdir := fs.NewDir(t, "")
+
+		got, err := quarantine.Quarantine(shared.Join("transfers", "sip"), qdir.Path(), report)
+		assert.NilError(t, err)
+		assert.Equal(t, got, qdir.Join("preprocessing-workflow-1", "sip"))
+		assert.Assert(t, fs.Equal(shared.Path(), fs.Expected(t,
+			fs.WithDir("transfers"),
+		)))
+		assert.Assert(t, fs.Equal(qdir.Join("preprocessing-workflow-1"), fs.Expected(t,
+			fs.WithMode(0o700),
+			fs.WithFile(quarantine.ReportName, "", fs.MatchAnyFileContent, fs.WithMode(0o600)),
+			fs.WithDir("sip",
+				fs.MatchAnyFileMode,
+				fs.WithFile("file.png", "not a PNG", fs.MatchAnyFileMode),
+			),
+		)))
+
+		r, err := quarantine.ReadReport(qdir.Path(), "preprocessing-workflow-1")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, r, &report)
+
+		r, err = quarantine.Restore(qdir.Path(), "preprocessing-workflow-1", shared.Path())
+		assert.NilError(t, err)
+		assert.DeepEqual(t, r, &report)
+		assert.Assert(t, fs.Equal(shared.Path(), fs.Expected(t,
+			fs.MatchAnyFileMode,
+			fs.WithDir("transfers",
+				fs.MatchAnyFileMode,
+				fs.WithDir("sip",
+					fs.MatchAnyFileMode,
+					fs.WithFile("file.png", "not a PNG", fs.MatchAnyFileMode),
+				),
+			),
+		)))
+		assert.Assert(t, fs.Equal(qdir.Path(), fs.Expected(t, fs.MatchAnyFileMode)))
+	})
+
+	t.Run("Keeps the workflow params", func(t *testing.T) {
+		t.Parallel()
+
+		shared := fs.NewDir(t, "",
+			fs.WithDir("transfers",
+				fs.WithDir("sip"),
+			),
+		)
+		qdir := fs.NewDir(t, "")
+
+		report := report
+		report.Params = json.RawMessage(`{"RelativePath":"transfers/sip","Producer":"Acme Corp.","DryRun":false}`)
+		_, err := quarantine.Quarantine(shared.Join("transfers", "sip"), qdir.Path(), report)
+		assert.NilError(t, err)
+
+		r, err := quarantine.Restore(qdir.Path(), "preprocessing-workflow-1", shared.Path())
+		assert.NilError(t, err)
+		var params map[string]any
+		assert.NilError(t, json.Unmarshal(r.Params, &params))
+		assert.DeepEqual(t, params, map[string]any{
+			"RelativePath": "transfers/sip",
+			"Producer":     "Acme Corp.",
+			"DryRun":       false,
+		})
+	})
+
+	t.Run("Restore errors when the SIP path already exists", func(t *testing.T) {
+		t.Parallel()
+
+		shared := fs.NewDir(t, "",
+			fs.WithDir("transfers",
+				fs.WithDir("sip"),
+			),
+		)
+		qdir := fs.NewDir(t, "")
+
+		_, err := quarantine.Quarantine(shared.Join("transfers", "sip"), qdir.Path(), report)
+		assert.NilError(t, err)
+
+		fs.Apply(t, shared, fs.WithDir("transfers", fs.WithDir("sip")))
+		_, err = quarantine.Restore(qdir.Path(), "preprocessing-workflow-1", shared.Path())
+		assert.Error(t, err, "restore SIP: "+shared.Join("transfers", "sip")+" already exists")
+	})
+
+	t.Run("Errors when the ID is not a local path", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := quarantine.Restore(t.TempDir(), "../etc", t.TempDir())
+		assert.Error(t, err, `invalid ID: "../etc"`)
+	})
+}
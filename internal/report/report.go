@@ -0,0 +1,206 @@
+// Package report renders the validation report of a preprocessed SIP as an
+// HTML page for its producer, with the outcome of each preprocessing step, the
+// problems found in each file with suggested remediations and the allowed file
+// formats. The report is written next to the SIP:
+//
+//	<SIP parent directory>/<SIP name>-validation-report.html
+package report
+
+import (
+	"bytes"
+	"cmp"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+)
+
+// FileSuffix is the suffix appended to the SIP name to name its report.
+const FileSuffix = "-validation-report.html"
+
+//go:embed report.html.tmpl
+var reportTemplate string
+
+// Report is the validation report of a SIP.
+type Report struct {
+	// ID identifies the report (the preprocessing workflow ID).
+	ID string
+
+	// SIPName is the name of the SIP.
+	SIPName string
+
+	// RelativePath is the path of the SIP relative to the shared path.
+	RelativePath string
+
+	// Outcome is the outcome of the preprocessing workflow, e.g. "content
+	// error".
+	Outcome string
+
+	// Language is the language of the report, see package messages.
+	Language string
+
+	// CreatedAt is the time the report was created.
+	CreatedAt time.Time
+
+	// Events lists the preprocessing steps, in execution order.
+	Events []*eventlog.Event
+
+	// Failures and Warnings are the blocking and the non-blocking validation
+	// failures.
+	Failures []eventlog.Failure
+	Warnings []eventlog.Failure
+
+	// AllowedFormats lists the allowed file formats, if known.
+	AllowedFormats []Format
+}
+
+// Format is an allowed file format.
+type Format struct {
+	Name string
+	PUID string
+}
+
+// Path returns the path of the report of the SIP at sipPath.
+func Path(sipPath string) string {
+	return filepath.Clean(sipPath) + FileSuffix
+}
+
+// ReadAllowedFormats reads the allowed file formats from a file format
+// allowlist, a CSV file with a "PRONOM PUID" column and an optional "Format
+// name" column.
+func ReadAllowedFormats(path string) ([]Format, error) {
+	f, err := os.Open(path) // #nosec G304 -- trusted file path.
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("empty allowlist")
+	}
+
+	column := func(name string) int {
+		return slices.IndexFunc(rows[0], func(s string) bool {
+			return strings.EqualFold(strings.TrimSpace(s), name)
+		})
+	}
+	puidIndex, nameIndex := column("pronom puid"), column("format name")
+	if puidIndex == -1 {
+		return nil, errors.New(`missing "PRONOM PUID" column`)
+	}
+
+	var formats []Format
+	for _, row := range rows[1:] {
+		f := Format{PUID: strings.TrimSpace(row[puidIndex])}
+		if f.PUID == "" {
+			continue
+		}
+		if nameIndex != -1 {
+			f.Name = strings.TrimSpace(row[nameIndex])
+		}
+		formats = append(formats, f)
+	}
+
+	return formats, nil
+}
+
+// Write writes the report as HTML to path, replacing an existing report.
+func Write(path string, r Report) error {
+	var buf bytes.Buffer
+	if err := Render(&buf, r); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+// problem is a validation failure of a file.
+type problem struct {
+	eventlog.Failure
+
+	// Warning is true if the failure doesn't block ingest.
+	Warning bool
+}
+
+// file lists the problems of a file, with an empty path for the problems of
+// the whole SIP.
+type file struct {
+	Path     string
+	Problems []problem
+}
+
+// Render writes the report as HTML to w, with the texts in the report
+// language.
+func Render(w io.Writer, r Report) error {
+	lang := cmp.Or(r.Language, messages.DefaultLanguage)
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"label": func(code string) string {
+			return messages.Label(lang, code)
+		},
+		"outcome": func(outcome any) string {
+			return messages.Label(lang, "outcome-"+class(outcome))
+		},
+		"class": class,
+		"remediation": func(code string) string {
+			return messages.Remediation(lang, code)
+		},
+		"firstLine": func(s string) string {
+			line, _, _ := strings.Cut(s, "\n")
+			return strings.TrimSuffix(line, ":")
+		},
+	}).Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, struct {
+		Report
+		Lang  string
+		Files []file
+	}{
+		Report: r,
+		Lang:   lang,
+		Files:  files(r.Failures, r.Warnings),
+	})
+}
+
+// class returns the outcome as a CSS class name, e.g. "content-error".
+func class(outcome any) string {
+	return strings.ReplaceAll(fmt.Sprint(outcome), " ", "-")
+}
+
+// files groups the failures and warnings by file, in path order.
+func files(failures, warnings []eventlog.Failure) []file {
+	var files []file
+	add := func(f eventlog.Failure, warning bool) {
+		i := slices.IndexFunc(files, func(file file) bool { return file.Path == f.Path })
+		if i == -1 {
+			files = append(files, file{Path: f.Path})
+			i = len(files) - 1
+		}
+		files[i].Problems = append(files[i].Problems, problem{Failure: f, Warning: warning})
+	}
+	for _, f := range failures {
+		add(f, false)
+	}
+	for _, f := range warnings {
+		add(f, true)
+	}
+	slices.SortStableFunc(files, func(a, b file) int { return strings.Compare(a.Path, b.Path) })
+
+	return files
+}
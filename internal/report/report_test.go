@@ -0,0 +1,159 @@
+package report_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/enums"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/report"
+)
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, report.Path("/shared/batch/transfer/"), "/shared/batch/transfer-validation-report.html")
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	startedAt := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	checkFiles := &eventlog.Event{
+		Code:        "check-files",
+		Name:        "Check SIP files",
+		Message:     "Content error: file checks have failed:\nempty file: \"empty.txt\"",
+		Outcome:     enums.EventOutcomeValidationFailure,
+		StartedAt:   startedAt,
+		CompletedAt: startedAt.Add(time.Minute),
+	}
+	checkFiles.Children = []*eventlog.Event{
+		{
+			Code:    "check-empty-files",
+			Name:    "Check for empty files",
+			Message: "1 file(s) failed the check",
+			Outcome: enums.EventOutcomeValidationFailure,
+		},
+	}
+	r := report.Report{
+		ID:           "workflow-id",
+		SIPName:      "transfer",
+		RelativePath: "batch/transfer",
+		Outcome:      "content error",
+		CreatedAt:    startedAt.Add(time.Hour),
+		Events:       []*eventlog.Event{checkFiles},
+		Failures: []eventlog.Failure{
+			{Path: "empty.txt", Check: "empty file", Code: "empty-file", Message: "empty file: \"empty.txt\""},
+			{Check: "required path", Code: "missing-required-path", Message: "missing required path: \"data\""},
+		},
+		Warnings: []eventlog.Failure{
+			{Path: "a b.txt", Check: "file name", Code: "unusual-file-name", Message: "unusual file name"},
+		},
+		AllowedFormats: []report.Format{{Name: "PDF/A", PUID: "fmt/95"}},
+	}
+
+	t.Run("Renders the report in English", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		assert.NilError(t, report.Render(&buf, r))
+		html := buf.String()
+
+		for _, s := range []string{
+			`<html lang="en">`,
+			"<h1>Validation report: transfer</h1>",
+			`<tr><th>Outcome</th><td class="content-error">Content error</td></tr>`,
+			"<tr><th>Created at</th><td>2024-05-01 10:00:00 UTC</td></tr>",
+			`<tr><td>Check SIP files</td><td class="validation-failure">Validation failure</td>` +
+				"<td>Content error: file checks have failed</td></tr>",
+			`<tr class="child"><td>Check for empty files</td>`,
+			"<td>empty file: &#34;empty.txt&#34;</td>",
+			"<td>(whole SIP)</td>",
+			`<td class="warning">Warning</td>`,
+			"<tr><td>PDF/A</td><td>fmt/95</td></tr>",
+		} {
+			assert.Assert(t, is.Contains(html, s))
+		}
+
+		// Files are sorted by path, with the whole SIP problems first.
+		whole := strings.Index(html, "(whole SIP)")
+		ab := strings.Index(html, "<td>a b.txt</td>")
+		empty := strings.Index(html, "<td>empty.txt</td>")
+		assert.Assert(t, whole < ab && ab < empty, "whole=%d ab=%d empty=%d", whole, ab, empty)
+	})
+
+	t.Run("Renders the report in French", func(t *testing.T) {
+		t.Parallel()
+
+		r := r
+		r.Language = "fr"
+		var buf bytes.Buffer
+		assert.NilError(t, report.Render(&buf, r))
+		assert.Assert(t, is.Contains(buf.String(), `<html lang="fr">`))
+		assert.Assert(t, is.Contains(buf.String(), "<h1>Rapport de validation: transfer</h1>"))
+	})
+
+	t.Run("Renders a report without failures", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		assert.NilError(t, report.Render(&buf, report.Report{SIPName: "transfer", Outcome: "success"}))
+		assert.Assert(t, is.Contains(buf.String(), "<p>No problems found.</p>"))
+		assert.Assert(t, !strings.Contains(buf.String(), "Allowed file formats"))
+	})
+}
+
+func TestReadAllowedFormats(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name    string
+		content string
+		want    []report.Format
+		wantErr string
+	}{
+		{
+			name:    "Reads the formats",
+			content: "Format name,Pronom PUID\nPDF/A,fmt/95\ntext, x-fmt/16 \n,\n",
+			want:    []report.Format{{Name: "PDF/A", PUID: "fmt/95"}, {Name: "text", PUID: "x-fmt/16"}},
+		},
+		{
+			name:    "Reads the formats without names",
+			content: "PRONOM PUID\nfmt/95\n",
+			want:    []report.Format{{PUID: "fmt/95"}},
+		},
+		{
+			name:    "Errors on a missing PUID column",
+			content: "Format name\nPDF/A\n",
+			wantErr: `missing "PRONOM PUID" column`,
+		},
+		{
+			name:    "Errors on an empty allowlist",
+			wantErr: "empty allowlist",
+		},
+		{
+			name:    "Errors on an invalid CSV",
+			content: "Format name,Pronom PUID\nPDF/A\n",
+			wantErr: "invalid CSV: record on line 2: wrong number of fields",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := fs.NewFile(t, "", fs.WithContent(tt.content))
+			got, err := report.ReadAllowedFormats(f.Path())
+			if tt.wantErr != "" {
+				assert.Error(t, err, tt.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, got, tt.want)
+		})
+	}
+}
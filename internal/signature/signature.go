@@ -0,0 +1,347 @@
+// Package signature signs preprocessed SIPs with a detached Ed25519 signature
+// over their tag manifests and premis.xml, and verifies the signature and the
+// bag checksums to detect changes made after preprocessing.
+package signature
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/md5"  // #nosec G501 -- BagIt manifests may use MD5.
+	"crypto/sha1" // #nosec G505 -- BagIt manifests may use SHA-1.
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// Path is the path of the signature file, relative to the bag.
+	Path = "metadata/preprocessing-signature.json"
+
+	// Algorithm is the signature algorithm.
+	Algorithm = "Ed25519"
+
+	// premisPath is the path of premis.xml, relative to the bag.
+	premisPath = "metadata/premis.xml"
+)
+
+// Signature is the detached signature of a bag.
+type Signature struct {
+	// Algorithm is the signature algorithm, "Ed25519".
+	Algorithm string
+
+	// KeyID identifies the signing key, see KeyID.
+	KeyID string
+
+	// Files are the signed files with their SHA-256 checksums, in path order.
+	Files []File
+
+	// Value is the signature of the message of Files.
+	Value []byte
+}
+
+// File is a signed file.
+type File struct {
+	// Path is the path of the file, relative to the bag.
+	Path   string
+	SHA256 string
+}
+
+// KeyID returns the identifier of the public key pub: the first 16 hex digits
+// of its SHA-256 checksum, prefixed with "ed25519:".
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return "ed25519:" + hex.EncodeToString(sum[:8])
+}
+
+// ReadPrivateKey reads a PEM encoded PKCS #8 Ed25519 private key, e.g.
+// generated with "openssl genpkey -algorithm ed25519".
+func ReadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %v", err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("read private key: %T is not an Ed25519 key", key)
+	}
+
+	return priv, nil
+}
+
+// ReadPublicKey reads a PEM encoded PKIX Ed25519 public key, e.g. extracted
+// with "openssl pkey -pubout", or the public key of a PKCS #8 private key.
+func ReadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "PRIVATE KEY" {
+		priv, err := ReadPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return priv.Public().(ed25519.PublicKey), nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("read public key: %v", err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("read public key: %T is not an Ed25519 key", key)
+	}
+
+	return pub, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	b, err := os.ReadFile(path) // #nosec G304 -- trusted key path.
+	if err != nil {
+		return nil, fmt.Errorf("read key: %v", err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("read key: no PEM data found in %q", path)
+	}
+
+	return block, nil
+}
+
+// Sign signs the tag manifests and premis.xml of the bag at bagPath with key,
+// and writes the signature to the Path file of the bag.
+func Sign(bagPath string, key ed25519.PrivateKey) (*Signature, error) {
+	files, err := signedFiles(bagPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := &Signature{
+		Algorithm: Algorithm,
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		Files:     files,
+	}
+	sig.Value = ed25519.Sign(key, message(files))
+
+	b, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(bagPath, Path), b, 0o600); err != nil {
+		return nil, err
+	}
+
+	return sig, nil
+}
+
+// Verify verifies the signature of the bag at bagPath with the public key pub,
+// then the checksums of the bag tag and payload manifests, and that every
+// payload file is listed in the payload manifests. It returns the signature
+// read from the bag.
+func Verify(bagPath string, pub ed25519.PublicKey) (*Signature, error) {
+	b, err := os.ReadFile(filepath.Join(bagPath, Path)) // #nosec G304 -- path is relative to the bag.
+	if err != nil {
+		return nil, fmt.Errorf("read signature: %v", err)
+	}
+	var sig Signature
+	if err := json.Unmarshal(b, &sig); err != nil {
+		return nil, fmt.Errorf("read signature: %v", err)
+	}
+	if sig.Algorithm != Algorithm {
+		return &sig, fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm)
+	}
+	if id := KeyID(pub); sig.KeyID != id {
+		return &sig, fmt.Errorf("signed with key %s, not %s", sig.KeyID, id)
+	}
+	if !ed25519.Verify(pub, message(sig.Files), sig.Value) {
+		return &sig, errors.New("invalid signature")
+	}
+
+	// Check that the signed files haven't changed, and that no tag manifest
+	// was added or removed.
+	files, err := signedFiles(bagPath)
+	if err != nil {
+		return &sig, err
+	}
+	for _, f := range sig.Files {
+		i := slices.IndexFunc(files, func(o File) bool { return o.Path == f.Path })
+		if i == -1 {
+			return &sig, fmt.Errorf("signed file not found: %q", f.Path)
+		}
+		if files[i].SHA256 != f.SHA256 {
+			return &sig, fmt.Errorf("signed file modified: %q", f.Path)
+		}
+	}
+	if len(files) != len(sig.Files) {
+		return &sig, errors.New("tag manifest added after signing")
+	}
+
+	// Check the bag checksums, covered by the signed tag manifests.
+	manifests, err := filepath.Glob(filepath.Join(bagPath, "*manifest-*.txt"))
+	if err != nil {
+		return &sig, err
+	}
+	var payloadManifests []map[string]bool
+	for _, m := range manifests {
+		paths, err := checkManifest(bagPath, filepath.Base(m))
+		if err != nil {
+			return &sig, err
+		}
+		if strings.HasPrefix(filepath.Base(m), "manifest-") {
+			payloadManifests = append(payloadManifests, paths)
+		}
+	}
+
+	// Check that no payload file was added after signing.
+	if err := checkPayload(bagPath, payloadManifests); err != nil {
+		return &sig, err
+	}
+
+	return &sig, nil
+}
+
+// signedFiles returns the tag manifests and premis.xml of the bag at bagPath
+// with their checksums, in path order.
+func signedFiles(bagPath string) ([]File, error) {
+	manifests, err := filepath.Glob(filepath.Join(bagPath, "tagmanifest-*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	if len(manifests) == 0 {
+		return nil, errors.New("no tag manifest found")
+	}
+
+	paths := []string{premisPath}
+	for _, m := range manifests {
+		paths = append(paths, filepath.Base(m))
+	}
+	slices.Sort(paths)
+
+	files := make([]File, len(paths))
+	for i, p := range paths {
+		sum, err := checksum(filepath.Join(bagPath, p), sha256.New())
+		if err != nil {
+			return nil, err
+		}
+		files[i] = File{Path: p, SHA256: sum}
+	}
+
+	return files, nil
+}
+
+// message returns the signed message of files, one "<SHA-256>  <path>" line
+// per file as written by sha256sum.
+func message(files []File) []byte {
+	var buf bytes.Buffer
+	for _, f := range files {
+		fmt.Fprintf(&buf, "%s  %s\n", f.SHA256, f.Path)
+	}
+
+	return buf.Bytes()
+}
+
+// checkManifest checks the checksums listed in the manifest file name of the
+// bag at bagPath, and returns the listed paths.
+func checkManifest(bagPath, name string) (map[string]bool, error) {
+	alg := name[strings.LastIndex(name, "-")+1 : len(name)-len(".txt")]
+	newHash, ok := map[string]func() hash.Hash{
+		"md5":    md5.New,  // #nosec G401
+		"sha1":   sha1.New, // #nosec G401
+		"sha256": sha256.New,
+		"sha512": sha512.New,
+	}[alg]
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported algorithm %q", name, alg)
+	}
+
+	f, err := os.Open(filepath.Join(bagPath, name)) // #nosec G304 -- manifest path is discovered in the bag.
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	paths := make(map[string]bool)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		want, p, ok := strings.Cut(s.Text(), " ")
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid line %q", name, s.Text())
+		}
+		p = strings.TrimLeft(p, " *")
+		if !filepath.IsLocal(p) {
+			return nil, fmt.Errorf("%s: invalid path %q", name, p)
+		}
+		got, err := checksum(filepath.Join(bagPath, p), newHash())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if !strings.EqualFold(got, want) {
+			return nil, fmt.Errorf("%s: checksum mismatch: %q", name, p)
+		}
+		paths[filepath.ToSlash(p)] = true
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// checkPayload checks that every file in the payload directory of the bag at
+// bagPath is listed in each of the payload manifests.
+func checkPayload(bagPath string, manifests []map[string]bool) error {
+	if len(manifests) == 0 {
+		return errors.New("no payload manifest found")
+	}
+
+	return filepath.WalkDir(filepath.Join(bagPath, "data"), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(bagPath, p)
+		if err != nil {
+			return err
+		}
+		for _, paths := range manifests {
+			if !paths[filepath.ToSlash(rel)] {
+				return fmt.Errorf("payload file not in a manifest: %q", filepath.ToSlash(rel))
+			}
+		}
+		return nil
+	})
+}
+
+func checksum(path string, h hash.Hash) (string, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is relative to the bag.
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
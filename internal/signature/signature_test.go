@@ -0,0 +1,215 @@
+package signature_test
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/signature"
+)
+
+// seed is the seed of the test signing key.
+var seed = []byte("0123456789abcdef0123456789abcdef")
+
+func sum(s string) string {
+	b := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(b[:])
+}
+
+// newBag returns the path of a bag with a payload manifest, a tag manifest
+// and a premis.xml file.
+func newBag(t *testing.T) string {
+	t.Helper()
+
+	bagit := "BagIt-Version: 0.97\nTag-File-Character-Encoding: UTF-8\n"
+	manifest := fmt.Sprintf("%s  data/file.txt\n", sum("content"))
+	tagManifest := fmt.Sprintf("%s  bagit.txt\n%s  manifest-sha256.txt\n", sum(bagit), sum(manifest))
+
+	return fs.NewDir(t, "",
+		fs.WithFile("bagit.txt", bagit),
+		fs.WithFile("manifest-sha256.txt", manifest),
+		fs.WithFile("tagmanifest-sha256.txt", tagManifest),
+		fs.WithDir("data", fs.WithFile("file.txt", "content")),
+		fs.WithDir("metadata", fs.WithFile("premis.xml", "<premis/>")),
+	).Path()
+}
+
+func writeKeys(t *testing.T, key ed25519.PrivateKey) (privPath, pubPath string) {
+	t.Helper()
+
+	priv, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NilError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	assert.NilError(t, err)
+
+	dir := t.TempDir()
+	privPath = filepath.Join(dir, "key.pem")
+	pubPath = filepath.Join(dir, "key.pub")
+	assert.NilError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv}), 0o600))
+	assert.NilError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0o600))
+
+	return privPath, pubPath
+}
+
+func TestReadKeys(t *testing.T) {
+	t.Parallel()
+
+	key := ed25519.NewKeyFromSeed(seed)
+	privPath, pubPath := writeKeys(t, key)
+
+	priv, err := signature.ReadPrivateKey(privPath)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, priv, key)
+
+	pub, err := signature.ReadPublicKey(pubPath)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, pub, key.Public())
+
+	pub, err = signature.ReadPublicKey(privPath)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, pub, key.Public())
+
+	_, err = signature.ReadPrivateKey(pubPath)
+	assert.ErrorContains(t, err, "read private key: ")
+
+	_, err = signature.ReadPrivateKey(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.ErrorContains(t, err, "read key: ")
+}
+
+func TestSign(t *testing.T) {
+	t.Parallel()
+
+	key := ed25519.NewKeyFromSeed(seed)
+	bagPath := newBag(t)
+
+	sig, err := signature.Sign(bagPath, key)
+	assert.NilError(t, err)
+	assert.Equal(t, sig.Algorithm, "Ed25519")
+	assert.Equal(t, sig.KeyID, signature.KeyID(key.Public().(ed25519.PublicKey)))
+	assert.DeepEqual(t, sig.Files, []signature.File{
+		{Path: "metadata/premis.xml", SHA256: sum("<premis/>")},
+		{Path: "tagmanifest-sha256.txt", SHA256: sum(fmt.Sprintf(
+			"%s  bagit.txt\n%s  manifest-sha256.txt\n",
+			sum("BagIt-Version: 0.97\nTag-File-Character-Encoding: UTF-8\n"),
+			sum(fmt.Sprintf("%s  data/file.txt\n", sum("content"))),
+		))},
+	})
+	assert.Assert(t, is.Len(sig.Value, ed25519.SignatureSize))
+
+	got, err := signature.Verify(bagPath, key.Public().(ed25519.PublicKey))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got, sig)
+}
+
+func TestSignErrors(t *testing.T) {
+	t.Parallel()
+
+	key := ed25519.NewKeyFromSeed(seed)
+
+	_, err := signature.Sign(t.TempDir(), key)
+	assert.Error(t, err, "no tag manifest found")
+
+	bagPath := newBag(t)
+	assert.NilError(t, os.Remove(filepath.Join(bagPath, "metadata", "premis.xml")))
+	_, err = signature.Sign(bagPath, key)
+	assert.ErrorContains(t, err, "premis.xml: no such file or directory")
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	key := ed25519.NewKeyFromSeed(seed)
+
+	for _, tt := range []struct {
+		name    string
+		change  func(t *testing.T, bagPath string)
+		pub     ed25519.PublicKey
+		wantErr string
+	}{
+		{
+			name: "Detects a modified premis.xml",
+			change: func(t *testing.T, bagPath string) {
+				assert.NilError(t, os.WriteFile(filepath.Join(bagPath, "metadata", "premis.xml"), []byte("<x/>"), 0o600))
+			},
+			wantErr: `signed file modified: "metadata/premis.xml"`,
+		},
+		{
+			name: "Detects an added tag manifest",
+			change: func(t *testing.T, bagPath string) {
+				assert.NilError(t, os.WriteFile(filepath.Join(bagPath, "tagmanifest-md5.txt"), nil, 0o600))
+			},
+			wantErr: "tag manifest added after signing",
+		},
+		{
+			name: "Detects a modified payload file",
+			change: func(t *testing.T, bagPath string) {
+				assert.NilError(t, os.WriteFile(filepath.Join(bagPath, "data", "file.txt"), []byte("changed"), 0o600))
+			},
+			wantErr: `manifest-sha256.txt: checksum mismatch: "data/file.txt"`,
+		},
+		{
+			name: "Detects an added payload file",
+			change: func(t *testing.T, bagPath string) {
+				assert.NilError(t, os.MkdirAll(filepath.Join(bagPath, "data", "more"), 0o700))
+				assert.NilError(t, os.WriteFile(filepath.Join(bagPath, "data", "more", "added.txt"), nil, 0o600))
+			},
+			wantErr: `payload file not in a manifest: "data/more/added.txt"`,
+		},
+		{
+			name: "Detects a modified signature",
+			change: func(t *testing.T, bagPath string) {
+				p := filepath.Join(bagPath, signature.Path)
+				b, err := os.ReadFile(p)
+				assert.NilError(t, err)
+				var sig signature.Signature
+				assert.NilError(t, json.Unmarshal(b, &sig))
+				sig.Value[0] ^= 0xff
+				b, err = json.Marshal(sig)
+				assert.NilError(t, err)
+				assert.NilError(t, os.WriteFile(p, b, 0o600))
+			},
+			wantErr: "invalid signature",
+		},
+		{
+			name:    "Rejects another key",
+			pub:     ed25519.NewKeyFromSeed([]byte("fedcba9876543210fedcba9876543210")).Public().(ed25519.PublicKey),
+			wantErr: "signed with key ed25519:",
+		},
+		{
+			name: "Errors without a signature",
+			change: func(t *testing.T, bagPath string) {
+				assert.NilError(t, os.Remove(filepath.Join(bagPath, signature.Path)))
+			},
+			wantErr: "read signature: ",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			bagPath := newBag(t)
+			_, err := signature.Sign(bagPath, key)
+			assert.NilError(t, err)
+			if tt.change != nil {
+				tt.change(t, bagPath)
+			}
+			pub := tt.pub
+			if pub == nil {
+				pub = key.Public().(ed25519.PublicKey)
+			}
+
+			_, err = signature.Verify(bagPath, pub)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
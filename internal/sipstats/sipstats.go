@@ -0,0 +1,77 @@
+// Package sipstats computes the statistics of the files of a SIP: the number
+// and size of the files by file format, the largest file and the deepest path.
+package sipstats
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Stats are the statistics of the files of a SIP.
+type Stats struct {
+	// Files and Size are the number and the total size in bytes of the files.
+	Files int
+	Size  int64
+
+	// Formats is the number and size of the files by file format, in PUID
+	// order. It is empty if the file formats weren't identified.
+	Formats []Format
+
+	// LargestFile is the largest file, the first one in walk order if several
+	// files have the same size.
+	LargestFile File
+
+	// DeepestPath is the path of the deepest file or directory, relative to
+	// the SIP, and Depth is its number of path elements.
+	DeepestPath string
+	Depth       int
+}
+
+// Format is the number and the total size in bytes of the files in a file
+// format.
+type Format struct {
+	// PUID is the PRONOM identifier of the format, e.g. "fmt/95".
+	PUID  string
+	Files int
+	Size  int64
+}
+
+// File is a SIP file.
+type File struct {
+	// Path is the path of the file, relative to the SIP.
+	Path string
+	Size int64
+}
+
+// AddFile adds the file at the relative path rel with size bytes in the format
+// puid, or in no format if puid is empty.
+func (s *Stats) AddFile(rel string, size int64, puid string) {
+	s.Files++
+	s.Size += size
+	if s.Files == 1 || size > s.LargestFile.Size {
+		s.LargestFile = File{Path: rel, Size: size}
+	}
+	s.AddDir(rel)
+
+	if puid == "" {
+		return
+	}
+	i, found := slices.BinarySearchFunc(s.Formats, puid, func(f Format, puid string) int {
+		return strings.Compare(f.PUID, puid)
+	})
+	if !found {
+		s.Formats = slices.Insert(s.Formats, i, Format{PUID: puid})
+	}
+	s.Formats[i].Files++
+	s.Formats[i].Size += size
+}
+
+// AddDir adds the directory at the relative path rel.
+func (s *Stats) AddDir(rel string) {
+	depth := len(strings.Split(filepath.ToSlash(rel), "/"))
+	if depth > s.Depth {
+		s.DeepestPath = rel
+		s.Depth = depth
+	}
+}
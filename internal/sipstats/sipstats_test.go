@@ -0,0 +1,48 @@
+package sipstats_test
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/sipstats"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	var s sipstats.Stats
+	s.AddDir("data")
+	s.AddFile("data/report.pdf", 300, "fmt/95")
+	s.AddDir("data/images")
+	s.AddDir("data/images/empty")
+	s.AddFile("data/images/a.tif", 1000, "fmt/353")
+	s.AddFile("data/images/b.tif", 1000, "fmt/353")
+	s.AddFile("README", 20, "")
+
+	assert.DeepEqual(t, s, sipstats.Stats{
+		Files: 4,
+		Size:  2320,
+		Formats: []sipstats.Format{
+			{PUID: "fmt/353", Files: 2, Size: 2000},
+			{PUID: "fmt/95", Files: 1, Size: 300},
+		},
+		LargestFile: sipstats.File{Path: "data/images/a.tif", Size: 1000},
+		DeepestPath: "data/images/empty",
+		Depth:       3,
+	})
+}
+
+func TestStatsEmptyFiles(t *testing.T) {
+	t.Parallel()
+
+	var s sipstats.Stats
+	s.AddFile("empty.txt", 0, "")
+
+	assert.DeepEqual(t, s, sipstats.Stats{
+		Files:       1,
+		LargestFile: sipstats.File{Path: "empty.txt"},
+		DeepestPath: "empty.txt",
+		Depth:       1,
+	})
+}
@@ -0,0 +1,177 @@
+// Package webhook notifies HTTP endpoints of the completion of a preprocessing
+// workflow. Notifications are POST requests with a JSON payload, signed with
+// the HMAC-SHA256 of the request body keyed by the endpoint secret:
+//
+//	X-Preprocessing-Signature: sha256=<hex encoded HMAC>
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the request header with the payload signature.
+const SignatureHeader = "X-Preprocessing-Signature"
+
+// Endpoint is an HTTP endpoint notified of the workflow completions.
+type Endpoint struct {
+	// URL is the http or https URL the notifications are posted to.
+	URL string
+
+	// Secret is the key of the payload signatures (required). It is left out
+	// of the configuration fingerprint.
+	Secret string `json:"-"`
+}
+
+func (e Endpoint) Validate() error {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return fmt.Errorf("URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("URL: invalid value %q, must be an http or https URL", e.URL)
+	}
+	if e.Secret == "" {
+		return errors.New("Secret: missing required value")
+	}
+
+	return nil
+}
+
+// Payload is the notification of a workflow completion.
+type Payload struct {
+	// WorkflowID and RunID identify the preprocessing workflow.
+	WorkflowID string
+	RunID      string
+
+	// SIPID, SIPName and RelativePath identify the SIP.
+	SIPID        string `json:",omitempty"`
+	SIPName      string
+	RelativePath string
+
+	// Outcome is the outcome of the preprocessing workflow.
+	Outcome string
+
+	// Tasks summarizes the preprocessing steps, in execution order.
+	Tasks []Task
+
+	// Failures and Warnings are the number of validation failures and
+	// warnings.
+	Failures int
+	Warnings int
+
+	// ValidationReportPath is the path of the HTML validation report of the
+	// SIP, if written.
+	ValidationReportPath string `json:",omitempty"`
+
+	// QuarantinePath is the path of the SIP in quarantine, if the SIP was
+	// rejected and moved to quarantine.
+	QuarantinePath string `json:",omitempty"`
+
+	CompletedAt time.Time
+}
+
+// Task is the summary of a preprocessing step.
+type Task struct {
+	Code    string
+	Name    string
+	Outcome string
+}
+
+// Sign returns the signature of body with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body with secret.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// StatusError is the error of a notification rejected by the endpoint.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports whether the endpoint may accept the notification later,
+// i.e. the status is a server error, a timeout or a rate limit.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= 500 ||
+		e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode == http.StatusTooManyRequests
+}
+
+// maxErrorBody limits the response body included in a StatusError.
+const maxErrorBody = 512
+
+type Client struct {
+	http    *http.Client
+	secrets map[string]string
+}
+
+// New returns a client notifying endpoints with httpClient.
+func New(httpClient *http.Client, endpoints []Endpoint) *Client {
+	secrets := make(map[string]string, len(endpoints))
+	for _, e := range endpoints {
+		secrets[e.URL] = e.Secret
+	}
+
+	return &Client{http: httpClient, secrets: secrets}
+}
+
+// Notify posts the signed payload to the endpoint with URL u. It returns a
+// *StatusError if the endpoint doesn't respond with a 2xx status.
+func (c *Client) Notify(ctx context.Context, u string, p Payload) error {
+	secret, ok := c.secrets[u]
+	if !ok {
+		return fmt.Errorf("unknown endpoint: %q", u)
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(secret, body))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(b))}
+	}
+
+	return nil
+}
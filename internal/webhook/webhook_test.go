@@ -0,0 +1,99 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/webhook"
+)
+
+func TestSign(t *testing.T) {
+	t.Parallel()
+
+	// echo -n '{"Outcome":"success"}' | openssl dgst -sha256 -hmac s3cr3t
+	body := []byte(`{"Outcome":"success"}`)
+	sig := webhook.Sign("s3cr3t", body)
+	assert.Equal(t, sig, "sha256=b2c7fa41203645a17f335e6fab610e26c7ad410f2aa59c6bddbc44c7ba55fff2")
+	assert.Assert(t, webhook.Verify("s3cr3t", body, sig))
+	assert.Assert(t, !webhook.Verify("secret", body, sig))
+	assert.Assert(t, !webhook.Verify("s3cr3t", []byte(`{"Outcome":"content error"}`), sig))
+}
+
+func TestNotify(t *testing.T) {
+	t.Parallel()
+
+	payload := webhook.Payload{
+		WorkflowID:   "preprocessing-1",
+		RunID:        "run-1",
+		SIPName:      "transfer",
+		RelativePath: "transfer",
+		Outcome:      "success",
+		Tasks:        []webhook.Task{{Code: "bag-sip", Name: "Bag SIP", Outcome: "success"}},
+		CompletedAt:  time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Posts a signed payload", func(t *testing.T) {
+		t.Parallel()
+
+		var got webhook.Payload
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			assert.NilError(t, err)
+			assert.Equal(t, r.Method, http.MethodPost)
+			assert.Equal(t, r.Header.Get("Content-Type"), "application/json")
+			assert.Assert(t, webhook.Verify("s3cr3t", body, r.Header.Get(webhook.SignatureHeader)))
+			assert.NilError(t, json.Unmarshal(body, &got))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		t.Cleanup(srv.Close)
+
+		c := webhook.New(srv.Client(), []webhook.Endpoint{{URL: srv.URL, Secret: "s3cr3t"}})
+		assert.NilError(t, c.Notify(context.Background(), srv.URL, payload))
+		assert.DeepEqual(t, got, payload)
+	})
+
+	t.Run("Errors on a non-2xx status", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+		}))
+		t.Cleanup(srv.Close)
+
+		c := webhook.New(srv.Client(), []webhook.Endpoint{{URL: srv.URL, Secret: "s3cr3t"}})
+		err := c.Notify(context.Background(), srv.URL, payload)
+		assert.Error(t, err, "unexpected status 401: invalid signature")
+		assert.Assert(t, !err.(*webhook.StatusError).Temporary())
+	})
+
+	t.Run("Errors on an unknown endpoint", func(t *testing.T) {
+		t.Parallel()
+
+		c := webhook.New(http.DefaultClient, nil)
+		err := c.Notify(context.Background(), "https://example.com/hooks", payload)
+		assert.Error(t, err, `unknown endpoint: "https://example.com/hooks"`)
+	})
+}
+
+func TestStatusErrorTemporary(t *testing.T) {
+	t.Parallel()
+
+	for code, want := range map[int]bool{
+		http.StatusBadRequest:          false,
+		http.StatusNotFound:            false,
+		http.StatusRequestTimeout:      true,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusServiceUnavailable:  true,
+	} {
+		err := &webhook.StatusError{StatusCode: code}
+		assert.Equal(t, err.Temporary(), want, "status %d", code)
+	}
+}
@@ -0,0 +1,194 @@
+package workflow
+
+import (
+	"time"
+
+	"github.com/artefactual-sdps/temporal-activities/bagcreate"
+	"github.com/artefactual-sdps/temporal-activities/ffvalidate"
+	temporalsdk_temporal "go.temporal.io/sdk/temporal"
+	temporalsdk_workflow "go.temporal.io/sdk/workflow"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+)
+
+// defaultActivityConfig is the default configuration of the activities that
+// only read or write small metadata files.
+var defaultActivityConfig = config.ActivityConfig{
+	Timeout: 5 * time.Minute,
+	Retry: config.RetryConfig{
+		MaxAttempts:        3,
+		InitialInterval:    time.Second,
+		BackoffCoefficient: 2,
+		MaximumInterval:    time.Minute,
+	},
+}
+
+// sipActivityConfig is the default configuration of the activities reading or
+// moving every file of the SIP, which can take hours for large SIPs. Their
+// timeout grows with the SIP size, allowing 10 minutes per GB (about 1.7 MB/s)
+// on top of an hour. They record heartbeats, so a dead worker is detected
+// within a minute. Transient errors, e.g. from a network file system, are
+// retried with a longer backoff.
+var sipActivityConfig = config.ActivityConfig{
+	Timeout:          time.Hour,
+	TimeoutPerGB:     10 * time.Minute,
+	HeartbeatTimeout: time.Minute,
+	Retry: config.RetryConfig{
+		MaxAttempts:        3,
+		InitialInterval:    10 * time.Second,
+		BackoffCoefficient: 2,
+		MaximumInterval:    5 * time.Minute,
+	},
+}
+
+// webhookActivityConfig is the default configuration of the webhook
+// notifications, retried with a longer backoff to ride out endpoint outages.
+var webhookActivityConfig = config.ActivityConfig{
+	Timeout: time.Minute,
+	Retry: config.RetryConfig{
+		MaxAttempts:        5,
+		InitialInterval:    10 * time.Second,
+		BackoffCoefficient: 2,
+		MaximumInterval:    5 * time.Minute,
+	},
+}
+
+// defaultActivityConfigs are the default configurations by activity name,
+// activities not listed use defaultActivityConfig.
+var defaultActivityConfigs = map[string]config.ActivityConfig{
+	activities.VerifyChecksumsName:   sipActivityConfig,
+	activities.ScanVirusesName:       sipActivityConfig,
+	activities.CheckFilesName:        sipActivityConfig,
+	activities.ValidateStructureName: sipActivityConfig,
+	ffvalidate.Name:                  sipActivityConfig,
+	activities.AddPREMISObjectsName:  sipActivityConfig,
+	// Bagging and quarantine modify the SIP in place and may leave it in a
+	// state that can't be retried.
+	bagcreate.Name:               noRetries(sipActivityConfig),
+	activities.QuarantineSIPName: noRetries(sipActivityConfig),
+	// The PREMIS events and agents are appended to premis.xml, a retry after
+	// a timed out write would add them twice.
+	activities.AddPREMISEventName: noRetries(defaultActivityConfig),
+	activities.AddPREMISAgentName: noRetries(defaultActivityConfig),
+	activities.UnbagSIPName:       sipActivityConfig,
+	// Measuring walks every SIP file but isn't retried, the timeouts aren't
+	// scaled if it fails.
+	activities.MeasureSIPName:    noRetries(sipActivityConfig),
+	activities.NotifyWebhookName: webhookActivityConfig,
+}
+
+// scaledTimeout returns the timeout of an activity with configuration c for a
+// SIP of sipSize bytes.
+func scaledTimeout(c config.ActivityConfig, sipSize int64) time.Duration {
+	return c.Timeout + time.Duration(float64(c.TimeoutPerGB)*float64(sipSize)/1e9)
+}
+
+func noRetries(c config.ActivityConfig) config.ActivityConfig {
+	c.Retry.MaxAttempts = 1
+	return c
+}
+
+// activityConfig returns the configuration of the named activity, with the
+// unset values of the worker configuration taken from the activity defaults.
+func activityConfig(cfg config.Configuration, name string) config.ActivityConfig {
+	c, ok := defaultActivityConfigs[name]
+	if !ok {
+		c = defaultActivityConfig
+	}
+
+	set := cfg.Activities[name]
+	if set.Timeout > 0 {
+		c.Timeout = set.Timeout
+	}
+	if set.TimeoutPerGB > 0 {
+		c.TimeoutPerGB = set.TimeoutPerGB
+	}
+	if set.HeartbeatTimeout > 0 {
+		c.HeartbeatTimeout = set.HeartbeatTimeout
+	}
+	if set.Retry.MaxAttempts > 0 {
+		c.Retry.MaxAttempts = set.Retry.MaxAttempts
+	}
+	if set.Retry.InitialInterval > 0 {
+		c.Retry.InitialInterval = set.Retry.InitialInterval
+	}
+	if set.Retry.BackoffCoefficient > 0 {
+		c.Retry.BackoffCoefficient = set.Retry.BackoffCoefficient
+	}
+	if set.Retry.MaximumInterval > 0 {
+		c.Retry.MaximumInterval = set.Retry.MaximumInterval
+	}
+	if len(set.Retry.NonRetryableErrors) > 0 {
+		c.Retry.NonRetryableErrors = set.Retry.NonRetryableErrors
+	}
+
+	return c
+}
+
+// activityOpts returns a context executing the named activity with its
+// configured timeouts, scaled for a SIP of sipSize bytes, and retry policy.
+func activityOpts(
+	ctx temporalsdk_workflow.Context,
+	cfg config.Configuration,
+	name string,
+	sipSize int64,
+) temporalsdk_workflow.Context {
+	c := activityConfig(cfg, name)
+
+	return temporalsdk_workflow.WithActivityOptions(
+		ctx,
+		temporalsdk_workflow.ActivityOptions{
+			StartToCloseTimeout: scaledTimeout(c, sipSize),
+			HeartbeatTimeout:    c.HeartbeatTimeout,
+			RetryPolicy: &temporalsdk_temporal.RetryPolicy{
+				InitialInterval:        c.Retry.InitialInterval,
+				BackoffCoefficient:     c.Retry.BackoffCoefficient,
+				MaximumInterval:        c.Retry.MaximumInterval,
+				MaximumAttempts:        int32(c.Retry.MaxAttempts), // #nosec G115 -- validated config value.
+				NonRetryableErrorTypes: c.Retry.NonRetryableErrors,
+			},
+		},
+	)
+}
+
+func (w *PreprocessingWorkflow) withActivityOpts(
+	ctx temporalsdk_workflow.Context,
+	name string,
+) temporalsdk_workflow.Context {
+	return activityOpts(ctx, w.cfg, name, w.sipSize)
+}
+
+// withWriteActivityOpts is withActivityOpts for the activities modifying the
+// SIP. When the workflow is cancelled, it waits for them to return before
+// cleaning up the SIP.
+func (w *PreprocessingWorkflow) withWriteActivityOpts(
+	ctx temporalsdk_workflow.Context,
+	name string,
+) temporalsdk_workflow.Context {
+	return temporalsdk_workflow.WithWaitForCancellation(w.withActivityOpts(ctx, name), true)
+}
+
+// withSIPSize returns a copy of the workflow scaling the activity timeouts
+// with the size of the SIP at sipPath. The timeouts aren't scaled if the SIP
+// can't be measured, the following steps report the SIP errors.
+func (w *PreprocessingWorkflow) withSIPSize(
+	ctx temporalsdk_workflow.Context,
+	sipPath string,
+) *PreprocessingWorkflow {
+	var res activities.MeasureSIPResult
+	err := temporalsdk_workflow.ExecuteActivity(
+		w.withActivityOpts(ctx, activities.MeasureSIPName),
+		activities.MeasureSIPName,
+		&activities.MeasureSIPParams{SIPPath: sipPath},
+	).Get(ctx, &res)
+	if err != nil {
+		temporalsdk_workflow.GetLogger(ctx).Warn("Measuring SIP failed", "message", err.Error())
+		return w
+	}
+
+	sw := *w
+	sw.sipSize = res.Size
+
+	return &sw
+}
@@ -0,0 +1,191 @@
+package workflow
+
+import (
+	"cmp"
+	"fmt"
+
+	"go.artefactual.dev/tools/temporal"
+	temporalsdk_temporal "go.temporal.io/sdk/temporal"
+	temporalsdk_workflow "go.temporal.io/sdk/workflow"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/batch"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+)
+
+type BatchPreprocessingWorkflowParams struct {
+	// RelativePaths lists the paths of the SIPs to preprocess, relative to the
+	// shared path.
+	RelativePaths []string
+
+	// Directory is a directory of the shared path whose entries are the SIPs
+	// to preprocess, used if RelativePaths is empty.
+	Directory string
+
+	// MaxConcurrency is the maximum number of SIPs preprocessed at the same
+	// time, with 0 using the worker configuration.
+	MaxConcurrency int
+
+	// Producer, Profile and Language are the producer, the processing profile
+	// and the language of every SIP of the batch (optional), see
+	// PreprocessingWorkflowParams.
+	Producer string
+	Profile  string
+	Language string
+
+	// DryRun only validates the SIPs, see PreprocessingWorkflowParams.
+	DryRun bool
+}
+
+// sipParams returns the params of the child workflow preprocessing the SIP at
+// relPath.
+func (p *BatchPreprocessingWorkflowParams) sipParams(relPath string) *PreprocessingWorkflowParams {
+	return &PreprocessingWorkflowParams{
+		RelativePath: relPath,
+		Producer:     p.Producer,
+		Profile:      p.Profile,
+		Language:     p.Language,
+		DryRun:       p.DryRun,
+	}
+}
+
+type BatchPreprocessingWorkflowResult struct {
+	Report batch.Report
+
+	// ReportPath is the path of the report file, if a report directory is
+	// configured.
+	ReportPath string
+}
+
+type BatchPreprocessingWorkflow struct {
+	cfg config.Configuration
+}
+
+// NewBatchPreprocessingWorkflow returns a workflow preprocessing a batch of
+// SIPs, each one in a PreprocessingWorkflow child workflow.
+func NewBatchPreprocessingWorkflow(cfg config.Configuration) *BatchPreprocessingWorkflow {
+	return &BatchPreprocessingWorkflow{
+		cfg: cfg,
+	}
+}
+
+func (w *BatchPreprocessingWorkflow) Execute(
+	ctx temporalsdk_workflow.Context,
+	params *BatchPreprocessingWorkflowParams,
+) (*BatchPreprocessingWorkflowResult, error) {
+	logger := temporalsdk_workflow.GetLogger(ctx)
+	logger.Debug("BatchPreprocessingWorkflow workflow running!", "params", params)
+
+	if params == nil || (len(params.RelativePaths) == 0 && params.Directory == "") || params.MaxConcurrency < 0 {
+		return nil, temporal.NewNonRetryableError(fmt.Errorf("error calling workflow with unexpected inputs"))
+	}
+	// Check the params shared by the SIPs before starting any child workflow.
+	if err := params.sipParams(".").validate(); err != nil {
+		return nil, temporal.NewNonRetryableError(fmt.Errorf("error calling workflow with unexpected inputs: %v", err))
+	}
+
+	report := batch.Report{
+		ID:        temporalsdk_workflow.GetInfo(ctx).WorkflowExecution.ID,
+		StartedAt: temporalsdk_workflow.Now(ctx),
+	}
+
+	paths := params.RelativePaths
+	if len(paths) == 0 {
+		var listSIPs activities.ListSIPsResult
+		e := temporalsdk_workflow.ExecuteActivity(
+			activityOpts(ctx, w.cfg, activities.ListSIPsName, 0),
+			activities.ListSIPsName,
+			&activities.ListSIPsParams{Path: params.Directory},
+		).Get(ctx, &listSIPs)
+		if e != nil {
+			return nil, e
+		}
+		paths = listSIPs.RelativePaths
+	}
+
+	for _, sip := range w.preprocess(ctx, params, paths) {
+		report.Add(sip)
+	}
+	report.CompletedAt = temporalsdk_workflow.Now(ctx)
+	result := &BatchPreprocessingWorkflowResult{Report: report}
+
+	// Write the report of cancelled batches too.
+	if w.cfg.Batch.ReportPath != "" {
+		reportCtx, _ := temporalsdk_workflow.NewDisconnectedContext(ctx)
+		var writeReport activities.WriteBatchReportResult
+		e := temporalsdk_workflow.ExecuteActivity(
+			activityOpts(reportCtx, w.cfg, activities.WriteBatchReportName, 0),
+			activities.WriteBatchReportName,
+			&activities.WriteBatchReportParams{Report: report},
+		).Get(reportCtx, &writeReport)
+		if e != nil {
+			return nil, e
+		}
+		result.ReportPath = writeReport.Path
+	}
+
+	return result, nil
+}
+
+// preprocess starts a child workflow for each SIP in paths, running at most
+// the configured number of child workflows at the same time, and returns their
+// results in paths order. SIPs not started before the batch is cancelled have
+// a cancelled outcome.
+func (w *BatchPreprocessingWorkflow) preprocess(
+	ctx temporalsdk_workflow.Context,
+	params *BatchPreprocessingWorkflowParams,
+	paths []string,
+) []batch.SIP {
+	concurrency := cmp.Or(params.MaxConcurrency, w.cfg.Batch.MaxConcurrency, 1)
+	batchID := temporalsdk_workflow.GetInfo(ctx).WorkflowExecution.ID
+	sips := make([]batch.SIP, len(paths))
+
+	selector := temporalsdk_workflow.NewSelector(ctx)
+	running := 0
+	for i, p := range paths {
+		if running == concurrency {
+			selector.Select(ctx)
+			running--
+		}
+
+		sips[i].RelativePath = p
+		if ctx.Err() != nil {
+			sips[i].Outcome = OutcomeCancelled.String()
+			continue
+		}
+
+		sips[i].WorkflowID = fmt.Sprintf("%s-%d", batchID, i+1)
+		future := temporalsdk_workflow.ExecuteChildWorkflow(
+			temporalsdk_workflow.WithChildOptions(ctx, temporalsdk_workflow.ChildWorkflowOptions{
+				WorkflowID: sips[i].WorkflowID,
+				// Record the outcome of the SIP clean up on cancellation.
+				WaitForCancellation: true,
+			}),
+			w.cfg.Temporal.WorkflowName,
+			params.sipParams(p),
+		)
+		selector.AddFuture(future, func(f temporalsdk_workflow.Future) {
+			var result PreprocessingWorkflowResult
+			err := f.Get(ctx, &result)
+			if temporalsdk_temporal.IsCanceledError(err) {
+				sips[i].Outcome = OutcomeCancelled.String()
+				return
+			}
+			if err != nil {
+				sips[i].Outcome = OutcomeSystemError.String()
+				sips[i].Error = err.Error()
+				return
+			}
+			sips[i].Outcome = result.Outcome.String()
+			sips[i].Failures = len(result.Failures)
+			sips[i].Warnings = len(result.Warnings)
+			sips[i].QuarantinePath = result.QuarantinePath
+		})
+		running++
+	}
+	for ; running > 0; running-- {
+		selector.Select(ctx)
+	}
+
+	return sips
+}
@@ -0,0 +1,253 @@
+package workflow_test
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
+	temporalsdk_workflow "go.temporal.io/sdk/workflow"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/fs"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/batch"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/workflow"
+)
+
+// fakePreprocessing is a preprocessing workflow taking an hour to preprocess
+// a SIP, with an outcome depending on the SIP name.
+type fakePreprocessing struct {
+	mu         sync.Mutex
+	running    int
+	maxRunning int
+	params     []workflow.PreprocessingWorkflowParams
+}
+
+func (f *fakePreprocessing) Execute(
+	ctx temporalsdk_workflow.Context,
+	params *workflow.PreprocessingWorkflowParams,
+) (*workflow.PreprocessingWorkflowResult, error) {
+	f.mu.Lock()
+	f.running++
+	f.maxRunning = max(f.maxRunning, f.running)
+	f.params = append(f.params, *params)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.running--
+		f.mu.Unlock()
+	}()
+
+	result := &workflow.PreprocessingWorkflowResult{RelativePath: params.RelativePath, DryRun: params.DryRun}
+	if err := temporalsdk_workflow.Sleep(ctx, time.Hour); err != nil {
+		result.Outcome = workflow.OutcomeCancelled
+		return result, nil
+	}
+
+	switch filepath.Base(params.RelativePath) {
+	case "invalid":
+		result.Outcome = workflow.OutcomeContentError
+		result.Failures = []eventlog.Failure{{Path: "file.png"}, {Path: "file.exe"}}
+		result.QuarantinePath = "/home/preprocessing/quarantine/" + params.RelativePath
+	case "warning":
+		result.Outcome = workflow.OutcomeSuccessWithWarnings
+		result.Warnings = []eventlog.Failure{{Path: "empty.txt"}}
+	case "broken":
+		return nil, errors.New("workflow failed")
+	}
+
+	return result, nil
+}
+
+func newBatchTestEnv(cfg config.Configuration) (*temporalsdk_testsuite.TestWorkflowEnvironment, *fakePreprocessing) {
+	ts := &temporalsdk_testsuite.WorkflowTestSuite{}
+	env := ts.NewTestWorkflowEnvironment()
+
+	fake := &fakePreprocessing{}
+	env.RegisterWorkflowWithOptions(fake.Execute, temporalsdk_workflow.RegisterOptions{Name: cfg.Temporal.WorkflowName})
+	env.RegisterWorkflowWithOptions(
+		workflow.NewBatchPreprocessingWorkflow(cfg).Execute,
+		temporalsdk_workflow.RegisterOptions{Name: cfg.Temporal.BatchWorkflowName},
+	)
+	env.RegisterActivityWithOptions(
+		activities.NewListSIPs(cfg.SharedPath).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.ListSIPsName},
+	)
+	env.RegisterActivityWithOptions(
+		activities.NewWriteBatchReport(cfg.Batch.ReportPath).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.WriteBatchReportName},
+	)
+
+	return env, fake
+}
+
+func TestBatchPreprocessingWorkflow(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Configuration{
+		Temporal: config.Temporal{WorkflowName: "preprocessing", BatchWorkflowName: "batch-preprocessing"},
+		Batch:    config.BatchConfig{MaxConcurrency: 5},
+	}
+
+	t.Run("Preprocesses the SIPs of a directory", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := cfg
+		cfg.SharedPath = fs.NewDir(t, "",
+			fs.WithDir("batch",
+				fs.WithDir("valid"),
+				fs.WithDir("invalid"),
+				fs.WithDir("warning"),
+				fs.WithDir("broken"),
+				fs.WithDir("valid-2"),
+			),
+		).Path()
+		cfg.Batch.ReportPath = t.TempDir()
+		env, fake := newBatchTestEnv(cfg)
+
+		env.ExecuteWorkflow(
+			cfg.Temporal.BatchWorkflowName,
+			&workflow.BatchPreprocessingWorkflowParams{Directory: "batch", MaxConcurrency: 2},
+		)
+		assert.Assert(t, env.IsWorkflowCompleted())
+
+		var result workflow.BatchPreprocessingWorkflowResult
+		err := env.GetWorkflowResult(&result)
+		assert.NilError(t, err)
+
+		// Five SIPs, two at a time.
+		assert.Equal(t, fake.maxRunning, 2)
+		assert.Equal(t, result.Report.CompletedAt.Sub(result.Report.StartedAt), 3*time.Hour)
+
+		assert.Equal(t, result.ReportPath, filepath.Join(cfg.Batch.ReportPath, "default-test-workflow-id.json"))
+		assert.Equal(t, result.Report.ID, "default-test-workflow-id")
+		assert.DeepEqual(t, result.Report.Outcomes, map[string]int{
+			"success":               2,
+			"content error":         1,
+			"success with warnings": 1,
+			"system error":          1,
+		})
+
+		assert.Equal(t, len(result.Report.SIPs), 5)
+		assert.Assert(t, is.Contains(result.Report.SIPs[0].Error, "workflow failed"))
+		result.Report.SIPs[0].Error = ""
+		assert.DeepEqual(t, result.Report.SIPs, []batch.SIP{
+			{
+				RelativePath: "batch/broken",
+				WorkflowID:   "default-test-workflow-id-1",
+				Outcome:      "system error",
+			},
+			{
+				RelativePath:   "batch/invalid",
+				WorkflowID:     "default-test-workflow-id-2",
+				Outcome:        "content error",
+				Failures:       2,
+				QuarantinePath: "/home/preprocessing/quarantine/batch/invalid",
+			},
+			{
+				RelativePath: "batch/valid",
+				WorkflowID:   "default-test-workflow-id-3",
+				Outcome:      "success",
+			},
+			{
+				RelativePath: "batch/valid-2",
+				WorkflowID:   "default-test-workflow-id-4",
+				Outcome:      "success",
+			},
+			{
+				RelativePath: "batch/warning",
+				WorkflowID:   "default-test-workflow-id-5",
+				Outcome:      "success with warnings",
+				Warnings:     1,
+			},
+		})
+		assert.Assert(t, fs.Equal(cfg.Batch.ReportPath, fs.Expected(t,
+			fs.MatchAnyFileMode,
+			fs.WithFile("default-test-workflow-id.json", "", fs.MatchAnyFileMode, fs.MatchAnyFileContent),
+		)))
+	})
+
+	t.Run("Reports the SIPs of a cancelled batch", func(t *testing.T) {
+		t.Parallel()
+
+		env, _ := newBatchTestEnv(cfg)
+		env.RegisterDelayedCallback(env.CancelWorkflow, 30*time.Minute)
+
+		env.ExecuteWorkflow(
+			cfg.Temporal.BatchWorkflowName,
+			&workflow.BatchPreprocessingWorkflowParams{
+				RelativePaths:  []string{"sip-1", "sip-2", "sip-3"},
+				MaxConcurrency: 2,
+			},
+		)
+		assert.Assert(t, env.IsWorkflowCompleted())
+
+		var result workflow.BatchPreprocessingWorkflowResult
+		err := env.GetWorkflowResult(&result)
+		assert.NilError(t, err)
+		assert.Equal(t, result.ReportPath, "")
+		assert.DeepEqual(t, result.Report.Outcomes, map[string]int{"cancelled": 3})
+		assert.DeepEqual(t, result.Report.SIPs, []batch.SIP{
+			{RelativePath: "sip-1", WorkflowID: "default-test-workflow-id-1", Outcome: "cancelled"},
+			{RelativePath: "sip-2", WorkflowID: "default-test-workflow-id-2", Outcome: "cancelled"},
+			{RelativePath: "sip-3", Outcome: "cancelled"},
+		})
+	})
+
+	t.Run("Forwards the shared SIP params to the child workflows", func(t *testing.T) {
+		t.Parallel()
+
+		env, fake := newBatchTestEnv(cfg)
+		env.ExecuteWorkflow(
+			cfg.Temporal.BatchWorkflowName,
+			&workflow.BatchPreprocessingWorkflowParams{
+				RelativePaths: []string{"sip-1", "sip-2"},
+				Producer:      "Acme Corp.",
+				Profile:       "acme",
+				Language:      "fr",
+				DryRun:        true,
+			},
+		)
+		assert.Assert(t, env.IsWorkflowCompleted())
+		assert.NilError(t, env.GetWorkflowError())
+		assert.DeepEqual(t, fake.params, []workflow.PreprocessingWorkflowParams{
+			{RelativePath: "sip-1", Producer: "Acme Corp.", Profile: "acme", Language: "fr", DryRun: true},
+			{RelativePath: "sip-2", Producer: "Acme Corp.", Profile: "acme", Language: "fr", DryRun: true},
+		})
+	})
+
+	t.Run("Errors when the shared SIP params are invalid", func(t *testing.T) {
+		t.Parallel()
+
+		env, fake := newBatchTestEnv(cfg)
+		env.ExecuteWorkflow(
+			cfg.Temporal.BatchWorkflowName,
+			&workflow.BatchPreprocessingWorkflowParams{
+				RelativePaths: []string{"sip-1"},
+				Profile:       "Acme Corp.",
+			},
+		)
+		assert.Assert(t, env.IsWorkflowCompleted())
+		assert.ErrorContains(t, env.GetWorkflowError(), `invalid Profile: "Acme Corp."`)
+		assert.Equal(t, len(fake.params), 0)
+	})
+
+	t.Run("Errors when there are no SIPs", func(t *testing.T) {
+		t.Parallel()
+
+		env, _ := newBatchTestEnv(cfg)
+		env.ExecuteWorkflow(
+			cfg.Temporal.BatchWorkflowName,
+			&workflow.BatchPreprocessingWorkflowParams{},
+		)
+		assert.Assert(t, env.IsWorkflowCompleted())
+		assert.ErrorContains(t, env.GetWorkflowError(), "error calling workflow with unexpected inputs")
+	})
+}
@@ -1,21 +1,36 @@
 package workflow
 
 import (
+	"cmp"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
-	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/artefactual-sdps/temporal-activities/bagcreate"
-	"github.com/artefactual-sdps/temporal-activities/ffvalidate"
+	"github.com/google/uuid"
 	"go.artefactual.dev/tools/temporal"
 	temporalsdk_temporal "go.temporal.io/sdk/temporal"
 	temporalsdk_workflow "go.temporal.io/sdk/workflow"
 
 	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/audit"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
 	"github.com/artefactual-sdps/preprocessing-demo/internal/enums"
 	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
 	"github.com/artefactual-sdps/preprocessing-demo/internal/premis"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/quarantine"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/report"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/signature"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/sipstats"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/webhook"
 )
 
 type Outcome int
@@ -24,70 +39,316 @@ const (
 	OutcomeSuccess Outcome = iota
 	OutcomeSystemError
 	OutcomeContentError
+	OutcomeSuccessWithWarnings
+	OutcomeCancelled
 )
 
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeSystemError:
+		return "system error"
+	case OutcomeContentError:
+		return "content error"
+	case OutcomeSuccessWithWarnings:
+		return "success with warnings"
+	case OutcomeCancelled:
+		return "cancelled"
+	}
+
+	return fmt.Sprintf("unknown outcome (%d)", int(o))
+}
+
 type PreprocessingWorkflowParams struct {
 	RelativePath string
+
+	// SIPID is the UUID of the SIP in Enduro (optional), recorded as the SIP
+	// identifier in premis.xml and bag-info.txt.
+	SIPID string
+
+	// SIPName is the name of the SIP (optional), defaults to the base name of
+	// RelativePath.
+	SIPName string
+
+	// Producer is the name of the producer of the SIP (optional), recorded in
+	// the workflow search attributes, bag-info.txt and as a PREMIS agent.
+	Producer string
+
+	// AccessionNumber is the accession number of the SIP (optional), recorded
+	// as a SIP identifier in premis.xml and bag-info.txt.
+	AccessionNumber string
+
+	// Profile is the name of the processing profile of the SIP (optional),
+	// defaults to the configured default profile.
+	Profile string
+
+	// Language is the language of the event names and messages (optional),
+	// defaults to the configured language.
+	Language string
+
+	// DryRun only runs the validation steps, which don't modify the SIP. The
+	// steps that would modify the SIP are reported as skipped.
+	DryRun bool
+}
+
+// maxParamLength is the maximum length of the text params, in bytes.
+const maxParamLength = 1024
+
+var profileRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// validate returns an error if a required param is missing or a param isn't
+// valid.
+func (p *PreprocessingWorkflowParams) validate() error {
+	if p.RelativePath == "" {
+		return errors.New("missing RelativePath")
+	}
+	if p.SIPID != "" {
+		if err := uuid.Validate(p.SIPID); err != nil {
+			return fmt.Errorf("invalid SIPID: %v", err)
+		}
+	}
+	for _, param := range []struct{ name, value string }{
+		{"SIPName", p.SIPName},
+		{"Producer", p.Producer},
+		{"AccessionNumber", p.AccessionNumber},
+	} {
+		if len(param.value) > maxParamLength {
+			return fmt.Errorf("invalid %s: longer than %d bytes", param.name, maxParamLength)
+		}
+		if !utf8.ValidString(param.value) || strings.ContainsFunc(param.value, unicode.IsControl) {
+			return fmt.Errorf("invalid %s: %q", param.name, param.value)
+		}
+	}
+	if p.Profile != "" && !profileRegexp.MatchString(p.Profile) {
+		return fmt.Errorf("invalid Profile: %q", p.Profile)
+	}
+	if p.Language != "" && !messages.IsSupported(p.Language) {
+		return fmt.Errorf("unsupported Language: %q", p.Language)
+	}
+
+	return nil
+}
+
+// sipName returns the name of the SIP, the base name of RelativePath by
+// default.
+func (p *PreprocessingWorkflowParams) sipName() string {
+	if p.SIPName != "" {
+		return p.SIPName
+	}
+
+	return filepath.Base(p.RelativePath)
+}
+
+// bagInfo returns the bag-info.txt tags of the SIP metadata.
+func (p *PreprocessingWorkflowParams) bagInfo() []activities.BagInfoTag {
+	var tags []activities.BagInfoTag
+	if p.Producer != "" {
+		tags = append(tags, activities.BagInfoTag{Label: "Source-Organization", Value: p.Producer})
+	}
+	if p.SIPID != "" {
+		tags = append(tags, activities.BagInfoTag{Label: "External-Identifier", Value: p.SIPID})
+	}
+	if p.AccessionNumber != "" {
+		tags = append(tags, activities.BagInfoTag{Label: "Internal-Sender-Identifier", Value: p.AccessionNumber})
+	}
+	if p.SIPName != "" {
+		tags = append(tags, activities.BagInfoTag{Label: "Internal-Sender-Description", Value: p.SIPName})
+	}
+
+	return tags
+}
+
+// intellectualEntity returns the PREMIS object of the SIP, or nil if the SIP
+// has no identifier.
+func (p *PreprocessingWorkflowParams) intellectualEntity() *premis.IntellectualEntity {
+	var ids []premis.ObjectIdentifier
+	if p.SIPID != "" {
+		ids = append(ids, premis.ObjectIdentifier{IdType: "UUID", IdValue: p.SIPID})
+	}
+	if p.AccessionNumber != "" {
+		ids = append(ids, premis.ObjectIdentifier{IdType: "accession number", IdValue: p.AccessionNumber})
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return &premis.IntellectualEntity{Identifiers: ids, OriginalName: p.sipName()}
 }
 
 type PreprocessingWorkflowResult struct {
 	Outcome           Outcome
 	RelativePath      string
 	PreservationTasks []*eventlog.Event
+
+	// Failures lists the failures of all the failed validation steps.
+	Failures []eventlog.Failure
+
+	// Warnings lists the failures of the validation checks configured to warn
+	// instead of blocking ingest.
+	Warnings []eventlog.Failure
+
+	// DryRun is true if the SIP was only validated and left unmodified.
+	DryRun bool
+
+	// QuarantinePath is the path of the SIP in quarantine, if the SIP was
+	// rejected and moved to quarantine.
+	QuarantinePath string
+
+	// ValidationReportPath is the path of the HTML validation report of the
+	// SIP, if enabled.
+	ValidationReportPath string
+
+	// Statistics are the statistics of the SIP files, computed by the
+	// check-files step: the number and size of the files by format, the
+	// largest file and the deepest path.
+	Statistics sipstats.Stats
+
+	// filesProcessed is the number of SIP files processed so far, reported by
+	// the progress query.
+	filesProcessed int
+
+	// language is the language of the event names and messages.
+	language string
+
+	// preprocessingLog is the result of writing the preprocessing log to the
+	// SIP, nil if it wasn't written.
+	preprocessingLog *activities.WritePreprocessingLogResult
+}
+
+// processedFiles records that a step has processed n SIP files.
+func (r *PreprocessingWorkflowResult) processedFiles(n int) {
+	r.filesProcessed = max(r.filesProcessed, n)
 }
 
-func (r *PreprocessingWorkflowResult) newEvent(ctx temporalsdk_workflow.Context, name string) *eventlog.Event {
-	ev := eventlog.NewEvent(temporalsdk_workflow.Now(ctx), name)
+// newEvent adds a new event with code to the result, named in the SIP
+// language.
+func (r *PreprocessingWorkflowResult) newEvent(ctx temporalsdk_workflow.Context, code string) *eventlog.Event {
+	ev := eventlog.NewEvent(temporalsdk_workflow.Now(ctx), messages.EventName(r.language, code))
+	ev.Code = code
 	r.PreservationTasks = append(r.PreservationTasks, ev)
 
 	return ev
 }
 
+// newChild adds a child event with code to ev, named in the SIP language.
+func (r *PreprocessingWorkflowResult) newChild(ev *eventlog.Event, code string) *eventlog.Event {
+	child := ev.NewChild(ev.StartedAt, messages.EventName(r.language, code))
+	child.Code = code
+
+	return child
+}
+
+// complete completes ev with outcome and the event message with code and
+// params.
+func (r *PreprocessingWorkflowResult) complete(
+	ctx temporalsdk_workflow.Context,
+	ev *eventlog.Event,
+	outcome enums.EventOutcome,
+	code string,
+	params messages.Params,
+) {
+	ev.Complete(temporalsdk_workflow.Now(ctx), outcome, "%s", r.message(ev, code, params))
+}
+
+// message sets the message code and params of ev and returns the message
+// rendered in the SIP language.
+func (r *PreprocessingWorkflowResult) message(ev *eventlog.Event, code string, params messages.Params) string {
+	ev.MessageCode = code
+	ev.Params = params
+
+	return messages.Message(r.language, code, params)
+}
+
+// validationError records the blocking failures and the warnings of a
+// validation step, with the reason message code. The step fails with a content
+// error if there are blocking failures, otherwise it completes with a warning
+// and a successful outcome becomes OutcomeSuccessWithWarnings.
 func (r *PreprocessingWorkflowResult) validationError(
 	ctx temporalsdk_workflow.Context,
 	ev *eventlog.Event,
-	msg string,
-	failures []string,
+	reason string,
+	failures []eventlog.Failure,
+	warnings []eventlog.Failure,
 ) *PreprocessingWorkflowResult {
-	r.Outcome = OutcomeContentError
-	ev.Complete(
-		temporalsdk_workflow.Now(ctx),
-		enums.EventOutcomeValidationFailure,
-		"Content error: %s:\n%s",
-		msg,
-		strings.Join(failures, "\n"),
-	)
+	failures = r.localize(failures)
+	warnings = r.localize(warnings)
+	r.Failures = append(r.Failures, failures...)
+	r.Warnings = append(r.Warnings, warnings...)
+
+	params := messages.Params{"reason": reason}
+	if len(failures) > 0 {
+		r.Outcome = OutcomeContentError
+		ev.Fail(
+			temporalsdk_workflow.Now(ctx),
+			slices.Concat(failures, warnings),
+			"%s",
+			r.message(ev, "content-error", params),
+		)
+		return r
+	}
+
+	if r.Outcome == OutcomeSuccess {
+		r.Outcome = OutcomeSuccessWithWarnings
+	}
+	ev.Warn(temporalsdk_workflow.Now(ctx), warnings, "%s", r.message(ev, "warning", params))
 
 	return r
 }
 
+// localize returns failures with their messages in the SIP language.
+func (r *PreprocessingWorkflowResult) localize(failures []eventlog.Failure) []eventlog.Failure {
+	if r.language == messages.DefaultLanguage {
+		return failures
+	}
+
+	localized := make([]eventlog.Failure, len(failures))
+	for i, f := range failures {
+		localized[i] = f.Localize(r.language)
+	}
+
+	return localized
+}
+
+// systemError records the failure of a step, with the reason message code. A
+// step failing because the workflow has been cancelled completes with a
+// cancelled outcome.
 func (r *PreprocessingWorkflowResult) systemError(
 	ctx temporalsdk_workflow.Context,
 	err error,
 	ev *eventlog.Event,
-	msg string,
+	reason string,
 ) *PreprocessingWorkflowResult {
+	if temporalsdk_temporal.IsCanceledError(err) && hasChange(ctx, cancelChangeID) {
+		r.complete(ctx, ev, enums.EventOutcomeCancelled, "cancelled-before-completion", nil)
+		r.Outcome = OutcomeCancelled
+		return r
+	}
+
 	logger := temporalsdk_workflow.GetLogger(ctx)
 	logger.Error("System error", "message", err.Error())
 
-	ev.Complete(
-		temporalsdk_workflow.Now(ctx),
-		enums.EventOutcomeSystemFailure,
-		"System error: %s",
-		msg,
-	)
+	r.complete(ctx, ev, enums.EventOutcomeSystemFailure, "system-error", messages.Params{"reason": reason})
 	r.Outcome = OutcomeSystemError
 
 	return r
 }
 
 type PreprocessingWorkflow struct {
-	sharedPath string
+	cfg config.Configuration
+
+	// profile is the name of the processing profile of the SIP, if any. cfg
+	// is the configuration of the profile.
+	profile string
+
+	// sipSize is the size of the SIP files in bytes, if measured, scaling the
+	// activity timeouts.
+	sipSize int64
 }
 
-func NewPreprocessingWorkflow(sharedPath string) *PreprocessingWorkflow {
+func NewPreprocessingWorkflow(cfg config.Configuration) *PreprocessingWorkflow {
 	return &PreprocessingWorkflow{
-		sharedPath: sharedPath,
+		cfg: cfg,
 	}
 }
 
@@ -101,79 +362,541 @@ func (w *PreprocessingWorkflow) Execute(
 	logger := temporalsdk_workflow.GetLogger(ctx)
 	logger.Debug("PreprocessingWorkflow workflow running!", "params", params)
 
-	if params == nil || params.RelativePath == "" {
+	if params == nil {
 		e = temporal.NewNonRetryableError(fmt.Errorf("error calling workflow with unexpected inputs"))
 		return nil, e
 	}
+	if err := params.validate(); err != nil {
+		e = temporal.NewNonRetryableError(fmt.Errorf("error calling workflow with unexpected inputs: %v", err))
+		return nil, e
+	}
+
+	// Process the SIP with the configuration of its profile.
+	if profile := cmp.Or(params.Profile, w.cfg.DefaultProfile); profile != "" && hasChange(ctx, profilesChangeID) {
+		pw, err := w.withProfile(profile)
+		if err != nil {
+			e = temporal.NewNonRetryableError(fmt.Errorf("error calling workflow with unexpected inputs: %v", err))
+			return nil, e
+		}
+		w = pw
+	}
+
 	result.RelativePath = params.RelativePath
+	result.DryRun = params.DryRun
+	result.language = cmp.Or(params.Language, w.cfg.Language, messages.DefaultLanguage)
+	sipPath := filepath.Join(w.cfg.SharedPath, params.RelativePath)
 
-	// Validate file formats.
-	ev := result.newEvent(ctx, "Validate SIP file formats")
-	var validateFileFormat ffvalidate.Result
-	e = temporalsdk_workflow.ExecuteActivity(
-		withLocalActOpts(ctx),
-		ffvalidate.Name,
-		&ffvalidate.Params{Path: filepath.Join(w.sharedPath, params.RelativePath)},
-	).Get(ctx, &validateFileFormat)
-	if e != nil {
-		result.systemError(ctx, e, ev, "file format validation has failed")
+	progress, err := w.registerQueryHandlers(ctx, result)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { progress.done = true }()
+
+	sipAttributes := []temporalsdk_temporal.SearchAttributeUpdate{
+		SIPNameAttribute.ValueSet(params.sipName()),
+	}
+	for _, attr := range []struct {
+		key   temporalsdk_temporal.SearchAttributeKeyKeyword
+		value string
+	}{
+		{SIPIDAttribute, params.SIPID},
+		{ProducerAttribute, params.Producer},
+		{AccessionNumberAttribute, params.AccessionNumber},
+		{ProfileAttribute, w.profile},
+	} {
+		if attr.value != "" {
+			sipAttributes = append(sipAttributes, attr.key.ValueSet(attr.value))
+		}
+	}
+	w.upsertSearchAttributes(ctx, sipAttributes...)
+
+	if hasChange(ctx, sipSizeChangeID) {
+		w = w.withSIPSize(ctx, sipPath)
+	}
+
+	w.preprocess(ctx, params, result, sipPath)
+
+	// Clean up the SIP in a context that isn't cancelled.
+	if result.Outcome == OutcomeCancelled {
+		cleanupCtx, _ := temporalsdk_workflow.NewDisconnectedContext(ctx)
+		w.cancel(cleanupCtx, result, sipPath)
+		w.upsertOutcome(cleanupCtx, result)
+		w.notify(cleanupCtx, params, result)
+		w.recordRun(cleanupCtx, params, result)
 		return result, nil
 	}
-	if validateFileFormat.Failures != nil {
-		result.validationError(
-			ctx,
-			ev,
-			"file format validation has failed. One or more file formats are not allowed",
-			validateFileFormat.Failures,
-		)
-	} else {
-		ev.Succeed(temporalsdk_workflow.Now(ctx), "No disallowed file formats found")
+
+	// Move rejected SIPs to quarantine.
+	rejected := result.Outcome == OutcomeSystemError || result.Outcome == OutcomeContentError
+	if rejected && w.cfg.QuarantinePath != "" && !params.DryRun && hasChange(ctx, quarantineChangeID) {
+		w.quarantine(ctx, params, result, sipPath)
+	}
+
+	// Write the validation report next to the SIP.
+	if w.cfg.ValidationReport.Enabled && hasChange(ctx, validationReportChangeID) {
+		w.writeValidationReport(ctx, params, result, cmp.Or(result.QuarantinePath, sipPath))
+	}
+	w.upsertOutcome(ctx, result)
+	w.notify(ctx, params, result)
+	w.recordRun(ctx, params, result)
+
+	return result, nil
+}
+
+// preprocess validates and transforms the SIP at sipPath, recording the
+// outcome of each step in result.
+func (w *PreprocessingWorkflow) preprocess(
+	ctx temporalsdk_workflow.Context,
+	params *PreprocessingWorkflowParams,
+	result *PreprocessingWorkflowResult,
+	sipPath string,
+) {
+	// Validate the SIP. Content errors stop the workflow after the first
+	// failing step, or after all the steps when CollectAll is enabled.
+	var premisEvents []premis.EventSummary
+	for _, validate := range w.validationSteps(ctx) {
+		summary := validate(ctx, result, sipPath)
+		if result.Outcome == OutcomeSystemError || result.Outcome == OutcomeCancelled {
+			return
+		}
+		if summary != nil {
+			premisEvents = append(premisEvents, *summary)
+		}
+		if result.Outcome == OutcomeContentError && !w.cfg.Validation.CollectAll {
+			return
+		}
 	}
 
 	// Stop here if there are validation errors.
 	if result.Outcome == OutcomeContentError {
-		return result, nil
+		return
+	}
+
+	// Wait for an archivist to review a SIP with validation warnings.
+	if w.cfg.Review.Enabled && result.Outcome == OutcomeSuccessWithWarnings && !params.DryRun &&
+		hasChange(ctx, reviewChangeID) {
+		summary := w.review(ctx, result)
+		if summary == nil {
+			return
+		}
+		premisEvents = append(premisEvents, *summary)
 	}
 
-	// Bag the SIP for Enduro processing.
-	ev = result.newEvent(ctx, "Bag SIP")
+	// Sign the bagged SIP if a signing key is configured.
+	sign := w.cfg.Signing.KeyPath != "" && hasChange(ctx, signingChangeID)
+
+	// Report what would have been done to the SIP without modifying it.
+	if params.DryRun {
+		events := len(premisEvents) + 1
+		if sign {
+			events++
+		}
+		result.complete(ctx, result.newEvent(ctx, "bag-sip"), enums.EventOutcomeSkipped, "dry-run-bag", nil)
+		result.complete(
+			ctx,
+			result.newEvent(ctx, "create-premis"),
+			enums.EventOutcomeSkipped,
+			"dry-run-premis",
+			messages.Params{"events": strconv.Itoa(events)},
+		)
+		if sign {
+			result.complete(ctx, result.newEvent(ctx, "sign-sip"), enums.EventOutcomeSkipped, "dry-run-sign", nil)
+		}
+		return
+	}
+
+	// Bag the SIP for Enduro processing, with the log of the previous steps.
+	preprocessingLog := eventlog.Log{
+		WorkflowID:        temporalsdk_workflow.GetInfo(ctx).WorkflowExecution.ID,
+		RunID:             temporalsdk_workflow.GetInfo(ctx).WorkflowExecution.RunID,
+		RelativePath:      params.RelativePath,
+		SIPID:             params.SIPID,
+		Language:          result.language,
+		Profile:           w.profile,
+		CreatedAt:         temporalsdk_workflow.Now(ctx),
+		Events:            slices.Clone(result.PreservationTasks),
+		ConfigFingerprint: w.cfg.Fingerprint(),
+	}
+	ev := result.newEvent(ctx, "bag-sip")
+	if hasChange(ctx, preprocessingLogChangeID) {
+		var writeLog activities.WritePreprocessingLogResult
+		e := temporalsdk_workflow.ExecuteActivity(
+			w.withWriteActivityOpts(ctx, activities.WritePreprocessingLogName),
+			activities.WritePreprocessingLogName,
+			&activities.WritePreprocessingLogParams{SIPPath: sipPath, Log: preprocessingLog},
+		).Get(ctx, &writeLog)
+		if temporalsdk_temporal.IsCanceledError(e) {
+			// The log may have been written before the cancellation.
+			result.preprocessingLog = &writeLog
+		}
+		if e != nil {
+			result.systemError(ctx, e, ev, "preprocessing-log-failed")
+			return
+		}
+		result.preprocessingLog = &writeLog
+	}
 	var createBag bagcreate.Result
-	e = temporalsdk_workflow.ExecuteActivity(
-		withLocalActOpts(ctx),
-		bagcreate.Name,
+	e := temporalsdk_workflow.ExecuteActivity(
+		w.withWriteActivityOpts(ctx, bagcreate.Name),
+		w.activityName(bagcreate.Name),
 		&bagcreate.Params{
-			SourcePath: filepath.Join(w.sharedPath, params.RelativePath),
+			SourcePath: sipPath,
 		},
 	).Get(ctx, &createBag)
 	if e != nil {
-		return result.systemError(ctx, e, ev, "bagging has failed"), nil
+		result.systemError(ctx, e, ev, "bag-failed")
+		return
+	}
+	if tags := params.bagInfo(); len(tags) > 0 && hasChange(ctx, sipMetadataChangeID) {
+		var addBagInfo activities.AddBagInfoResult
+		e = temporalsdk_workflow.ExecuteActivity(
+			w.withWriteActivityOpts(ctx, activities.AddBagInfoName),
+			activities.AddBagInfoName,
+			&activities.AddBagInfoParams{BagPath: sipPath, Tags: tags},
+		).Get(ctx, &addBagInfo)
+		if e != nil {
+			result.systemError(ctx, e, ev, "bag-failed")
+			return
+		}
+	}
+	result.complete(ctx, ev, enums.EventOutcomeSuccess, "bag-created", nil)
+	premisEvents = append(premisEvents, premis.EventSummary{
+		Type:          "validation",
+		Detail:        "name=\"Bag SIP\"",
+		Outcome:       "valid",
+		OutcomeDetail: "Format allowed",
+	})
+
+	// Executions started before the signature event change record the
+	// signature in premis.xml before signing, even if signing fails.
+	signatureEvent := sign && hasChange(ctx, signatureEventChangeID)
+	if sign && !signatureEvent {
+		premisEvents = append(premisEvents, signatureEventSummary())
 	}
-	ev.Succeed(temporalsdk_workflow.Now(ctx), "SIP has been bagged")
 
 	// Write PREMIS XML.
-	ev = result.newEvent(ctx, "Create premis.xml")
-	if e = writePREMISFile(ctx, filepath.Join(w.sharedPath, params.RelativePath)); e != nil {
-		result.systemError(ctx, e, ev, "premis.xml creation has failed")
-	} else {
-		ev.Succeed(temporalsdk_workflow.Now(ctx), "Created a premis.xml and stored in metadata directory")
+	ev = result.newEvent(ctx, "create-premis")
+	if e := w.writePREMISFile(ctx, params, sipPath, premisEvents); e != nil {
+		result.systemError(ctx, e, ev, "premis-failed")
+		return
 	}
+	result.complete(ctx, ev, enums.EventOutcomeSuccess, "premis-created", nil)
 
-	return result, nil
+	// Sign the tag manifests and premis.xml.
+	if sign {
+		ev = result.newEvent(ctx, "sign-sip")
+		signSIP, e := w.signSIP(ctx, sipPath, signatureEvent)
+		if e != nil {
+			result.systemError(ctx, e, ev, "signing-failed")
+			return
+		}
+		result.complete(ctx, ev, enums.EventOutcomeSuccess, "sip-signed", messages.Params{"key": signSIP.KeyID})
+	}
+}
+
+// signSIP signs the bagged SIP at sipPath. Once signed, if recordEvent is
+// true, the signature is recorded in premis.xml and the SIP is signed again,
+// so the signature covers its own PREMIS event.
+func (w *PreprocessingWorkflow) signSIP(
+	ctx temporalsdk_workflow.Context,
+	sipPath string,
+	recordEvent bool,
+) (*activities.SignSIPResult, error) {
+	var signSIP activities.SignSIPResult
+	e := temporalsdk_workflow.ExecuteActivity(
+		w.withWriteActivityOpts(ctx, activities.SignSIPName),
+		activities.SignSIPName,
+		&activities.SignSIPParams{BagPath: sipPath},
+	).Get(ctx, &signSIP)
+	if e != nil {
+		return nil, e
+	}
+	if !recordEvent {
+		return &signSIP, nil
+	}
+
+	var addPREMISEvent activities.AddPREMISEventResult
+	e = temporalsdk_workflow.ExecuteActivity(
+		w.withWriteActivityOpts(ctx, activities.AddPREMISEventName),
+		activities.AddPREMISEventName,
+		&activities.AddPREMISEventParams{
+			PREMISFilePath: filepath.Join(sipPath, "metadata", "premis.xml"),
+			Agent:          premis.AgentDefault(),
+			Summary:        signatureEventSummary(),
+		},
+	).Get(ctx, &addPREMISEvent)
+	if e != nil {
+		return nil, e
+	}
+
+	e = temporalsdk_workflow.ExecuteActivity(
+		w.withWriteActivityOpts(ctx, activities.SignSIPName),
+		activities.SignSIPName,
+		&activities.SignSIPParams{BagPath: sipPath},
+	).Get(ctx, &signSIP)
+	if e != nil {
+		return nil, e
+	}
+
+	return &signSIP, nil
+}
+
+// signatureEventSummary returns the PREMIS event of the SIP signature.
+func signatureEventSummary() premis.EventSummary {
+	return premis.EventSummary{
+		Type:          "digital signature generation",
+		Detail:        fmt.Sprintf("name=\"Sign SIP\" algorithm=%q", signature.Algorithm),
+		Outcome:       "success",
+		OutcomeDetail: "Tag manifests and premis.xml signed in " + signature.Path,
+	}
+}
+
+// quarantine moves the rejected SIP at sipPath to the quarantine directory
+// with a failure report. A quarantine failure is recorded in its own event but
+// doesn't change the workflow outcome.
+func (w *PreprocessingWorkflow) quarantine(
+	ctx temporalsdk_workflow.Context,
+	params *PreprocessingWorkflowParams,
+	result *PreprocessingWorkflowResult,
+	sipPath string,
+) {
+	// The params only have string and bool fields, encoding can't fail.
+	encodedParams, _ := json.Marshal(params)
+	report := quarantine.Report{
+		ID:                temporalsdk_workflow.GetInfo(ctx).WorkflowExecution.ID,
+		RelativePath:      result.RelativePath,
+		Outcome:           result.Outcome.String(),
+		QuarantinedAt:     temporalsdk_workflow.Now(ctx),
+		Failures:          result.Failures,
+		PreservationTasks: result.PreservationTasks,
+		Params:            encodedParams,
+	}
+
+	ev := result.newEvent(ctx, "quarantine-sip")
+	var quarantineSIP activities.QuarantineSIPResult
+	e := temporalsdk_workflow.ExecuteActivity(
+		w.withActivityOpts(ctx, activities.QuarantineSIPName),
+		activities.QuarantineSIPName,
+		&activities.QuarantineSIPParams{SIPPath: sipPath, Report: report},
+	).Get(ctx, &quarantineSIP)
+	if e != nil {
+		temporalsdk_workflow.GetLogger(ctx).Error("System error", "message", e.Error())
+		result.complete(
+			ctx,
+			ev,
+			enums.EventOutcomeSystemFailure,
+			"system-error",
+			messages.Params{"reason": "quarantine-failed"},
+		)
+		return
+	}
+
+	result.QuarantinePath = quarantineSIP.Path
+	result.complete(ctx, ev, enums.EventOutcomeSuccess, "quarantined", nil)
 }
 
-func withLocalActOpts(ctx temporalsdk_workflow.Context) temporalsdk_workflow.Context {
-	return temporalsdk_workflow.WithActivityOptions(
-		ctx,
-		temporalsdk_workflow.ActivityOptions{
-			ScheduleToCloseTimeout: 5 * time.Minute,
-			RetryPolicy: &temporalsdk_temporal.RetryPolicy{
-				MaximumAttempts: 1,
+// writeValidationReport writes the HTML validation report of the SIP at
+// sipPath next to it. A report failure is logged but doesn't change the
+// workflow outcome.
+func (w *PreprocessingWorkflow) writeValidationReport(
+	ctx temporalsdk_workflow.Context,
+	params *PreprocessingWorkflowParams,
+	result *PreprocessingWorkflowResult,
+	sipPath string,
+) {
+	var writeReport activities.WriteValidationReportResult
+	e := temporalsdk_workflow.ExecuteActivity(
+		w.withActivityOpts(ctx, activities.WriteValidationReportName),
+		activities.WriteValidationReportName,
+		&activities.WriteValidationReportParams{
+			Path: report.Path(sipPath),
+			Report: report.Report{
+				ID:           temporalsdk_workflow.GetInfo(ctx).WorkflowExecution.ID,
+				SIPName:      params.sipName(),
+				RelativePath: result.RelativePath,
+				Outcome:      result.Outcome.String(),
+				Language:     result.language,
+				CreatedAt:    temporalsdk_workflow.Now(ctx),
+				Events:       result.PreservationTasks,
+				Failures:     result.Failures,
+				Warnings:     result.Warnings,
 			},
+			AllowlistPath: w.cfg.FileFormat.AllowlistPath,
 		},
-	)
+	).Get(ctx, &writeReport)
+	if e != nil {
+		temporalsdk_workflow.GetLogger(ctx).Error("Validation report failed", "message", e.Error())
+		return
+	}
+
+	result.ValidationReportPath = writeReport.Path
 }
 
-func writePREMISFile(ctx temporalsdk_workflow.Context, sipPath string) error {
+// notify posts the workflow result to the configured webhooks, in parallel.
+// A notification failure is logged but doesn't change the workflow outcome.
+func (w *PreprocessingWorkflow) notify(
+	ctx temporalsdk_workflow.Context,
+	params *PreprocessingWorkflowParams,
+	result *PreprocessingWorkflowResult,
+) {
+	if len(w.cfg.Webhooks) == 0 || !hasChange(ctx, webhooksChangeID) {
+		return
+	}
+
+	info := temporalsdk_workflow.GetInfo(ctx)
+	payload := webhook.Payload{
+		WorkflowID:           info.WorkflowExecution.ID,
+		RunID:                info.WorkflowExecution.RunID,
+		SIPID:                params.SIPID,
+		SIPName:              params.sipName(),
+		RelativePath:         result.RelativePath,
+		Outcome:              result.Outcome.String(),
+		Failures:             len(result.Failures),
+		Warnings:             len(result.Warnings),
+		ValidationReportPath: result.ValidationReportPath,
+		QuarantinePath:       result.QuarantinePath,
+		CompletedAt:          temporalsdk_workflow.Now(ctx),
+	}
+	for _, ev := range result.PreservationTasks {
+		payload.Tasks = append(payload.Tasks, webhook.Task{
+			Code:    ev.Code,
+			Name:    ev.Name,
+			Outcome: ev.Outcome.String(),
+		})
+	}
+
+	futures := make([]temporalsdk_workflow.Future, len(w.cfg.Webhooks))
+	for i, e := range w.cfg.Webhooks {
+		futures[i] = temporalsdk_workflow.ExecuteActivity(
+			w.withActivityOpts(ctx, activities.NotifyWebhookName),
+			activities.NotifyWebhookName,
+			&activities.NotifyWebhookParams{URL: e.URL, Payload: payload},
+		)
+	}
+	for i, f := range futures {
+		var notifyWebhook activities.NotifyWebhookResult
+		if e := f.Get(ctx, &notifyWebhook); e != nil {
+			temporalsdk_workflow.GetLogger(ctx).Error(
+				"Webhook notification failed",
+				"url", w.cfg.Webhooks[i].URL,
+				"message", e.Error(),
+			)
+		}
+	}
+}
+
+// recordRun records the run in the audit database. A record failure is logged
+// but doesn't change the workflow outcome.
+func (w *PreprocessingWorkflow) recordRun(
+	ctx temporalsdk_workflow.Context,
+	params *PreprocessingWorkflowParams,
+	result *PreprocessingWorkflowResult,
+) {
+	if w.cfg.Audit.DatabasePath == "" || !hasChange(ctx, auditChangeID) {
+		return
+	}
+
+	info := temporalsdk_workflow.GetInfo(ctx)
+	run := audit.Run{
+		WorkflowID:      info.WorkflowExecution.ID,
+		RunID:           info.WorkflowExecution.RunID,
+		RelativePath:    result.RelativePath,
+		SIPID:           params.SIPID,
+		SIPName:         params.sipName(),
+		Producer:        params.Producer,
+		AccessionNumber: params.AccessionNumber,
+		Profile:         w.profile,
+		Language:        result.language,
+		DryRun:          result.DryRun,
+		Outcome:         result.Outcome.String(),
+		StartedAt:       info.WorkflowStartTime,
+		CompletedAt:     temporalsdk_workflow.Now(ctx),
+		Files:           result.filesProcessed,
+		Size:            result.Statistics.Size,
+		Failures:        len(result.Failures),
+		Warnings:        len(result.Warnings),
+	}
+	for _, f := range result.Statistics.Formats {
+		run.Formats = append(run.Formats, audit.Format{PUID: f.PUID, Files: f.Files, Size: f.Size})
+	}
+	for _, ev := range result.PreservationTasks {
+		run.Tasks = append(run.Tasks, audit.Task{
+			Code:        ev.Code,
+			Name:        ev.Name,
+			Outcome:     ev.Outcome.String(),
+			StartedAt:   ev.StartedAt,
+			CompletedAt: ev.CompletedAt,
+		})
+	}
+
+	var recordRun activities.RecordRunResult
+	e := temporalsdk_workflow.ExecuteActivity(
+		w.withActivityOpts(ctx, activities.RecordRunName),
+		activities.RecordRunName,
+		&activities.RecordRunParams{Run: run},
+	).Get(ctx, &recordRun)
+	if e != nil {
+		temporalsdk_workflow.GetLogger(ctx).Error("Audit record failed", "message", e.Error())
+	}
+}
+
+// cancel records the cancellation of the workflow and, if bagging has
+// started, restores the SIP at sipPath to its original layout and removes the
+// preprocessing log.
+func (w *PreprocessingWorkflow) cancel(
+	ctx temporalsdk_workflow.Context,
+	result *PreprocessingWorkflowResult,
+	sipPath string,
+) {
+	var bag *eventlog.Event
+	if !result.DryRun {
+		for _, ev := range result.PreservationTasks {
+			if ev.Code == "bag-sip" {
+				bag = ev
+			}
+		}
+	}
+
+	ev := result.newEvent(ctx, "cancel-preprocessing")
+	msg := "cancelled-not-modified"
+	if bag != nil {
+		unbagParams := &activities.UnbagSIPParams{SIPPath: sipPath, BagStartedAt: bag.StartedAt}
+		if result.preprocessingLog != nil {
+			unbagParams.LogRunID = temporalsdk_workflow.GetInfo(ctx).WorkflowExecution.RunID
+			unbagParams.RemoveMetadataDir = result.preprocessingLog.MetadataCreated
+		}
+
+		var unbagSIP activities.UnbagSIPResult
+		e := temporalsdk_workflow.ExecuteActivity(
+			w.withActivityOpts(ctx, activities.UnbagSIPName),
+			activities.UnbagSIPName,
+			unbagParams,
+		).Get(ctx, &unbagSIP)
+		if e != nil {
+			temporalsdk_workflow.GetLogger(ctx).Error("System error", "message", e.Error())
+			result.complete(
+				ctx,
+				ev,
+				enums.EventOutcomeSystemFailure,
+				"system-error",
+				messages.Params{"reason": "restore-failed"},
+			)
+			return
+		}
+		if unbagSIP.Restored {
+			msg = "cancelled-restored"
+		}
+	}
+
+	result.complete(ctx, ev, enums.EventOutcomeCancelled, msg, nil)
+}
+
+func (w *PreprocessingWorkflow) writePREMISFile(
+	ctx temporalsdk_workflow.Context,
+	params *PreprocessingWorkflowParams,
+	sipPath string,
+	events []premis.EventSummary,
+) error {
 	var e error
 	metadataPath := filepath.Join(sipPath, "metadata")
 	premisFilePath := filepath.Join(metadataPath, "premis.xml")
@@ -181,60 +904,39 @@ func writePREMISFile(ctx temporalsdk_workflow.Context, sipPath string) error {
 	// Add PREMIS objects.
 	var addPREMISObjects activities.AddPREMISObjectsResult
 	e = temporalsdk_workflow.ExecuteActivity(
-		withLocalActOpts(ctx),
+		w.withWriteActivityOpts(ctx, activities.AddPREMISObjectsName),
 		activities.AddPREMISObjectsName,
 		&activities.AddPREMISObjectsParams{
-			SIPPath:        sipPath,
-			PREMISFilePath: premisFilePath,
+			SIPPath:            sipPath,
+			PREMISFilePath:     premisFilePath,
+			IntellectualEntity: params.intellectualEntity(),
 		},
 	).Get(ctx, &addPREMISObjects)
 	if e != nil {
 		return e
 	}
 
-	// Add PREMIS event noting validate SIP file formats result.
-	var addPREMISEvent activities.AddPREMISEventResult
-	e = temporalsdk_workflow.ExecuteActivity(
-		withLocalActOpts(ctx),
-		activities.AddPREMISEventName,
-		&activities.AddPREMISEventParams{
-			PREMISFilePath: premisFilePath,
-			Agent:          premis.AgentDefault(),
-			Summary: premis.EventSummary{
-				Type:          "validation",
-				Detail:        "name=\"Validate SIP file formats\"",
-				Outcome:       "valid",
-				OutcomeDetail: "File formats allowed",
+	// Add a PREMIS event for each preprocessing step.
+	for _, summary := range events {
+		var addPREMISEvent activities.AddPREMISEventResult
+		e = temporalsdk_workflow.ExecuteActivity(
+			w.withWriteActivityOpts(ctx, activities.AddPREMISEventName),
+			activities.AddPREMISEventName,
+			&activities.AddPREMISEventParams{
+				PREMISFilePath: premisFilePath,
+				Agent:          premis.AgentDefault(),
+				Summary:        summary,
 			},
-		},
-	).Get(ctx, &addPREMISEvent)
-	if e != nil {
-		return e
-	}
-
-	// Add PREMIS events for SIP bagging.
-	e = temporalsdk_workflow.ExecuteActivity(
-		withLocalActOpts(ctx),
-		activities.AddPREMISEventName,
-		&activities.AddPREMISEventParams{
-			PREMISFilePath: premisFilePath,
-			Agent:          premis.AgentDefault(),
-			Summary: premis.EventSummary{
-				Type:          "validation",
-				Detail:        "name=\"Bag SIP\"",
-				Outcome:       "valid",
-				OutcomeDetail: "Format allowed",
-			},
-		},
-	).Get(ctx, &addPREMISEvent)
-	if e != nil {
-		return e
+		).Get(ctx, &addPREMISEvent)
+		if e != nil {
+			return e
+		}
 	}
 
 	// Add Enduro PREMIS agent.
 	var addPREMISAgent activities.AddPREMISAgentResult
 	e = temporalsdk_workflow.ExecuteActivity(
-		withLocalActOpts(ctx),
+		w.withWriteActivityOpts(ctx, activities.AddPREMISAgentName),
 		activities.AddPREMISAgentName,
 		&activities.AddPREMISAgentParams{
 			PREMISFilePath: premisFilePath,
@@ -245,5 +947,25 @@ func writePREMISFile(ctx temporalsdk_workflow.Context, sipPath string) error {
 		return e
 	}
 
+	// Add the producer PREMIS agent.
+	if params.Producer != "" && hasChange(ctx, sipMetadataChangeID) {
+		e = temporalsdk_workflow.ExecuteActivity(
+			w.withWriteActivityOpts(ctx, activities.AddPREMISAgentName),
+			activities.AddPREMISAgentName,
+			&activities.AddPREMISAgentParams{
+				PREMISFilePath: premisFilePath,
+				Agent: premis.Agent{
+					Type:    "organization",
+					Name:    params.Producer,
+					IdType:  "local",
+					IdValue: params.Producer,
+				},
+			},
+		).Get(ctx, &addPREMISAgent)
+		if e != nil {
+			return e
+		}
+	}
+
 	return nil
 }
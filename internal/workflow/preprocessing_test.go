@@ -1,24 +1,42 @@
 package workflow_test
 
 import (
+	"context"
+	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/artefactual-sdps/temporal-activities/bagcreate"
 	"github.com/artefactual-sdps/temporal-activities/ffvalidate"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	temporalsdk_activity "go.temporal.io/sdk/activity"
+	temporalsdk_temporal "go.temporal.io/sdk/temporal"
 	temporalsdk_testsuite "go.temporal.io/sdk/testsuite"
 	temporalsdk_worker "go.temporal.io/sdk/worker"
 	"gotest.tools/v3/fs"
 
 	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/audit"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/clamd"
 	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
 	"github.com/artefactual-sdps/preprocessing-demo/internal/enums"
 	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/quarantine"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/signature"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/sipstats"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/webhook"
 	"github.com/artefactual-sdps/preprocessing-demo/internal/workflow"
 )
 
@@ -72,13 +90,90 @@ func (s *PreprocessingTestSuite) SetupTest(cfg config.Configuration) {
 
 	// Register activities.
 	s.env.RegisterActivityWithOptions(
-		ffvalidate.New(cfg.FileFormat).Execute,
+		activities.NewVerifyChecksums(cfg.Fixity.ManifestNames).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.VerifyChecksumsName},
+	)
+	if cfg.ClamAV.Address != "" {
+		clamdClient, err := clamd.New(cfg.ClamAV)
+		s.Require().NoError(err)
+		s.env.RegisterActivityWithOptions(
+			activities.NewScanViruses(clamdClient).Execute,
+			temporalsdk_activity.RegisterOptions{Name: activities.ScanVirusesName},
+		)
+	}
+	s.env.RegisterActivityWithOptions(
+		activities.NewCheckFiles(nil, nil).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.CheckFilesName},
+	)
+	s.env.RegisterActivityWithOptions(
+		activities.Heartbeat(ffvalidate.New(cfg.FileFormat).Execute),
 		temporalsdk_activity.RegisterOptions{Name: ffvalidate.Name},
 	)
 	s.env.RegisterActivityWithOptions(
-		bagcreate.New(cfg.Bagit).Execute,
+		activities.Heartbeat(bagcreate.New(cfg.Bagit).Execute),
 		temporalsdk_activity.RegisterOptions{Name: bagcreate.Name},
 	)
+	for name := range cfg.Profiles {
+		profileCfg, err := cfg.WithProfile(name)
+		s.Require().NoError(err)
+		s.env.RegisterActivityWithOptions(
+			activities.Heartbeat(ffvalidate.New(profileCfg.FileFormat).Execute),
+			temporalsdk_activity.RegisterOptions{Name: workflow.ProfileActivityName(ffvalidate.Name, name)},
+		)
+		s.env.RegisterActivityWithOptions(
+			activities.Heartbeat(bagcreate.New(profileCfg.Bagit).Execute),
+			temporalsdk_activity.RegisterOptions{Name: workflow.ProfileActivityName(bagcreate.Name, name)},
+		)
+	}
+	s.env.RegisterActivityWithOptions(
+		activities.Heartbeat(activities.NewValidateStructure().Execute),
+		temporalsdk_activity.RegisterOptions{Name: activities.ValidateStructureName},
+	)
+	s.env.RegisterActivityWithOptions(
+		activities.NewQuarantineSIP(cfg.QuarantinePath).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.QuarantineSIPName},
+	)
+	s.env.RegisterActivityWithOptions(
+		activities.NewUnbagSIP().Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.UnbagSIPName},
+	)
+	s.env.RegisterActivityWithOptions(
+		activities.NewMeasureSIP().Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.MeasureSIPName},
+	)
+	s.env.RegisterActivityWithOptions(
+		activities.NewWritePreprocessingLog("test").Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.WritePreprocessingLogName},
+	)
+	s.env.RegisterActivityWithOptions(
+		activities.NewWriteValidationReport().Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.WriteValidationReportName},
+	)
+	s.env.RegisterActivityWithOptions(
+		activities.NewNotifyWebhook(webhook.New(http.DefaultClient, cfg.Webhooks)).Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.NotifyWebhookName},
+	)
+	if cfg.Audit.DatabasePath != "" {
+		db, err := audit.Open(cfg.Audit.DatabasePath)
+		s.Require().NoError(err)
+		s.T().Cleanup(func() { db.Close() })
+		s.env.RegisterActivityWithOptions(
+			activities.NewRecordRun(db).Execute,
+			temporalsdk_activity.RegisterOptions{Name: activities.RecordRunName},
+		)
+	}
+	if cfg.Signing.KeyPath != "" {
+		key, err := signature.ReadPrivateKey(cfg.Signing.KeyPath)
+		s.Require().NoError(err)
+		s.env.RegisterActivityWithOptions(
+			activities.NewSignSIP(key).Execute,
+			temporalsdk_activity.RegisterOptions{Name: activities.SignSIPName},
+		)
+	}
+	s.env.RegisterActivityWithOptions(
+		activities.NewAddBagInfo().Execute,
+		temporalsdk_activity.RegisterOptions{Name: activities.AddBagInfoName},
+	)
 	s.env.RegisterActivityWithOptions(
 		activities.NewAddPREMISAgent().Execute,
 		temporalsdk_activity.RegisterOptions{Name: activities.AddPREMISAgentName},
@@ -92,7 +187,8 @@ func (s *PreprocessingTestSuite) SetupTest(cfg config.Configuration) {
 		temporalsdk_activity.RegisterOptions{Name: activities.AddPREMISObjectsName},
 	)
 
-	s.workflow = workflow.NewPreprocessingWorkflow(s.testDir)
+	cfg.SharedPath = s.testDir
+	s.workflow = workflow.NewPreprocessingWorkflow(cfg)
 }
 
 func (s *PreprocessingTestSuite) AfterTest(suiteName, testName string) {
@@ -103,6 +199,77 @@ func TestPreprocessingWorkflow(t *testing.T) {
 	suite.Run(t, new(PreprocessingTestSuite))
 }
 
+// fileCheckEvents returns the child events of a check files event without
+// problems, completed at t with the millisecond precision of child events.
+func fileCheckEvents(t time.Time) eventlog.Children {
+	t = t.Truncate(time.Millisecond)
+	return eventlog.Children{
+		{
+			Code:        "check-empty-files",
+			Name:        "Check empty files",
+			MessageCode: "file-check-valid",
+			Message:     "No problems found",
+			Outcome:     enums.EventOutcomeSuccess,
+			StartedAt:   t,
+			CompletedAt: t,
+		},
+		{
+			Code:        "check-file-names",
+			Name:        "Check file names",
+			MessageCode: "file-check-valid",
+			Message:     "No problems found",
+			Outcome:     enums.EventOutcomeSuccess,
+			StartedAt:   t,
+			CompletedAt: t,
+		},
+		{
+			Code:        "check-deprecated-formats",
+			Name:        "Check deprecated formats",
+			MessageCode: "deprecated-formats-not-configured",
+			Message:     "No deprecated formats configured",
+			Outcome:     enums.EventOutcomeSkipped,
+			StartedAt:   t,
+			CompletedAt: t,
+		},
+	}
+}
+
+// mockValidation mocks the verify-checksums activity of a SIP without checksum
+// manifests and the check-files and file format validation activities of the
+// SIP at sipPath, returning checkFiles and validateFormats. File format
+// validation isn't mocked if validateFormats is nil.
+func (s *PreprocessingTestSuite) mockValidation(
+	sipPath string,
+	checkFiles *activities.CheckFilesResult,
+	validateFormats *ffvalidate.Result,
+) {
+	sessionCtx := mock.AnythingOfType("*context.timerCtx")
+
+	s.env.OnActivity(
+		activities.VerifyChecksumsName,
+		sessionCtx,
+		&activities.VerifyChecksumsParams{SIPPath: sipPath},
+	).Return(
+		&activities.VerifyChecksumsResult{}, nil,
+	)
+	s.env.OnActivity(
+		activities.CheckFilesName,
+		sessionCtx,
+		&activities.CheckFilesParams{SIPPath: sipPath},
+	).Return(
+		checkFiles, nil,
+	)
+	if validateFormats != nil {
+		s.env.OnActivity(
+			ffvalidate.Name,
+			sessionCtx,
+			&ffvalidate.Params{Path: sipPath},
+		).Return(
+			validateFormats, nil,
+		)
+	}
+}
+
 func (s *PreprocessingTestSuite) TestSuccess() {
 	transferFiles := fs.NewDir(s.T(), "",
 		fs.WithFile("allowed_file_formats.csv", allowedFormatsCSV),
@@ -111,6 +278,7 @@ func (s *PreprocessingTestSuite) TestSuccess() {
 	relPath := transferFiles.Path() // "transfer"
 
 	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
 		FileFormat: ffvalidate.Config{
 			AllowlistPath: transferFiles.Path() + "/allowed_file_formats.csv",
 		},
@@ -118,6 +286,36 @@ func (s *PreprocessingTestSuite) TestSuccess() {
 	sessionCtx := mock.AnythingOfType("*context.timerCtx")
 
 	// Mock activities.
+	s.env.OnActivity(
+		activities.VerifyChecksumsName,
+		sessionCtx,
+		&activities.VerifyChecksumsParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.VerifyChecksumsResult{
+			Manifests: []string{"checksums.sha256"},
+			Verified:  1,
+		}, nil,
+	)
+
+	s.env.OnActivity(
+		activities.CheckFilesName,
+		sessionCtx,
+		&activities.CheckFilesParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.CheckFilesResult{
+			Checked: 2,
+			Size:    1024,
+			Stats: sipstats.Stats{
+				Files:       2,
+				Size:        1024,
+				Formats:     []sipstats.Format{{PUID: "fmt/95", Files: 2, Size: 1024}},
+				LargestFile: sipstats.File{Path: "content/a.pdf", Size: 512},
+				DeepestPath: "content/a.pdf",
+				Depth:       2,
+			},
+		}, nil,
+	)
+
 	s.env.OnActivity(
 		ffvalidate.Name,
 		sessionCtx,
@@ -151,32 +349,119 @@ func (s *PreprocessingTestSuite) TestSuccess() {
 			RelativePath: relPath,
 			PreservationTasks: []*eventlog.Event{
 				{
+					Code:        "verify-checksums",
+					Name:        "Verify SIP checksums",
+					MessageCode: "checksums-verified",
+					Params:      messages.Params{"manifests": "checksums.sha256", "verified": "1"},
+					Message:     "Verified 1 file checksums from checksums.sha256",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+				},
+				{
+					Code:        "check-files",
+					Name:        "Check SIP files",
+					MessageCode: "files-valid",
+					Params:      messages.Params{"files": "2"},
+					Message:     "No problems found in 2 files",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+					Children:    fileCheckEvents(s.env.Now().UTC()),
+				},
+				{
+					Code:        "validate-file-formats",
 					Name:        "Validate SIP file formats",
+					MessageCode: "file-formats-valid",
 					Message:     "No disallowed file formats found",
 					Outcome:     enums.EventOutcomeSuccess,
 					StartedAt:   s.env.Now().UTC(),
 					CompletedAt: s.env.Now().UTC(),
 				},
 				{
+					Code:        "bag-sip",
 					Name:        "Bag SIP",
+					MessageCode: "bag-created",
 					Message:     "SIP has been bagged",
 					Outcome:     enums.EventOutcomeSuccess,
 					StartedAt:   s.env.Now().UTC(),
 					CompletedAt: s.env.Now().UTC(),
 				},
 				{
+					Code:        "create-premis",
 					Name:        "Create premis.xml",
+					MessageCode: "premis-created",
 					Message:     "Created a premis.xml and stored in metadata directory",
 					Outcome:     enums.EventOutcomeSuccess,
 					StartedAt:   s.env.Now().UTC(),
 					CompletedAt: s.env.Now().UTC(),
 				},
 			},
+			Statistics: sipstats.Stats{
+				Files:       2,
+				Size:        1024,
+				Formats:     []sipstats.Format{{PUID: "fmt/95", Files: 2, Size: 1024}},
+				LargestFile: sipstats.File{Path: "content/a.pdf", Size: 512},
+				DeepestPath: "content/a.pdf",
+				Depth:       2,
+			},
 		},
 		&result,
 	)
 }
 
+func (s *PreprocessingTestSuite) TestCheckFilesOptIn() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+	sessionCtx := mock.AnythingOfType("*context.timerCtx")
+	sipPath := filepath.Join(s.testDir, relPath)
+
+	s.env.OnActivity(
+		activities.VerifyChecksumsName,
+		sessionCtx,
+		&activities.VerifyChecksumsParams{SIPPath: sipPath},
+	).Return(
+		&activities.VerifyChecksumsResult{}, nil,
+	)
+	s.env.OnActivity(activities.CheckFilesName, mock.Anything, mock.Anything).Never()
+	s.env.OnActivity(
+		ffvalidate.Name,
+		sessionCtx,
+		&ffvalidate.Params{Path: sipPath},
+	).Return(
+		&ffvalidate.Result{}, nil,
+	)
+	s.env.OnActivity(
+		bagcreate.Name,
+		sessionCtx,
+		&bagcreate.Params{SourcePath: sipPath},
+	).Return(
+		&bagcreate.Result{BagPath: sipPath}, nil,
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeSuccess, result.Outcome)
+
+	var codes []string
+	for _, ev := range result.PreservationTasks {
+		codes = append(codes, ev.Code)
+	}
+	s.Equal([]string{"verify-checksums", "validate-file-formats", "bag-sip", "create-premis"}, codes)
+}
+
 func (s *PreprocessingTestSuite) TestNoRelativePathError() {
 	s.SetupTest(config.Configuration{})
 	s.env.ExecuteWorkflow(
@@ -191,9 +476,47 @@ func (s *PreprocessingTestSuite) TestNoRelativePathError() {
 	s.ErrorContains(err, "error calling workflow with unexpected inputs")
 }
 
+func (s *PreprocessingTestSuite) TestInvalidParamsError() {
+	for _, tc := range []struct {
+		name    string
+		params  workflow.PreprocessingWorkflowParams
+		wantErr string
+	}{
+		{
+			name:    "Invalid SIPID",
+			params:  workflow.PreprocessingWorkflowParams{RelativePath: "transfer", SIPID: "sip-1"},
+			wantErr: "invalid SIPID: invalid UUID length: 5",
+		},
+		{
+			name:    "Control characters in Producer",
+			params:  workflow.PreprocessingWorkflowParams{RelativePath: "transfer", Producer: "Acme\n"},
+			wantErr: `invalid Producer: "Acme\n"`,
+		},
+		{
+			name:    "Invalid Profile",
+			params:  workflow.PreprocessingWorkflowParams{RelativePath: "transfer", Profile: "../default"},
+			wantErr: `invalid Profile: "../default"`,
+		},
+		{
+			name:    "Unsupported Language",
+			params:  workflow.PreprocessingWorkflowParams{RelativePath: "transfer", Language: "es"},
+			wantErr: `unsupported Language: "es"`,
+		},
+	} {
+		s.Run(tc.name, func() {
+			s.SetupTest(config.Configuration{})
+			s.env.ExecuteWorkflow(s.workflow.Execute, &tc.params)
+
+			s.True(s.env.IsWorkflowCompleted())
+			s.ErrorContains(s.env.GetWorkflowError(), "error calling workflow with unexpected inputs: "+tc.wantErr)
+		})
+	}
+}
+
 func (s *PreprocessingTestSuite) TestSystemError() {
 	relPath := "transfer"
 	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
 		FileFormat: ffvalidate.Config{
 			AllowlistPath: "./testdata/allowed_file_formats.csv",
 		},
@@ -201,6 +524,22 @@ func (s *PreprocessingTestSuite) TestSystemError() {
 	sessionCtx := mock.AnythingOfType("*context.timerCtx")
 
 	// Mock activities.
+	s.env.OnActivity(
+		activities.VerifyChecksumsName,
+		sessionCtx,
+		&activities.VerifyChecksumsParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.VerifyChecksumsResult{}, nil,
+	)
+
+	s.env.OnActivity(
+		activities.CheckFilesName,
+		sessionCtx,
+		&activities.CheckFilesParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.CheckFilesResult{Checked: 2}, nil,
+	)
+
 	s.env.OnActivity(
 		ffvalidate.Name,
 		sessionCtx,
@@ -237,14 +576,39 @@ func (s *PreprocessingTestSuite) TestSystemError() {
 			RelativePath: relPath,
 			PreservationTasks: []*eventlog.Event{
 				{
+					Code:        "verify-checksums",
+					Name:        "Verify SIP checksums",
+					MessageCode: "checksums-no-manifests",
+					Message:     "No checksum manifests found",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+				},
+				{
+					Code:        "check-files",
+					Name:        "Check SIP files",
+					MessageCode: "files-valid",
+					Params:      messages.Params{"files": "2"},
+					Message:     "No problems found in 2 files",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+					Children:    fileCheckEvents(s.env.Now().UTC()),
+				},
+				{
+					Code:        "validate-file-formats",
 					Name:        "Validate SIP file formats",
+					MessageCode: "file-formats-valid",
 					Message:     "No disallowed file formats found",
 					Outcome:     enums.EventOutcomeSuccess,
 					StartedAt:   s.env.Now().UTC(),
 					CompletedAt: s.env.Now().UTC(),
 				},
 				{
+					Code:        "bag-sip",
 					Name:        "Bag SIP",
+					MessageCode: "system-error",
+					Params:      messages.Params{"reason": "bag-failed"},
 					Message:     "System error: bagging has failed",
 					Outcome:     enums.EventOutcomeSystemFailure,
 					StartedAt:   s.env.Now().UTC(),
@@ -256,28 +620,71 @@ func (s *PreprocessingTestSuite) TestSystemError() {
 	)
 }
 
-func (s *PreprocessingTestSuite) TestFFValidationError() {
-	relPath := "transfer"
+func (s *PreprocessingTestSuite) TestActivityRetries() {
+	transferFiles := fs.NewDir(s.T(), "",
+		fs.WithFile("allowed_file_formats.csv", allowedFormatsCSV),
+	)
+	relPath := transferFiles.Path()
+
 	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
 		FileFormat: ffvalidate.Config{
-			AllowlistPath: "./testdata/allowed_file_formats.csv",
+			AllowlistPath: transferFiles.Path() + "/allowed_file_formats.csv",
+		},
+		Activities: map[string]config.ActivityConfig{
+			bagcreate.Name: {Retry: config.RetryConfig{MaxAttempts: 2}},
 		},
 	})
 	sessionCtx := mock.AnythingOfType("*context.timerCtx")
 
-	// Mock activities.
+	// Mock activities, failing the first verify-checksums and bag-create
+	// attempts with transient errors.
+	s.env.OnActivity(
+		activities.VerifyChecksumsName,
+		sessionCtx,
+		&activities.VerifyChecksumsParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		nil, fmt.Errorf("verify checksums: read %s: stale NFS file handle", relPath),
+	).Once()
+	s.env.OnActivity(
+		activities.VerifyChecksumsName,
+		sessionCtx,
+		&activities.VerifyChecksumsParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.VerifyChecksumsResult{}, nil,
+	).Once()
+
+	s.env.OnActivity(
+		activities.CheckFilesName,
+		sessionCtx,
+		&activities.CheckFilesParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.CheckFilesResult{Checked: 2}, nil,
+	)
+
 	s.env.OnActivity(
 		ffvalidate.Name,
 		sessionCtx,
 		&ffvalidate.Params{Path: filepath.Join(s.testDir, relPath)},
 	).Return(
-		&ffvalidate.Result{
-			Failures: []string{
-				`file format "fmt/11" not allowed: "test_transfer/content/content/dir/file1.png"`,
-			},
-		}, nil,
+		&ffvalidate.Result{}, nil,
 	)
 
+	s.env.OnActivity(
+		bagcreate.Name,
+		sessionCtx,
+		&bagcreate.Params{SourcePath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		nil, fmt.Errorf("bagcreate: write %s: stale NFS file handle", relPath),
+	).Once()
+	s.env.OnActivity(
+		bagcreate.Name,
+		sessionCtx,
+		&bagcreate.Params{SourcePath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&bagcreate.Result{BagPath: filepath.Join(s.testDir, relPath)}, nil,
+	).Once()
+
 	s.env.ExecuteWorkflow(
 		s.workflow.Execute,
 		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
@@ -288,21 +695,1838 @@ func (s *PreprocessingTestSuite) TestFFValidationError() {
 	var result workflow.PreprocessingWorkflowResult
 	err := s.env.GetWorkflowResult(&result)
 	s.NoError(err)
-	s.Equal(
-		&workflow.PreprocessingWorkflowResult{
-			Outcome:      workflow.OutcomeContentError,
-			RelativePath: relPath,
-			PreservationTasks: []*eventlog.Event{
-				{
-					Name: "Validate SIP file formats",
-					Message: `Content error: file format validation has failed. One or more file formats are not allowed:
-file format "fmt/11" not allowed: "test_transfer/content/content/dir/file1.png"`,
-					Outcome:     enums.EventOutcomeValidationFailure,
-					StartedAt:   s.env.Now().UTC(),
-					CompletedAt: s.env.Now().UTC(),
-				},
-			},
+	s.Equal(workflow.OutcomeSuccess, result.Outcome)
+	s.Equal("Verify SIP checksums", result.PreservationTasks[0].Name)
+	s.Equal(enums.EventOutcomeSuccess, result.PreservationTasks[0].Outcome)
+	s.Equal("Bag SIP", result.PreservationTasks[3].Name)
+	s.Equal(enums.EventOutcomeSuccess, result.PreservationTasks[3].Outcome)
+}
+
+func (s *PreprocessingTestSuite) TestSIPSizeTimeouts() {
+	transferFiles := fs.NewDir(s.T(), "",
+		fs.WithFile("allowed_file_formats.csv", allowedFormatsCSV),
+	)
+	relPath := transferFiles.Path()
+
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: transferFiles.Path() + "/allowed_file_formats.csv",
 		},
-		&result,
+		Activities: map[string]config.ActivityConfig{
+			bagcreate.Name: {Timeout: 2 * time.Hour, TimeoutPerGB: time.Hour},
+		},
+	})
+	sessionCtx := mock.AnythingOfType("*context.timerCtx")
+	sipPath := filepath.Join(s.testDir, relPath)
+
+	// timeout returns the start-to-close timeout of the activity running
+	// with ctx.
+	timeout := func(ctx context.Context) time.Duration {
+		info := temporalsdk_activity.GetInfo(ctx)
+		return info.Deadline.Sub(info.StartedTime).Round(time.Minute)
+	}
+
+	// Mock a 100 GB SIP.
+	s.env.OnActivity(
+		activities.MeasureSIPName,
+		sessionCtx,
+		&activities.MeasureSIPParams{SIPPath: sipPath},
+	).Return(
+		&activities.MeasureSIPResult{Files: 2, Size: 100_000_000_000}, nil,
+	)
+	s.mockValidation(
+		sipPath,
+		&activities.CheckFilesResult{Checked: 2},
+		nil,
+	)
+	s.env.OnActivity(
+		ffvalidate.Name,
+		sessionCtx,
+		&ffvalidate.Params{Path: sipPath},
+	).Return(
+		func(ctx context.Context, _ *ffvalidate.Params) (*ffvalidate.Result, error) {
+			s.Equal(time.Hour+100*10*time.Minute, timeout(ctx))
+			return &ffvalidate.Result{}, nil
+		},
+	)
+	s.env.OnActivity(
+		bagcreate.Name,
+		sessionCtx,
+		&bagcreate.Params{SourcePath: sipPath},
+	).Return(
+		func(ctx context.Context, _ *bagcreate.Params) (*bagcreate.Result, error) {
+			s.Equal(2*time.Hour+100*time.Hour, timeout(ctx))
+			return &bagcreate.Result{BagPath: sipPath}, nil
+		},
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeSuccess, result.Outcome)
+}
+
+func (s *PreprocessingTestSuite) TestPREMISEventNotRetried() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+	sip := fs.NewDir(s.T(), "", fs.WithFile("file.txt", "content"))
+	sipPath := filepath.Join(s.testDir, relPath)
+	s.Require().NoError(os.Rename(sip.Path(), sipPath))
+
+	s.mockValidation(
+		sipPath,
+		&activities.CheckFilesResult{Checked: 1},
+		&ffvalidate.Result{},
+	)
+	s.env.OnActivity(
+		activities.AddPREMISEventName,
+		mock.AnythingOfType("*context.timerCtx"),
+		mock.Anything,
+	).Return(
+		nil, fmt.Errorf("add-premis-event: write premis.xml: i/o timeout"),
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
 	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeSystemError, result.Outcome)
+
+	// Appending to premis.xml isn't retried, it could add the event twice.
+	s.env.AssertActivityNumberOfCalls(s.T(), activities.AddPREMISEventName, 1)
+}
+
+func (s *PreprocessingTestSuite) TestFFValidationError() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+	sessionCtx := mock.AnythingOfType("*context.timerCtx")
+
+	failures := []eventlog.Failure{
+		{
+			Path:    "test_transfer/content/content/dir/file1.png",
+			Check:   "file format",
+			Code:    "format-not-allowed",
+			Params:  messages.Params{"path": "test_transfer/content/content/dir/file1.png", "puid": "fmt/11"},
+			Message: `file format "fmt/11" not allowed: "test_transfer/content/content/dir/file1.png"`,
+			PUID:    "fmt/11",
+		},
+	}
+
+	// Mock activities.
+	s.env.OnActivity(
+		activities.VerifyChecksumsName,
+		sessionCtx,
+		&activities.VerifyChecksumsParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.VerifyChecksumsResult{}, nil,
+	)
+
+	s.env.OnActivity(
+		activities.CheckFilesName,
+		sessionCtx,
+		&activities.CheckFilesParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.CheckFilesResult{Checked: 2}, nil,
+	)
+
+	s.env.OnActivity(
+		ffvalidate.Name,
+		sessionCtx,
+		&ffvalidate.Params{Path: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&ffvalidate.Result{
+			Failures: []string{
+				`file format "fmt/11" not allowed: "test_transfer/content/content/dir/file1.png"`,
+			},
+		}, nil,
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(
+		&workflow.PreprocessingWorkflowResult{
+			Outcome:      workflow.OutcomeContentError,
+			RelativePath: relPath,
+			PreservationTasks: []*eventlog.Event{
+				{
+					Code:        "verify-checksums",
+					Name:        "Verify SIP checksums",
+					MessageCode: "checksums-no-manifests",
+					Message:     "No checksum manifests found",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+				},
+				{
+					Code:        "check-files",
+					Name:        "Check SIP files",
+					MessageCode: "files-valid",
+					Params:      messages.Params{"files": "2"},
+					Message:     "No problems found in 2 files",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+					Children:    fileCheckEvents(s.env.Now().UTC()),
+				},
+				{
+					Code:        "validate-file-formats",
+					Name:        "Validate SIP file formats",
+					MessageCode: "content-error",
+					Params:      messages.Params{"reason": "file-formats-invalid"},
+					Message: `Content error: file format validation has failed. One or more file formats are not allowed:
+file format "fmt/11" not allowed: "test_transfer/content/content/dir/file1.png"`,
+					Outcome:     enums.EventOutcomeValidationFailure,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+					Failures:    failures,
+				},
+			},
+			Failures: failures,
+		},
+		&result,
+	)
+}
+
+func (s *PreprocessingTestSuite) TestFFValidationErrorQuotedPaths() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+
+	// ffvalidate quotes the PUIDs and paths with %q.
+	paths := []string{
+		`transfer/content/"draft" report.png`,
+		`transfer/content/back\slash.png`,
+		"transfer/content/été\tcafé.png",
+	}
+	msgs := make([]string, len(paths))
+	for i, p := range paths {
+		msgs[i] = fmt.Sprintf("file format %q not allowed: %q", "fmt/11", p)
+	}
+
+	s.mockValidation(
+		filepath.Join(s.testDir, relPath),
+		&activities.CheckFilesResult{Checked: 3},
+		&ffvalidate.Result{Failures: msgs},
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeContentError, result.Outcome)
+	s.Len(result.Failures, len(paths))
+	for i, f := range result.Failures {
+		s.Equal(paths[i], f.Path)
+		s.Equal("fmt/11", f.PUID)
+		s.Equal(messages.Params{"path": paths[i], "puid": "fmt/11"}, f.Params)
+		s.Equal(msgs[i], f.Message)
+	}
+}
+
+func (s *PreprocessingTestSuite) TestValidationReport() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+		ValidationReport: config.ValidationReportConfig{Enabled: true},
+	})
+
+	// Mock activities.
+	s.mockValidation(
+		filepath.Join(s.testDir, relPath),
+		&activities.CheckFilesResult{Checked: 2},
+		&ffvalidate.Result{
+			Failures: []string{
+				`file format "fmt/11" not allowed: "test_transfer/content/content/dir/file1.png"`,
+			},
+		},
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath, SIPName: "Acme SIP"},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeContentError, result.Outcome)
+	s.Equal(filepath.Join(s.testDir, "transfer-validation-report.html"), result.ValidationReportPath)
+
+	b, err := os.ReadFile(result.ValidationReportPath)
+	s.NoError(err)
+	s.Contains(string(b), "<h1>Validation report: Acme SIP</h1>")
+	s.Contains(string(b), `<td class="content-error">Content error</td>`)
+	s.Contains(string(b), "<td>test_transfer/content/content/dir/file1.png</td>")
+	s.Contains(string(b), "Convert the file to one of the allowed file formats")
+	s.Contains(string(b), "<tr><td>INTERLIS</td><td>fmt/653</td></tr>")
+}
+
+func (s *PreprocessingTestSuite) TestValidationReportError() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+		ValidationReport: config.ValidationReportConfig{Enabled: true},
+	})
+
+	// Mock activities.
+	s.mockValidation(
+		filepath.Join(s.testDir, relPath),
+		&activities.CheckFilesResult{Checked: 2},
+		&ffvalidate.Result{},
+	)
+
+	s.env.OnActivity(activities.WriteValidationReportName, mock.Anything, mock.Anything).Return(
+		nil, fmt.Errorf("write-validation-report: open %s-validation-report.html: permission denied", relPath),
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath, DryRun: true},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	// A report failure doesn't change the outcome.
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeSuccess, result.Outcome)
+	s.Equal("", result.ValidationReportPath)
+}
+
+func (s *PreprocessingTestSuite) TestWebhooks() {
+	relPath := "transfer"
+	sipID := "0d9e9a7c-8a3e-4d8e-9f6c-2b0d1b7d1c3e"
+	var payloads []webhook.Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		s.NoError(err)
+		if !webhook.Verify("s3cr3t", body, r.Header.Get(webhook.SignatureHeader)) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		var p webhook.Payload
+		s.NoError(json.Unmarshal(body, &p))
+		payloads = append(payloads, p)
+	}))
+	s.T().Cleanup(srv.Close)
+
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+		Webhooks: []webhook.Endpoint{
+			{URL: srv.URL + "/hooks", Secret: "s3cr3t"},
+			{URL: srv.URL + "/invalid", Secret: "invalid"},
+		},
+	})
+
+	// Mock activities.
+	s.mockValidation(
+		filepath.Join(s.testDir, relPath),
+		&activities.CheckFilesResult{Checked: 2},
+		&ffvalidate.Result{},
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath, SIPID: sipID, DryRun: true},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	// The rejected notification doesn't change the outcome.
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeSuccess, result.Outcome)
+	s.Equal(
+		[]webhook.Payload{
+			{
+				WorkflowID:   "default-test-workflow-id",
+				RunID:        "default-test-run-id",
+				SIPID:        sipID,
+				SIPName:      relPath,
+				RelativePath: relPath,
+				Outcome:      "success",
+				Tasks: []webhook.Task{
+					{Code: "verify-checksums", Name: "Verify SIP checksums", Outcome: "success"},
+					{Code: "check-files", Name: "Check SIP files", Outcome: "success"},
+					{Code: "validate-file-formats", Name: "Validate SIP file formats", Outcome: "success"},
+					{Code: "bag-sip", Name: "Bag SIP", Outcome: "skipped"},
+					{Code: "create-premis", Name: "Create premis.xml", Outcome: "skipped"},
+				},
+				CompletedAt: s.env.Now().UTC(),
+			},
+		},
+		payloads,
+	)
+}
+
+func (s *PreprocessingTestSuite) TestAudit() {
+	relPath := "transfer"
+	dbPath := filepath.Join(s.T().TempDir(), "audit.db")
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+		Audit: config.AuditConfig{DatabasePath: dbPath},
+	})
+
+	// Mock activities.
+	s.mockValidation(
+		filepath.Join(s.testDir, relPath),
+		&activities.CheckFilesResult{
+			Checked: 2,
+			Size:    2048,
+			Stats: sipstats.Stats{
+				Files: 2,
+				Size:  2048,
+				Formats: []sipstats.Format{
+					{PUID: "fmt/95", Files: 1, Size: 1536},
+					{PUID: "x-fmt/16", Files: 1, Size: 512},
+				},
+			},
+		},
+		&ffvalidate.Result{},
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath, Producer: "Acme", DryRun: true},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeSuccess, result.Outcome)
+
+	db, err := audit.Open(dbPath)
+	s.Require().NoError(err)
+	defer db.Close()
+
+	runs, err := db.Runs(context.Background(), audit.Filter{})
+	s.NoError(err)
+	s.Len(runs, 1)
+	s.Equal("default-test-workflow-id", runs[0].WorkflowID)
+	s.Equal(relPath, runs[0].SIPName)
+	s.Equal("Acme", runs[0].Producer)
+	s.Equal("en", runs[0].Language)
+	s.True(runs[0].DryRun)
+	s.Equal("success", runs[0].Outcome)
+	s.Equal(2, runs[0].Files)
+	s.Equal(int64(2048), runs[0].Size)
+
+	stats, err := db.Stats(context.Background(), "step", audit.Filter{})
+	s.NoError(err)
+	steps := make([]string, len(stats))
+	for i, st := range stats {
+		steps[i] = st.Key
+	}
+	s.Equal([]string{"bag-sip", "check-files", "create-premis", "validate-file-formats", "verify-checksums"}, steps)
+
+	stats, err = db.Stats(context.Background(), "format", audit.Filter{})
+	s.NoError(err)
+	s.Len(stats, 2)
+	s.Equal("fmt/95", stats[0].Key)
+	s.Equal(int64(1), stats[0].Files)
+	s.Equal(int64(1536), stats[0].Size)
+}
+
+func (s *PreprocessingTestSuite) TestLanguage() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{Language: "fr", Validation: config.ValidationConfig{CheckFiles: true}})
+
+	s.mockValidation(
+		filepath.Join(s.testDir, relPath),
+		&activities.CheckFilesResult{
+			Checked: 2,
+			Failures: []eventlog.Failure{
+				{
+					Path:    "content/empty.txt",
+					Check:   "empty file",
+					Code:    "empty-file",
+					Params:  messages.Params{"path": "content/empty.txt"},
+					Message: `empty file: "content/empty.txt"`,
+				},
+			},
+		},
+		nil,
+	)
+
+	// The SIP language overrides the configured language.
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath, Language: "de"},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeContentError, result.Outcome)
+	s.Equal(
+		[]eventlog.Failure{
+			{
+				Path:    "content/empty.txt",
+				Check:   "empty file",
+				Code:    "empty-file",
+				Params:  messages.Params{"path": "content/empty.txt"},
+				Message: `Leere Datei: "content/empty.txt"`,
+			},
+		},
+		result.Failures,
+	)
+	s.Len(result.PreservationTasks, 2)
+
+	ev := result.PreservationTasks[0]
+	s.Equal("verify-checksums", ev.Code)
+	s.Equal("SIP-Prüfsummen verifizieren", ev.Name)
+	s.Equal("checksums-no-manifests", ev.MessageCode)
+	s.Equal("Keine Prüfsummen-Manifeste gefunden", ev.Message)
+
+	ev = result.PreservationTasks[1]
+	s.Equal("check-files", ev.Code)
+	s.Equal("SIP-Dateien prüfen", ev.Name)
+	s.Equal("content-error", ev.MessageCode)
+	s.Equal(messages.Params{"reason": "files-invalid"}, ev.Params)
+	s.Equal(
+		"Inhaltsfehler: Die Dateiprüfungen sind fehlgeschlagen. Eine oder mehrere Dateien sind leer, "+
+			"haben ungewöhnliche Namen oder veraltete Formate:\n"+
+			`Leere Datei: "content/empty.txt"`,
+		ev.Message,
+	)
+	s.Equal(result.Failures, ev.Failures)
+	s.Equal("check-empty-files", ev.Children[0].Code)
+	s.Equal("Leere Dateien prüfen", ev.Children[0].Name)
+	s.Equal("Gefundene Probleme: 1", ev.Children[0].Message)
+}
+
+func (s *PreprocessingTestSuite) TestSearchAttributes() {
+	relPath := "transfers/sip-1"
+	s.SetupTest(config.Configuration{
+		Temporal:   config.Temporal{SearchAttributes: true},
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+	sipPath := filepath.Join(s.testDir, relPath)
+
+	s.mockValidation(
+		sipPath,
+		&activities.CheckFilesResult{Checked: 2, Size: 1024},
+		&ffvalidate.Result{Failures: []string{`file format "fmt/11" not allowed: "file1.png"`}},
+	)
+
+	s.env.OnUpsertTypedSearchAttributes(temporalsdk_temporal.NewSearchAttributes(
+		workflow.SIPNameAttribute.ValueSet("sip-1"),
+		workflow.ProducerAttribute.ValueSet("Acme"),
+	)).Return(nil).Once()
+	s.env.OnUpsertTypedSearchAttributes(temporalsdk_temporal.NewSearchAttributes(
+		workflow.FileCountAttribute.ValueSet(2),
+		workflow.TotalSizeAttribute.ValueSet(1024),
+	)).Return(nil).Once()
+	s.env.OnUpsertTypedSearchAttributes(temporalsdk_temporal.NewSearchAttributes(
+		workflow.OutcomeAttribute.ValueSet("content error"),
+		workflow.FailedStepAttribute.ValueSet("Validate SIP file formats"),
+	)).Return(nil).Once()
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath, Producer: "Acme"},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+func (s *PreprocessingTestSuite) TestSearchAttributesDisabled() {
+	relPath := "transfers/sip-1"
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+	sipPath := filepath.Join(s.testDir, relPath)
+
+	s.mockValidation(
+		sipPath,
+		&activities.CheckFilesResult{Checked: 2, Size: 1024},
+		&ffvalidate.Result{Failures: []string{`file format "fmt/11" not allowed: "file1.png"`}},
+	)
+	s.env.OnUpsertTypedSearchAttributes(mock.Anything).Never()
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath, Producer: "Acme"},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+func (s *PreprocessingTestSuite) TestSIPMetadata() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+	sip := fs.NewDir(s.T(), "", fs.WithFile("file.txt", "content"))
+	sipPath := filepath.Join(s.testDir, relPath)
+	s.Require().NoError(os.Rename(sip.Path(), sipPath))
+
+	s.mockValidation(
+		sipPath,
+		&activities.CheckFilesResult{Checked: 1},
+		&ffvalidate.Result{},
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{
+			RelativePath:    relPath,
+			SIPID:           "c1f5b2a0-7c6a-4c8a-9a49-8f2a5a0e5b1a",
+			SIPName:         "Annual reports",
+			Producer:        "Acme",
+			AccessionNumber: "2024-001",
+		},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeSuccess, result.Outcome)
+
+	bagInfo, err := os.ReadFile(filepath.Join(sipPath, "bag-info.txt"))
+	s.Require().NoError(err)
+	s.Contains(string(bagInfo), `Source-Organization: Acme
+External-Identifier: c1f5b2a0-7c6a-4c8a-9a49-8f2a5a0e5b1a
+Internal-Sender-Identifier: 2024-001
+Internal-Sender-Description: Annual reports
+`)
+
+	premisXML, err := os.ReadFile(filepath.Join(sipPath, "metadata", "premis.xml"))
+	s.Require().NoError(err)
+	s.Contains(
+		string(premisXML),
+		"<premis:objectIdentifierValue>c1f5b2a0-7c6a-4c8a-9a49-8f2a5a0e5b1a</premis:objectIdentifierValue>",
+	)
+	s.Contains(string(premisXML), "<premis:objectIdentifierValue>2024-001</premis:objectIdentifierValue>")
+	s.Contains(string(premisXML), "<premis:originalName>Annual reports</premis:originalName>")
+	s.Contains(string(premisXML), "<premis:agentName>Acme</premis:agentName>")
+
+	// The preprocessing log is bagged with the SIP.
+	logJSON, err := os.ReadFile(filepath.Join(sipPath, "data", activities.PreprocessingLogPath))
+	s.Require().NoError(err)
+	var log eventlog.Log
+	s.Require().NoError(json.Unmarshal(logJSON, &log))
+	s.Equal("c1f5b2a0-7c6a-4c8a-9a49-8f2a5a0e5b1a", log.SIPID)
+	s.Equal("test", log.WorkerVersion)
+	s.Len(log.Events, 3)
+	s.Equal("Validate SIP file formats", log.Events[2].Name)
+}
+
+// writeSigningKey writes a PEM encoded Ed25519 signing key to a temporary
+// file and returns the key and the file path.
+func (s *PreprocessingTestSuite) writeSigningKey() (ed25519.PrivateKey, string) {
+	key := ed25519.NewKeyFromSeed([]byte("0123456789abcdef0123456789abcdef"))
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	s.Require().NoError(err)
+	keyPath := filepath.Join(s.T().TempDir(), "signing.pem")
+	s.Require().NoError(os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	return key, keyPath
+}
+
+func (s *PreprocessingTestSuite) TestSigning() {
+	relPath := "transfer"
+	key, keyPath := s.writeSigningKey()
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+		Signing: config.SigningConfig{KeyPath: keyPath},
+	})
+	sip := fs.NewDir(s.T(), "", fs.WithFile("file.txt", "content"))
+	sipPath := filepath.Join(s.testDir, relPath)
+	s.Require().NoError(os.Rename(sip.Path(), sipPath))
+
+	s.mockValidation(
+		sipPath,
+		&activities.CheckFilesResult{Checked: 1},
+		&ffvalidate.Result{},
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{
+			RelativePath: relPath,
+			SIPID:        "c1f5b2a0-7c6a-4c8a-9a49-8f2a5a0e5b1a",
+		},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeSuccess, result.Outcome)
+
+	pub := key.Public().(ed25519.PublicKey)
+	ev := result.PreservationTasks[len(result.PreservationTasks)-1]
+	s.Equal("sign-sip", ev.Code)
+	s.Equal(enums.EventOutcomeSuccess, ev.Outcome)
+	s.Equal("Signed the tag manifests and premis.xml with key "+signature.KeyID(pub), ev.Message)
+
+	premisXML, err := os.ReadFile(filepath.Join(sipPath, "metadata", "premis.xml"))
+	s.Require().NoError(err)
+	s.Contains(string(premisXML), "<premis:eventType>digital signature generation</premis:eventType>")
+
+	sig, err := signature.Verify(sipPath, pub)
+	s.Require().NoError(err)
+	s.Equal("metadata/premis.xml", sig.Files[0].Path)
+}
+
+func (s *PreprocessingTestSuite) TestSigningError() {
+	relPath := "transfer"
+	_, keyPath := s.writeSigningKey()
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+		Signing: config.SigningConfig{KeyPath: keyPath},
+	})
+	sip := fs.NewDir(s.T(), "", fs.WithFile("file.txt", "content"))
+	sipPath := filepath.Join(s.testDir, relPath)
+	s.Require().NoError(os.Rename(sip.Path(), sipPath))
+
+	s.mockValidation(
+		sipPath,
+		&activities.CheckFilesResult{Checked: 1},
+		&ffvalidate.Result{},
+	)
+	s.env.OnActivity(
+		activities.SignSIPName,
+		mock.AnythingOfType("*context.timerCtx"),
+		&activities.SignSIPParams{BagPath: sipPath},
+	).Return(
+		nil, fmt.Errorf("sign-sip: no tag manifest found"),
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeSystemError, result.Outcome)
+
+	ev := result.PreservationTasks[len(result.PreservationTasks)-1]
+	s.Equal("sign-sip", ev.Code)
+	s.Equal(enums.EventOutcomeSystemFailure, ev.Outcome)
+
+	// The signature is only recorded in premis.xml once the SIP is signed.
+	premisXML, err := os.ReadFile(filepath.Join(sipPath, "metadata", "premis.xml"))
+	s.Require().NoError(err)
+	s.NotContains(string(premisXML), "digital signature generation")
+}
+
+func (s *PreprocessingTestSuite) TestProfile() {
+	relPath := "transfer"
+	cfg := config.Configuration{
+		DefaultProfile: "default",
+		Profiles: map[string]config.ProfileConfig{
+			"default": {},
+			"acme": {
+				FileFormat:    ffvalidate.Config{AllowlistPath: "./testdata/allowed_file_formats.csv"},
+				Bagit:         bagcreate.Config{ChecksumAlgorithm: "sha256"},
+				Structure:     config.StructureConfig{RequiredPaths: []string{"metadata/*.xml"}},
+				Checks:        config.ChecksConfig{Structure: config.CheckModeWarn},
+				DisabledSteps: []string{config.StepChecksums, config.StepFiles},
+			},
+		},
+	}
+	s.SetupTest(cfg)
+	sessionCtx := mock.AnythingOfType("*context.timerCtx")
+	sipPath := filepath.Join(s.testDir, relPath)
+
+	s.env.OnActivity(activities.VerifyChecksumsName, mock.Anything, mock.Anything).Never()
+	s.env.OnActivity(activities.CheckFilesName, mock.Anything, mock.Anything).Never()
+	s.env.OnActivity(
+		activities.ValidateStructureName,
+		sessionCtx,
+		&activities.ValidateStructureParams{SIPPath: sipPath, RequiredPaths: []string{"metadata/*.xml"}},
+	).Return(
+		&activities.ValidateStructureResult{
+			Failures: []eventlog.Failure{
+				{Check: "structure", Code: "missing-required-path", Message: `missing required path: "metadata/*.xml"`},
+			},
+		}, nil,
+	)
+	s.env.OnActivity(
+		workflow.ProfileActivityName(ffvalidate.Name, "acme"),
+		sessionCtx,
+		&ffvalidate.Params{Path: sipPath},
+	).Return(
+		&ffvalidate.Result{}, nil,
+	)
+	s.env.OnActivity(
+		workflow.ProfileActivityName(bagcreate.Name, "acme"),
+		sessionCtx,
+		&bagcreate.Params{SourcePath: sipPath},
+	).Return(
+		&bagcreate.Result{BagPath: sipPath}, nil,
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath, Profile: "acme"},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeSuccessWithWarnings, result.Outcome)
+	s.Len(result.PreservationTasks, 4)
+	s.Equal("Validate SIP structure", result.PreservationTasks[0].Name)
+	s.Equal(enums.EventOutcomeWarning, result.PreservationTasks[0].Outcome)
+	s.Equal("Validate SIP file formats", result.PreservationTasks[1].Name)
+	s.Equal("Bag SIP", result.PreservationTasks[2].Name)
+	s.Equal("Create premis.xml", result.PreservationTasks[3].Name)
+
+	// The preprocessing log identifies the configuration of the profile.
+	logJSON, err := os.ReadFile(filepath.Join(sipPath, activities.PreprocessingLogPath))
+	s.Require().NoError(err)
+	var log eventlog.Log
+	s.Require().NoError(json.Unmarshal(logJSON, &log))
+	cfg.SharedPath = s.testDir
+	profileCfg, err := cfg.WithProfile("acme")
+	s.Require().NoError(err)
+	s.Equal(profileCfg.Fingerprint(), log.ConfigFingerprint)
+	s.NotEqual(cfg.Fingerprint(), log.ConfigFingerprint)
+}
+
+func (s *PreprocessingTestSuite) TestUnknownProfileError() {
+	s.SetupTest(config.Configuration{Profiles: map[string]config.ProfileConfig{"acme": {}}})
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: "transfer", Profile: "other"},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.ErrorContains(
+		s.env.GetWorkflowError(),
+		`error calling workflow with unexpected inputs: unknown profile: "other"`,
+	)
+}
+
+func (s *PreprocessingTestSuite) TestChecksumValidationError() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		Fixity: config.FixityConfig{ManifestNames: []string{"checksums.md5"}},
+	})
+	sessionCtx := mock.AnythingOfType("*context.timerCtx")
+	failures := []eventlog.Failure{
+		{
+			Path:    "content/file1.txt",
+			Check:   "checksum",
+			Code:    "checksum-mismatch",
+			Params:  messages.Params{"algorithm": "md5", "path": "content/file1.txt"},
+			Message: `md5 checksum mismatch: "content/file1.txt"`,
+		},
+		{
+			Path:    "content/file2.txt",
+			Check:   "checksum",
+			Code:    "checksum-file-unlisted",
+			Params:  messages.Params{"path": "content/file2.txt"},
+			Message: `file not listed in any checksum manifest: "content/file2.txt"`,
+		},
+	}
+
+	// Mock activities.
+	s.env.OnActivity(
+		activities.VerifyChecksumsName,
+		sessionCtx,
+		&activities.VerifyChecksumsParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.VerifyChecksumsResult{
+			Manifests: []string{"checksums.md5"},
+			Failures:  failures,
+		}, nil,
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(
+		&workflow.PreprocessingWorkflowResult{
+			Outcome:      workflow.OutcomeContentError,
+			RelativePath: relPath,
+			PreservationTasks: []*eventlog.Event{
+				{
+					Code:        "verify-checksums",
+					Name:        "Verify SIP checksums",
+					MessageCode: "content-error",
+					Params:      messages.Params{"reason": "checksums-mismatch"},
+					Message: `Content error: checksum verification has failed. One or more files don't match the checksum manifests:
+md5 checksum mismatch: "content/file1.txt"
+file not listed in any checksum manifest: "content/file2.txt"`,
+					Outcome:     enums.EventOutcomeValidationFailure,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+					Failures:    failures,
+				},
+			},
+			Failures: failures,
+		},
+		&result,
+	)
+}
+
+func (s *PreprocessingTestSuite) TestVirusFound() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		ClamAV: clamd.Config{Address: "tcp://localhost:3310"},
+	})
+	sessionCtx := mock.AnythingOfType("*context.timerCtx")
+	failures := []eventlog.Failure{
+		{
+			Path:    "content/eicar.com",
+			Check:   "virus",
+			Code:    "virus-found",
+			Params:  messages.Params{"path": "content/eicar.com", "signature": "Win.Test.EICAR_HDB-1"},
+			Message: `virus "Win.Test.EICAR_HDB-1" found: "content/eicar.com"`,
+		},
+	}
+
+	// Mock activities.
+	s.env.OnActivity(
+		activities.VerifyChecksumsName,
+		sessionCtx,
+		&activities.VerifyChecksumsParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.VerifyChecksumsResult{}, nil,
+	)
+
+	s.env.OnActivity(
+		activities.ScanVirusesName,
+		sessionCtx,
+		&activities.ScanVirusesParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.ScanVirusesResult{
+			Scanned:  2,
+			Failures: failures,
+		}, nil,
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(
+		&workflow.PreprocessingWorkflowResult{
+			Outcome:      workflow.OutcomeContentError,
+			RelativePath: relPath,
+			PreservationTasks: []*eventlog.Event{
+				{
+					Code:        "verify-checksums",
+					Name:        "Verify SIP checksums",
+					MessageCode: "checksums-no-manifests",
+					Message:     "No checksum manifests found",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+				},
+				{
+					Code:        "scan-viruses",
+					Name:        "Scan SIP for viruses",
+					MessageCode: "content-error",
+					Params:      messages.Params{"reason": "viruses-found"},
+					Message: `Content error: virus scan has failed. One or more files are infected or too large to scan:
+virus "Win.Test.EICAR_HDB-1" found: "content/eicar.com"`,
+					Outcome:     enums.EventOutcomeValidationFailure,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+					Failures:    failures,
+				},
+			},
+			Failures: failures,
+		},
+		&result,
+	)
+}
+
+func (s *PreprocessingTestSuite) TestCollectAllValidationErrors() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true, CollectAll: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+	sessionCtx := mock.AnythingOfType("*context.timerCtx")
+	checksumFailures := []eventlog.Failure{
+		{
+			Path:    "content/file1.txt",
+			Check:   "checksum",
+			Code:    "checksum-mismatch",
+			Params:  messages.Params{"algorithm": "md5", "path": "content/file1.txt"},
+			Message: `md5 checksum mismatch: "content/file1.txt"`,
+		},
+	}
+	formatFailures := []eventlog.Failure{
+		{
+			Path:    "content/file2.png",
+			Check:   "file format",
+			Code:    "format-not-allowed",
+			Params:  messages.Params{"path": "content/file2.png", "puid": "fmt/11"},
+			Message: `file format "fmt/11" not allowed: "content/file2.png"`,
+			PUID:    "fmt/11",
+		},
+	}
+
+	// Mock activities.
+	s.env.OnActivity(
+		activities.VerifyChecksumsName,
+		sessionCtx,
+		&activities.VerifyChecksumsParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.VerifyChecksumsResult{
+			Manifests: []string{"checksums.md5"},
+			Failures:  checksumFailures,
+		}, nil,
+	)
+
+	s.env.OnActivity(
+		activities.CheckFilesName,
+		sessionCtx,
+		&activities.CheckFilesParams{SIPPath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&activities.CheckFilesResult{Checked: 2}, nil,
+	)
+
+	s.env.OnActivity(
+		ffvalidate.Name,
+		sessionCtx,
+		&ffvalidate.Params{Path: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&ffvalidate.Result{
+			Failures: []string{`file format "fmt/11" not allowed: "content/file2.png"`},
+		}, nil,
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(
+		&workflow.PreprocessingWorkflowResult{
+			Outcome:      workflow.OutcomeContentError,
+			RelativePath: relPath,
+			PreservationTasks: []*eventlog.Event{
+				{
+					Code:        "verify-checksums",
+					Name:        "Verify SIP checksums",
+					MessageCode: "content-error",
+					Params:      messages.Params{"reason": "checksums-mismatch"},
+					Message: `Content error: checksum verification has failed. One or more files don't match the checksum manifests:
+md5 checksum mismatch: "content/file1.txt"`,
+					Outcome:     enums.EventOutcomeValidationFailure,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+					Failures:    checksumFailures,
+				},
+				{
+					Code:        "check-files",
+					Name:        "Check SIP files",
+					MessageCode: "files-valid",
+					Params:      messages.Params{"files": "2"},
+					Message:     "No problems found in 2 files",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+					Children:    fileCheckEvents(s.env.Now().UTC()),
+				},
+				{
+					Code:        "validate-file-formats",
+					Name:        "Validate SIP file formats",
+					MessageCode: "content-error",
+					Params:      messages.Params{"reason": "file-formats-invalid"},
+					Message: `Content error: file format validation has failed. One or more file formats are not allowed:
+file format "fmt/11" not allowed: "content/file2.png"`,
+					Outcome:     enums.EventOutcomeValidationFailure,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+					Failures:    formatFailures,
+				},
+			},
+			Failures: append(checksumFailures, formatFailures...),
+		},
+		&result,
+	)
+}
+
+func (s *PreprocessingTestSuite) TestDryRun() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+
+	// Mock activities. Bagging and PREMIS activities must not run.
+	s.mockValidation(
+		filepath.Join(s.testDir, relPath),
+		&activities.CheckFilesResult{Checked: 2},
+		&ffvalidate.Result{},
+	)
+
+	s.env.OnActivity(bagcreate.Name, mock.Anything, mock.Anything).Never()
+	s.env.OnActivity(activities.AddPREMISObjectsName, mock.Anything, mock.Anything).Never()
+	s.env.OnActivity(activities.AddPREMISEventName, mock.Anything, mock.Anything).Never()
+	s.env.OnActivity(activities.AddPREMISAgentName, mock.Anything, mock.Anything).Never()
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath, DryRun: true},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(
+		&workflow.PreprocessingWorkflowResult{
+			Outcome:      workflow.OutcomeSuccess,
+			RelativePath: relPath,
+			DryRun:       true,
+			PreservationTasks: []*eventlog.Event{
+				{
+					Code:        "verify-checksums",
+					Name:        "Verify SIP checksums",
+					MessageCode: "checksums-no-manifests",
+					Message:     "No checksum manifests found",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+				},
+				{
+					Code:        "check-files",
+					Name:        "Check SIP files",
+					MessageCode: "files-valid",
+					Params:      messages.Params{"files": "2"},
+					Message:     "No problems found in 2 files",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+					Children:    fileCheckEvents(s.env.Now().UTC()),
+				},
+				{
+					Code:        "validate-file-formats",
+					Name:        "Validate SIP file formats",
+					MessageCode: "file-formats-valid",
+					Message:     "No disallowed file formats found",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+				},
+				{
+					Code:        "bag-sip",
+					Name:        "Bag SIP",
+					MessageCode: "dry-run-bag",
+					Message:     "Dry run: SIP would have been bagged",
+					Outcome:     enums.EventOutcomeSkipped,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+				},
+				{
+					Code:        "create-premis",
+					Name:        "Create premis.xml",
+					MessageCode: "dry-run-premis",
+					Params:      messages.Params{"events": "3"},
+					Message:     "Dry run: a premis.xml with 3 events would have been stored in metadata directory",
+					Outcome:     enums.EventOutcomeSkipped,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+				},
+			},
+		},
+		&result,
+	)
+}
+
+func (s *PreprocessingTestSuite) TestQuarantine() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		Validation:     config.ValidationConfig{CheckFiles: true},
+		QuarantinePath: "/home/preprocessing/quarantine",
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+	sessionCtx := mock.AnythingOfType("*context.timerCtx")
+	params := &workflow.PreprocessingWorkflowParams{RelativePath: relPath, AccessionNumber: "2024-001"}
+	encodedParams, err := json.Marshal(params)
+	s.Require().NoError(err)
+	failures := []eventlog.Failure{
+		{
+			Path:    "content/file1.png",
+			Check:   "file format",
+			Code:    "format-not-allowed",
+			Params:  messages.Params{"path": "content/file1.png", "puid": "fmt/11"},
+			Message: `file format "fmt/11" not allowed: "content/file1.png"`,
+			PUID:    "fmt/11",
+		},
+	}
+	validationTasks := []*eventlog.Event{
+		{
+			Code:        "verify-checksums",
+			Name:        "Verify SIP checksums",
+			MessageCode: "checksums-no-manifests",
+			Message:     "No checksum manifests found",
+			Outcome:     enums.EventOutcomeSuccess,
+			StartedAt:   s.env.Now().UTC(),
+			CompletedAt: s.env.Now().UTC(),
+		},
+		{
+			Code:        "check-files",
+			Name:        "Check SIP files",
+			MessageCode: "files-valid",
+			Params:      messages.Params{"files": "2"},
+			Message:     "No problems found in 2 files",
+			Outcome:     enums.EventOutcomeSuccess,
+			StartedAt:   s.env.Now().UTC(),
+			CompletedAt: s.env.Now().UTC(),
+			Children:    fileCheckEvents(s.env.Now().UTC()),
+		},
+		{
+			Code:        "validate-file-formats",
+			Name:        "Validate SIP file formats",
+			MessageCode: "content-error",
+			Params:      messages.Params{"reason": "file-formats-invalid"},
+			Message: `Content error: file format validation has failed. One or more file formats are not allowed:
+file format "fmt/11" not allowed: "content/file1.png"`,
+			Outcome:     enums.EventOutcomeValidationFailure,
+			StartedAt:   s.env.Now().UTC(),
+			CompletedAt: s.env.Now().UTC(),
+			Failures:    failures,
+		},
+	}
+
+	// Mock activities.
+	s.mockValidation(
+		filepath.Join(s.testDir, relPath),
+		&activities.CheckFilesResult{Checked: 2},
+		&ffvalidate.Result{
+			Failures: []string{`file format "fmt/11" not allowed: "content/file1.png"`},
+		},
+	)
+
+	s.env.OnActivity(
+		activities.QuarantineSIPName,
+		sessionCtx,
+		&activities.QuarantineSIPParams{
+			SIPPath: filepath.Join(s.testDir, relPath),
+			Report: quarantine.Report{
+				ID:                "default-test-workflow-id",
+				RelativePath:      relPath,
+				Outcome:           "content error",
+				QuarantinedAt:     s.env.Now().UTC(),
+				Failures:          failures,
+				PreservationTasks: validationTasks,
+				Params:            encodedParams,
+			},
+		},
+	).Return(
+		&activities.QuarantineSIPResult{
+			Path: "/home/preprocessing/quarantine/default-test-workflow-id/transfer",
+		}, nil,
+	)
+
+	s.env.ExecuteWorkflow(s.workflow.Execute, params)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err = s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(
+		&workflow.PreprocessingWorkflowResult{
+			Outcome:      workflow.OutcomeContentError,
+			RelativePath: relPath,
+			PreservationTasks: append(validationTasks, &eventlog.Event{
+				Code:        "quarantine-sip",
+				Name:        "Quarantine SIP",
+				MessageCode: "quarantined",
+				Message:     "SIP has been moved to quarantine",
+				Outcome:     enums.EventOutcomeSuccess,
+				StartedAt:   s.env.Now().UTC(),
+				CompletedAt: s.env.Now().UTC(),
+			}),
+			Failures:       failures,
+			QuarantinePath: "/home/preprocessing/quarantine/default-test-workflow-id/transfer",
+		},
+		&result,
+	)
+}
+
+func (s *PreprocessingTestSuite) TestWarnings() {
+	transferFiles := fs.NewDir(s.T(), "",
+		fs.WithFile("allowed_file_formats.csv", allowedFormatsCSV),
+	)
+
+	relPath := transferFiles.Path()
+
+	s.SetupTest(config.Configuration{
+		QuarantinePath: "/home/preprocessing/quarantine",
+		Validation: config.ValidationConfig{
+			CheckFiles: true,
+			Checks: config.ChecksConfig{
+				FileFormats: config.CheckModeWarn,
+				EmptyFiles:  config.CheckModeWarn,
+			},
+		},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: transferFiles.Path() + "/allowed_file_formats.csv",
+		},
+	})
+	sessionCtx := mock.AnythingOfType("*context.timerCtx")
+	fileWarnings := []eventlog.Failure{
+		{
+			Path:    "content/empty.txt",
+			Check:   "empty file",
+			Code:    "empty-file",
+			Params:  messages.Params{"path": "content/empty.txt"},
+			Message: `empty file: "content/empty.txt"`,
+		},
+	}
+	fileWarningEvents := fileCheckEvents(s.env.Now().UTC())
+	fileWarningEvents[0].Outcome = enums.EventOutcomeWarning
+	fileWarningEvents[0].MessageCode = "file-check-invalid"
+	fileWarningEvents[0].Params = messages.Params{"count": "1"}
+	fileWarningEvents[0].Message = "Problems found: 1"
+	formatWarnings := []eventlog.Failure{
+		{
+			Path:    "content/file1.png",
+			Check:   "file format",
+			Code:    "format-not-allowed",
+			Params:  messages.Params{"path": "content/file1.png", "puid": "fmt/11"},
+			Message: `file format "fmt/11" not allowed: "content/file1.png"`,
+			PUID:    "fmt/11",
+		},
+	}
+
+	// Mock activities. Warnings must not quarantine the SIP.
+	s.mockValidation(
+		filepath.Join(s.testDir, relPath),
+		&activities.CheckFilesResult{Checked: 2, Failures: fileWarnings},
+		&ffvalidate.Result{
+			Failures: []string{`file format "fmt/11" not allowed: "content/file1.png"`},
+		},
+	)
+
+	s.env.OnActivity(
+		bagcreate.Name,
+		sessionCtx,
+		&bagcreate.Params{SourcePath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&bagcreate.Result{BagPath: filepath.Join(s.testDir, relPath)},
+		nil,
+	)
+
+	s.env.OnActivity(activities.QuarantineSIPName, mock.Anything, mock.Anything).Never()
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(
+		&workflow.PreprocessingWorkflowResult{
+			Outcome:      workflow.OutcomeSuccessWithWarnings,
+			RelativePath: relPath,
+			PreservationTasks: []*eventlog.Event{
+				{
+					Code:        "verify-checksums",
+					Name:        "Verify SIP checksums",
+					MessageCode: "checksums-no-manifests",
+					Message:     "No checksum manifests found",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+				},
+				{
+					Code:        "check-files",
+					Name:        "Check SIP files",
+					MessageCode: "warning",
+					Params:      messages.Params{"reason": "files-invalid"},
+					Message: `Warning: file checks have failed. One or more files are empty, have unusual names or deprecated formats:
+empty file: "content/empty.txt"`,
+					Outcome:     enums.EventOutcomeWarning,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+					Failures:    fileWarnings,
+					Children:    fileWarningEvents,
+				},
+				{
+					Code:        "validate-file-formats",
+					Name:        "Validate SIP file formats",
+					MessageCode: "warning",
+					Params:      messages.Params{"reason": "file-formats-invalid"},
+					Message: `Warning: file format validation has failed. One or more file formats are not allowed:
+file format "fmt/11" not allowed: "content/file1.png"`,
+					Outcome:     enums.EventOutcomeWarning,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+					Failures:    formatWarnings,
+				},
+				{
+					Code:        "bag-sip",
+					Name:        "Bag SIP",
+					MessageCode: "bag-created",
+					Message:     "SIP has been bagged",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+				},
+				{
+					Code:        "create-premis",
+					Name:        "Create premis.xml",
+					MessageCode: "premis-created",
+					Message:     "Created a premis.xml and stored in metadata directory",
+					Outcome:     enums.EventOutcomeSuccess,
+					StartedAt:   s.env.Now().UTC(),
+					CompletedAt: s.env.Now().UTC(),
+				},
+			},
+			Warnings: append(fileWarnings, formatWarnings...),
+		},
+		&result,
+	)
+}
+
+func (s *PreprocessingTestSuite) TestProgressQueries() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{CheckFiles: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+	sessionCtx := mock.AnythingOfType("*context.timerCtx")
+
+	// Mock activities. File format validation takes a minute to let the
+	// progress be queried while it runs.
+	s.mockValidation(
+		filepath.Join(s.testDir, relPath),
+		&activities.CheckFilesResult{Checked: 2},
+		nil,
+	)
+
+	s.env.OnActivity(
+		ffvalidate.Name,
+		sessionCtx,
+		&ffvalidate.Params{Path: filepath.Join(s.testDir, relPath)},
+	).After(time.Minute).Return(
+		&ffvalidate.Result{
+			Failures: []string{`file format "fmt/11" not allowed: "content/file1.png"`},
+		}, nil,
+	)
+
+	s.env.RegisterDelayedCallback(func() {
+		res, err := s.env.QueryWorkflow(workflow.ProgressQuery)
+		s.Require().NoError(err)
+
+		var progress workflow.Progress
+		s.Require().NoError(res.Get(&progress))
+		s.Equal("Validate SIP file formats", progress.CurrentStep)
+		s.Equal(2, progress.CompletedSteps)
+		s.Equal(3, progress.RemainingSteps)
+		s.Equal(2, progress.FilesProcessed)
+		s.Len(progress.PreservationTasks, 3)
+	}, 30*time.Second)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	res, err := s.env.QueryWorkflow(workflow.ProgressQuery)
+	s.Require().NoError(err)
+
+	var progress workflow.Progress
+	s.Require().NoError(res.Get(&progress))
+	s.Equal("", progress.CurrentStep)
+	s.Equal(3, progress.CompletedSteps)
+	s.Equal(0, progress.RemainingSteps)
+	s.Equal(2, progress.FilesProcessed)
+
+	res, err = s.env.QueryWorkflow(workflow.PreservationTasksQuery)
+	s.Require().NoError(err)
+
+	var tasks []*eventlog.Event
+	s.Require().NoError(res.Get(&tasks))
+	s.Equal(progress.PreservationTasks, tasks)
+}
+
+// updateCallbacks records the outcome of a workflow update.
+type updateCallbacks struct {
+	rejected error
+	err      error
+}
+
+func (c *updateCallbacks) Accept()                   {}
+func (c *updateCallbacks) Reject(err error)          { c.rejected = err }
+func (c *updateCallbacks) Complete(_ any, err error) { c.err = err }
+
+func (s *PreprocessingTestSuite) mockReviewActivities(relPath string) {
+	s.mockValidation(
+		filepath.Join(s.testDir, relPath),
+		&activities.CheckFilesResult{
+			Checked: 2,
+			Failures: []eventlog.Failure{
+				{
+					Path:    "content/empty.txt",
+					Check:   "empty file",
+					Code:    "empty-file",
+					Params:  messages.Params{"path": "content/empty.txt"},
+					Message: `empty file: "content/empty.txt"`,
+				},
+			},
+		},
+		&ffvalidate.Result{},
+	)
+}
+
+func (s *PreprocessingTestSuite) TestReviewApproved() {
+	transferFiles := fs.NewDir(s.T(), "",
+		fs.WithFile("allowed_file_formats.csv", allowedFormatsCSV),
+	)
+	relPath := transferFiles.Path()
+
+	s.SetupTest(config.Configuration{
+		Validation: config.ValidationConfig{
+			CheckFiles: true,
+			Checks:     config.ChecksConfig{EmptyFiles: config.CheckModeWarn},
+		},
+		Review: config.ReviewConfig{Enabled: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: transferFiles.Path() + "/allowed_file_formats.csv",
+		},
+	})
+	s.mockReviewActivities(relPath)
+	s.env.OnActivity(
+		bagcreate.Name,
+		mock.AnythingOfType("*context.timerCtx"),
+		&bagcreate.Params{SourcePath: filepath.Join(s.testDir, relPath)},
+	).Return(
+		&bagcreate.Result{BagPath: filepath.Join(s.testDir, relPath)},
+		nil,
+	)
+
+	var invalid, valid updateCallbacks
+	s.env.RegisterDelayedCallback(func() {
+		res, err := s.env.QueryWorkflow(workflow.ReviewQuery)
+		s.Require().NoError(err)
+
+		var review workflow.Review
+		s.Require().NoError(res.Get(&review))
+		s.True(review.Pending)
+		s.Len(review.Findings, 1)
+		s.True(review.Deadline.IsZero())
+
+		s.env.UpdateWorkflow(workflow.ReviewUpdate, "1", &invalid, workflow.ReviewDecision{Approved: true})
+		s.env.UpdateWorkflow(workflow.ReviewUpdate, "2", &valid, workflow.ReviewDecision{
+			Approved: true,
+			Reviewer: "Jane",
+			Comment:  "Empty file expected",
+		})
+	}, time.Hour)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.EqualError(invalid.rejected, "reviewer is required")
+	s.NoError(valid.rejected)
+	s.NoError(valid.err)
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeSuccessWithWarnings, result.Outcome)
+	s.Len(result.PreservationTasks, 6)
+
+	review := result.PreservationTasks[3]
+	s.Equal("Review SIP", review.Name)
+	s.Equal("SIP approved by Jane: Empty file expected", review.Message)
+	s.Equal(enums.EventOutcomeSuccess, review.Outcome)
+	s.Equal(time.Hour, review.CompletedAt.Sub(review.StartedAt))
+}
+
+func (s *PreprocessingTestSuite) TestReviewTimeout() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		QuarantinePath: "/home/preprocessing/quarantine",
+		Validation: config.ValidationConfig{
+			CheckFiles: true,
+			Checks:     config.ChecksConfig{EmptyFiles: config.CheckModeWarn},
+		},
+		Review: config.ReviewConfig{
+			Enabled:         true,
+			Timeout:         24 * time.Hour,
+			TimeoutDecision: config.ReviewDecisionReject,
+		},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+	s.mockReviewActivities(relPath)
+	s.env.OnActivity(bagcreate.Name, mock.Anything, mock.Anything).Never()
+	s.env.OnActivity(
+		activities.QuarantineSIPName,
+		mock.AnythingOfType("*context.timerCtx"),
+		mock.AnythingOfType("*activities.QuarantineSIPParams"),
+	).Return(
+		&activities.QuarantineSIPResult{
+			Path: "/home/preprocessing/quarantine/default-test-workflow-id/transfer",
+		}, nil,
+	)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeContentError, result.Outcome)
+	s.Equal("/home/preprocessing/quarantine/default-test-workflow-id/transfer", result.QuarantinePath)
+	s.Len(result.PreservationTasks, 5)
+
+	review := result.PreservationTasks[3]
+	s.Equal("Review SIP", review.Name)
+	s.Equal("Content error: SIP rejected automatically: review timed out after 24h0m0s", review.Message)
+	s.Equal(enums.EventOutcomeValidationFailure, review.Outcome)
+	s.Equal(24*time.Hour, review.CompletedAt.Sub(review.StartedAt))
+}
+
+func (s *PreprocessingTestSuite) TestCancelledBeforeBagging() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		QuarantinePath: "/home/preprocessing/quarantine",
+		Validation: config.ValidationConfig{
+			CheckFiles: true,
+			Checks:     config.ChecksConfig{EmptyFiles: config.CheckModeWarn},
+		},
+		Review: config.ReviewConfig{Enabled: true},
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+	s.mockReviewActivities(relPath)
+	s.env.OnActivity(bagcreate.Name, mock.Anything, mock.Anything).Never()
+	s.env.OnActivity(activities.UnbagSIPName, mock.Anything, mock.Anything).Never()
+	s.env.OnActivity(activities.QuarantineSIPName, mock.Anything, mock.Anything).Never()
+
+	// Cancel the workflow while the SIP is waiting for review.
+	s.env.RegisterDelayedCallback(s.env.CancelWorkflow, time.Hour)
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeCancelled, result.Outcome)
+	s.Len(result.PreservationTasks, 5)
+
+	review := result.PreservationTasks[3]
+	s.Equal("Review SIP", review.Name)
+	s.Equal("Cancelled before completion", review.Message)
+	s.Equal(enums.EventOutcomeCancelled, review.Outcome)
+
+	s.Equal(
+		&eventlog.Event{
+			Code:        "cancel-preprocessing",
+			Name:        "Cancel preprocessing",
+			MessageCode: "cancelled-not-modified",
+			Message:     "Preprocessing cancelled, SIP was not modified",
+			Outcome:     enums.EventOutcomeCancelled,
+			StartedAt:   s.env.Now().UTC(),
+			CompletedAt: s.env.Now().UTC(),
+		},
+		result.PreservationTasks[4],
+	)
+}
+
+func (s *PreprocessingTestSuite) TestCancelledWhileBagging() {
+	relPath := "transfer"
+	s.SetupTest(config.Configuration{
+		Validation:     config.ValidationConfig{CheckFiles: true},
+		QuarantinePath: "/home/preprocessing/quarantine",
+		FileFormat: ffvalidate.Config{
+			AllowlistPath: "./testdata/allowed_file_formats.csv",
+		},
+	})
+	sessionCtx := mock.AnythingOfType("*context.timerCtx")
+	sipPath := filepath.Join(s.testDir, relPath)
+
+	s.mockValidation(
+		sipPath,
+		&activities.CheckFilesResult{Checked: 2},
+		&ffvalidate.Result{},
+	)
+
+	// Cancel the workflow while the SIP is being bagged.
+	s.env.OnActivity(
+		bagcreate.Name,
+		sessionCtx,
+		&bagcreate.Params{SourcePath: sipPath},
+	).After(time.Hour).Return(
+		&bagcreate.Result{BagPath: sipPath}, nil,
+	)
+	s.env.RegisterDelayedCallback(s.env.CancelWorkflow, time.Minute)
+
+	s.env.OnActivity(
+		activities.UnbagSIPName,
+		sessionCtx,
+		// The preprocessing log written before bagging is removed.
+		mock.MatchedBy(func(p *activities.UnbagSIPParams) bool {
+			return p.SIPPath == sipPath && p.LogRunID == "default-test-run-id" && p.RemoveMetadataDir
+		}),
+	).Return(
+		&activities.UnbagSIPResult{Restored: true}, nil,
+	)
+	s.env.OnActivity(activities.QuarantineSIPName, mock.Anything, mock.Anything).Never()
+
+	s.env.ExecuteWorkflow(
+		s.workflow.Execute,
+		&workflow.PreprocessingWorkflowParams{RelativePath: relPath},
+	)
+
+	s.True(s.env.IsWorkflowCompleted())
+
+	var result workflow.PreprocessingWorkflowResult
+	err := s.env.GetWorkflowResult(&result)
+	s.NoError(err)
+	s.Equal(workflow.OutcomeCancelled, result.Outcome)
+	s.Len(result.PreservationTasks, 5)
+
+	bag := result.PreservationTasks[3]
+	s.Equal("Bag SIP", bag.Name)
+	s.Equal("Cancelled before completion", bag.Message)
+	s.Equal(enums.EventOutcomeCancelled, bag.Outcome)
+
+	cancel := result.PreservationTasks[4]
+	s.Equal("Cancel preprocessing", cancel.Name)
+	s.Equal("Preprocessing cancelled, SIP has been restored to its original layout", cancel.Message)
+	s.Equal(enums.EventOutcomeCancelled, cancel.Outcome)
 }
@@ -0,0 +1,39 @@
+package workflow
+
+import (
+	"github.com/artefactual-sdps/temporal-activities/bagcreate"
+	"github.com/artefactual-sdps/temporal-activities/ffvalidate"
+)
+
+// ProfileActivities lists the activities configured by a processing profile.
+// The worker registers them once per profile, see ProfileActivityName.
+var ProfileActivities = []string{ffvalidate.Name, bagcreate.Name}
+
+// ProfileActivityName returns the name of the activity registered with the
+// configuration of the named profile, e.g. "acme/bag-create".
+func ProfileActivityName(name, profile string) string {
+	return profile + "/" + name
+}
+
+// activityName returns the name of the activity to execute, the activity
+// registered for the profile of the workflow if it's configured by profiles.
+func (w *PreprocessingWorkflow) activityName(name string) string {
+	for _, n := range ProfileActivities {
+		if w.profile != "" && n == name {
+			return ProfileActivityName(name, w.profile)
+		}
+	}
+
+	return name
+}
+
+// withProfile returns a copy of the workflow processing the SIP with the
+// configuration of the named profile.
+func (w *PreprocessingWorkflow) withProfile(profile string) (*PreprocessingWorkflow, error) {
+	cfg, err := w.cfg.WithProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreprocessingWorkflow{cfg: cfg, profile: profile}, nil
+}
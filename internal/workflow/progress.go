@@ -0,0 +1,90 @@
+package workflow
+
+import (
+	temporalsdk_workflow "go.temporal.io/sdk/workflow"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+)
+
+const (
+	// ProgressQuery is the name of the query returning the Progress of a
+	// preprocessing workflow.
+	ProgressQuery = "progress"
+
+	// PreservationTasksQuery is the name of the query returning the
+	// preservation tasks recorded so far by a preprocessing workflow.
+	PreservationTasksQuery = "preservation-tasks"
+)
+
+// Progress reports the progress of a preprocessing workflow.
+type Progress struct {
+	// CurrentStep is the name of the running step, empty when no step is
+	// running.
+	CurrentStep string
+
+	// CompletedSteps is the number of completed steps.
+	CompletedSteps int
+
+	// RemainingSteps is an estimate of the number of steps left to run. Steps
+	// may be skipped, e.g. when a validation step rejects the SIP.
+	RemainingSteps int
+
+	// FilesProcessed is the number of SIP files processed by the completed
+	// steps.
+	FilesProcessed int
+
+	// PreservationTasks are the events recorded so far.
+	PreservationTasks []*eventlog.Event
+}
+
+// progressTracker follows the progress of a workflow execution.
+type progressTracker struct {
+	result     *PreprocessingWorkflowResult
+	totalSteps int
+	done       bool
+}
+
+// registerQueryHandlers registers the progress query handlers for the workflow
+// execution recording its events in result.
+func (w *PreprocessingWorkflow) registerQueryHandlers(
+	ctx temporalsdk_workflow.Context,
+	result *PreprocessingWorkflowResult,
+) (*progressTracker, error) {
+	// Validation steps, bagging and the PREMIS file creation.
+	t := &progressTracker{result: result, totalSteps: len(w.validationSteps(ctx)) + 2}
+
+	err := temporalsdk_workflow.SetQueryHandler(ctx, ProgressQuery, func() (*Progress, error) {
+		return t.progress(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = temporalsdk_workflow.SetQueryHandler(ctx, PreservationTasksQuery, func() ([]*eventlog.Event, error) {
+		return result.PreservationTasks, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *progressTracker) progress() *Progress {
+	p := &Progress{
+		FilesProcessed:    t.result.filesProcessed,
+		PreservationTasks: t.result.PreservationTasks,
+	}
+	for _, ev := range t.result.PreservationTasks {
+		if ev.CompletedAt.IsZero() {
+			p.CurrentStep = ev.Name
+		} else {
+			p.CompletedSteps++
+		}
+	}
+	if !t.done {
+		p.RemainingSteps = max(t.totalSteps-p.CompletedSteps, 0)
+	}
+
+	return p
+}
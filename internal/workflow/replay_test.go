@@ -0,0 +1,93 @@
+package workflow_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artefactual-sdps/temporal-activities/bagcreate"
+	"github.com/artefactual-sdps/temporal-activities/ffvalidate"
+	temporalsdk_worker "go.temporal.io/sdk/worker"
+	temporalsdk_workflow "go.temporal.io/sdk/workflow"
+	"gotest.tools/v3/assert"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/clamd"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/webhook"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/workflow"
+)
+
+// TestReplay replays the workflow histories recorded in testdata/histories
+// against the current workflow code. It fails when a change to the workflow
+// isn't deterministic for executions started by a previous version, see the
+// versioned changes in versions.go.
+//
+// The histories named after a change ID were recorded by a worker running the
+// code from before that change, with the configuration below and a SIP
+// exercising the changed steps, so they fail when the change isn't guarded by
+// hasChange. The steps versioned by the first change IDs existed before the
+// workflow was versioned: success and content-error were recorded before
+// those change IDs, and the histories named after them by the first worker
+// that recorded their markers. Record new histories from a Temporal server
+// with:
+//
+//	temporal workflow show --workflow-id ID --output json > testdata/histories/NAME.json
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	// Enable every optional step, as when the histories were recorded, so
+	// their versioning is checked too.
+	cfg := config.Configuration{
+		SharedPath:     "/home/enduro/preprocessing",
+		QuarantinePath: "/home/enduro/quarantine",
+		Temporal:       config.Temporal{SearchAttributes: true},
+		Validation: config.ValidationConfig{
+			CheckFiles:        true,
+			DeprecatedFormats: []string{"fmt/40"},
+			FormatStatistics:  true,
+			Checks: config.ChecksConfig{
+				EmptyFiles:        config.CheckModeWarn,
+				FileNames:         config.CheckModeWarn,
+				DeprecatedFormats: config.CheckModeWarn,
+			},
+		},
+		Review: config.ReviewConfig{
+			Enabled:         true,
+			Timeout:         time.Hour,
+			TimeoutDecision: config.ReviewDecisionReject,
+		},
+		ValidationReport: config.ValidationReportConfig{Enabled: true},
+		Audit:            config.AuditConfig{DatabasePath: "/home/enduro/preprocessing.db"},
+		Signing:          config.SigningConfig{KeyPath: "/home/enduro/signing.pem"},
+		Webhooks:         []webhook.Endpoint{{URL: "http://enduro/webhook", Secret: "secret"}},
+		Profiles: map[string]config.ProfileConfig{
+			"acme": {
+				Bagit:     bagcreate.Config{ChecksumAlgorithm: "sha512"},
+				Structure: config.StructureConfig{RequiredPaths: []string{"*.txt"}},
+			},
+		},
+		FileFormat: ffvalidate.Config{AllowlistPath: "/home/enduro/allowed_file_formats.csv"},
+		Fixity:     config.FixityConfig{ManifestNames: []string{"checksums.sha256"}},
+		Structure:  config.StructureConfig{ForbiddenPaths: []string{"Thumbs.db"}},
+		ClamAV:     clamd.Config{Address: "tcp://clamd:3310"},
+	}
+
+	histories, err := filepath.Glob("testdata/histories/*.json")
+	assert.NilError(t, err)
+	assert.Assert(t, len(histories) > 0)
+
+	for _, h := range histories {
+		t.Run(filepath.Base(h), func(t *testing.T) {
+			t.Parallel()
+
+			replayer := temporalsdk_worker.NewWorkflowReplayer()
+			replayer.RegisterWorkflowWithOptions(
+				workflow.NewPreprocessingWorkflow(cfg).Execute,
+				temporalsdk_workflow.RegisterOptions{Name: "preprocessing"},
+			)
+
+			err := replayer.ReplayWorkflowHistoryFromJSONFile(nil, h)
+			assert.NilError(t, err)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	temporalsdk_workflow "go.temporal.io/sdk/workflow"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/enums"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/premis"
+)
+
+const (
+	// ReviewUpdate is the name of the update that approves or rejects a SIP
+	// waiting for review, with a ReviewDecision argument.
+	ReviewUpdate = "review"
+
+	// ReviewQuery is the name of the query returning the Review of a SIP. It's
+	// available once the review has started.
+	ReviewQuery = "review"
+)
+
+// ReviewDecision approves or rejects a SIP waiting for review.
+type ReviewDecision struct {
+	// Approved is true if the SIP is approved, false if it's rejected.
+	Approved bool
+
+	// Reviewer is the name of the archivist reviewing the SIP (required).
+	Reviewer string
+
+	// Comment explains the decision (optional).
+	Comment string
+}
+
+// Review is the review of a SIP with validation warnings.
+type Review struct {
+	// Pending is true while the workflow waits for a review decision.
+	Pending bool
+
+	// Findings lists the validation warnings to review.
+	Findings []eventlog.Failure
+
+	// Deadline is the time the review times out, zero if it never does.
+	Deadline time.Time
+
+	// Decision is the review decision, nil until the SIP has been reviewed.
+	Decision *ReviewDecision
+}
+
+// review pauses the workflow until an archivist approves or rejects the SIP,
+// or the review times out. A rejected SIP fails with a content error. It
+// returns the PREMIS event summary of an approval.
+func (w *PreprocessingWorkflow) review(
+	ctx temporalsdk_workflow.Context,
+	result *PreprocessingWorkflowResult,
+) *premis.EventSummary {
+	ev := result.newEvent(ctx, "review-sip")
+	timeout := w.cfg.Review.Timeout
+
+	r := &Review{Pending: true, Findings: result.Warnings}
+	if timeout > 0 {
+		r.Deadline = temporalsdk_workflow.Now(ctx).Add(timeout)
+	}
+
+	e := temporalsdk_workflow.SetQueryHandler(ctx, ReviewQuery, func() (*Review, error) {
+		return r, nil
+	})
+	if e != nil {
+		result.systemError(ctx, e, ev, "review-failed")
+		return nil
+	}
+
+	e = temporalsdk_workflow.SetUpdateHandlerWithOptions(
+		ctx,
+		ReviewUpdate,
+		func(ctx temporalsdk_workflow.Context, d ReviewDecision) error {
+			r.Pending = false
+			r.Decision = &d
+			return nil
+		},
+		temporalsdk_workflow.UpdateHandlerOptions{
+			Validator: func(ctx temporalsdk_workflow.Context, d ReviewDecision) error {
+				if !r.Pending {
+					return errors.New("SIP is not waiting for review")
+				}
+				if d.Reviewer == "" {
+					return errors.New("reviewer is required")
+				}
+				return nil
+			},
+		},
+	)
+	if e != nil {
+		result.systemError(ctx, e, ev, "review-failed")
+		return nil
+	}
+
+	// Wait for a decision, or make one when the review times out.
+	reviewed := func() bool { return !r.Pending }
+	timedOut := false
+	if timeout > 0 {
+		var ok bool
+		ok, e = temporalsdk_workflow.AwaitWithTimeout(ctx, timeout, reviewed)
+		if e == nil && !ok {
+			timedOut = true
+			r.Pending = false
+			r.Decision = &ReviewDecision{
+				Approved: w.cfg.Review.TimeoutDecision == config.ReviewDecisionApprove,
+				Comment:  fmt.Sprintf("review timed out after %s", timeout),
+			}
+		}
+	} else {
+		e = temporalsdk_workflow.Await(ctx, reviewed)
+	}
+	if e != nil {
+		result.systemError(ctx, e, ev, "review-failed")
+		return nil
+	}
+
+	d := r.Decision
+	by := "automatically"
+	if d.Reviewer != "" {
+		by = "by " + d.Reviewer
+	}
+	comment := ""
+	if d.Comment != "" {
+		comment = ": " + d.Comment
+	}
+
+	code := "sip-approved"
+	if !d.Approved {
+		code = "sip-rejected"
+	}
+	params := messages.Params{"reviewer": d.Reviewer}
+	switch {
+	case timedOut:
+		code += "-timeout"
+		params = messages.Params{"timeout": timeout.String()}
+	case d.Comment != "":
+		code += "-comment"
+		params["comment"] = d.Comment
+	}
+
+	if !d.Approved {
+		result.Outcome = OutcomeContentError
+		params["reason"] = code
+		result.complete(ctx, ev, enums.EventOutcomeValidationFailure, "content-error", params)
+		return nil
+	}
+
+	result.complete(ctx, ev, enums.EventOutcomeSuccess, code, params)
+
+	return &premis.EventSummary{
+		Type:          "validation",
+		Detail:        fmt.Sprintf("name=\"Review SIP\" reviewer=%q", d.Reviewer),
+		Outcome:       "approved",
+		OutcomeDetail: fmt.Sprintf("SIP with %d warnings approved %s%s", len(r.Findings), by, comment),
+	}
+}
@@ -0,0 +1,99 @@
+package workflow
+
+import (
+	temporalsdk_temporal "go.temporal.io/sdk/temporal"
+	temporalsdk_workflow "go.temporal.io/sdk/workflow"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/enums"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+)
+
+// Custom search attributes upserted by the preprocessing workflow as it
+// proceeds, if enabled. They must be registered in the Temporal namespace,
+// e.g. with the search-attributes CLI command, to search workflows with
+// queries like:
+//
+//	PreprocessingProducer = "Acme" AND PreprocessingFailedStep = "Validate SIP file formats"
+var (
+	// SIPIDAttribute is the UUID of the SIP in Enduro, if known.
+	SIPIDAttribute = temporalsdk_temporal.NewSearchAttributeKeyKeyword("PreprocessingSIPID")
+
+	// SIPNameAttribute is the name of the SIP, by default the base name of its
+	// path.
+	SIPNameAttribute = temporalsdk_temporal.NewSearchAttributeKeyKeyword("PreprocessingSIPName")
+
+	// ProducerAttribute is the name of the producer of the SIP, if known.
+	ProducerAttribute = temporalsdk_temporal.NewSearchAttributeKeyKeyword("PreprocessingProducer")
+
+	// AccessionNumberAttribute is the accession number of the SIP, if known.
+	AccessionNumberAttribute = temporalsdk_temporal.NewSearchAttributeKeyKeyword("PreprocessingAccessionNumber")
+
+	// ProfileAttribute is the name of the processing profile of the SIP, if
+	// any.
+	ProfileAttribute = temporalsdk_temporal.NewSearchAttributeKeyKeyword("PreprocessingProfile")
+
+	// FileCountAttribute is the number of files in the SIP.
+	FileCountAttribute = temporalsdk_temporal.NewSearchAttributeKeyInt64("PreprocessingFileCount")
+
+	// TotalSizeAttribute is the total size of the SIP files, in bytes.
+	TotalSizeAttribute = temporalsdk_temporal.NewSearchAttributeKeyInt64("PreprocessingTotalSize")
+
+	// OutcomeAttribute is the workflow Outcome, e.g. "content error".
+	OutcomeAttribute = temporalsdk_temporal.NewSearchAttributeKeyKeyword("PreprocessingOutcome")
+
+	// FailedStepAttribute is the name of the first failed step, e.g.
+	// "Validate SIP file formats".
+	FailedStepAttribute = temporalsdk_temporal.NewSearchAttributeKeyKeyword("PreprocessingFailedStep")
+)
+
+// SearchAttributes lists the custom search attributes of the preprocessing
+// workflow.
+var SearchAttributes = []temporalsdk_temporal.SearchAttributeKey{
+	SIPIDAttribute,
+	SIPNameAttribute,
+	ProducerAttribute,
+	AccessionNumberAttribute,
+	ProfileAttribute,
+	FileCountAttribute,
+	TotalSizeAttribute,
+	OutcomeAttribute,
+	FailedStepAttribute,
+}
+
+// upsertSearchAttributes updates the search attributes of the workflow if
+// they are enabled. The server rejects updates of attributes that aren't
+// registered in the namespace, failing the workflow task until they are.
+func (w *PreprocessingWorkflow) upsertSearchAttributes(
+	ctx temporalsdk_workflow.Context,
+	updates ...temporalsdk_temporal.SearchAttributeUpdate,
+) {
+	if !w.cfg.Temporal.SearchAttributes || !hasChange(ctx, searchAttributesChangeID) {
+		return
+	}
+
+	if err := temporalsdk_workflow.UpsertTypedSearchAttributes(ctx, updates...); err != nil {
+		temporalsdk_workflow.GetLogger(ctx).Warn("Unable to update search attributes", "error", err.Error())
+	}
+}
+
+// upsertOutcome updates the outcome and failed step search attributes with
+// the final result of the workflow.
+func (w *PreprocessingWorkflow) upsertOutcome(ctx temporalsdk_workflow.Context, result *PreprocessingWorkflowResult) {
+	updates := []temporalsdk_temporal.SearchAttributeUpdate{OutcomeAttribute.ValueSet(result.Outcome.String())}
+	if step := result.failedStep(); step != "" {
+		updates = append(updates, FailedStepAttribute.ValueSet(step))
+	}
+	w.upsertSearchAttributes(ctx, updates...)
+}
+
+// failedStep returns the name of the first step that failed, in the default
+// language so queries don't depend on the SIP language, or an empty string.
+func (r *PreprocessingWorkflowResult) failedStep() string {
+	for _, ev := range r.PreservationTasks {
+		if ev.Outcome == enums.EventOutcomeSystemFailure || ev.Outcome == enums.EventOutcomeValidationFailure {
+			return messages.EventName(messages.DefaultLanguage, ev.Code)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,385 @@
+package workflow
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/artefactual-sdps/temporal-activities/ffvalidate"
+	temporalsdk_workflow "go.temporal.io/sdk/workflow"
+
+	"github.com/artefactual-sdps/preprocessing-demo/internal/activities"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/config"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/enums"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/eventlog"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/messages"
+	"github.com/artefactual-sdps/preprocessing-demo/internal/premis"
+)
+
+// validationStep checks the SIP at sipPath without modifying it. It records
+// its outcome as a new event in the result and returns the PREMIS event
+// summary to add to the SIP when the validation succeeds.
+type validationStep func(
+	ctx temporalsdk_workflow.Context,
+	result *PreprocessingWorkflowResult,
+	sipPath string,
+) *premis.EventSummary
+
+// validationSteps returns the enabled validation steps in execution order.
+func (w *PreprocessingWorkflow) validationSteps(ctx temporalsdk_workflow.Context) []validationStep {
+	var steps []validationStep
+	if w.stepEnabled(config.StepChecksums) && hasChange(ctx, verifyChecksumsChangeID) {
+		steps = append(steps, w.verifyChecksums)
+	}
+	if w.cfg.ClamAV.Address != "" && w.stepEnabled(config.StepViruses) && hasChange(ctx, scanVirusesChangeID) {
+		steps = append(steps, w.scanViruses)
+	}
+	structure := w.cfg.Structure
+	if len(structure.RequiredPaths)+len(structure.ForbiddenPaths) > 0 && w.stepEnabled(config.StepStructure) &&
+		hasChange(ctx, profilesChangeID) {
+		steps = append(steps, w.validateStructure)
+	}
+	if w.cfg.Validation.CheckFiles && w.stepEnabled(config.StepFiles) && hasChange(ctx, checkFilesChangeID) {
+		steps = append(steps, w.checkFiles)
+	}
+	if w.stepEnabled(config.StepFileFormats) {
+		steps = append(steps, w.validateFileFormats)
+	}
+
+	return steps
+}
+
+// stepEnabled reports whether the named validation step isn't disabled.
+func (w *PreprocessingWorkflow) stepEnabled(step string) bool {
+	return !slices.Contains(w.cfg.Validation.DisabledSteps, step)
+}
+
+// verifyChecksums verifies producer-supplied checksums.
+func (w *PreprocessingWorkflow) verifyChecksums(
+	ctx temporalsdk_workflow.Context,
+	result *PreprocessingWorkflowResult,
+	sipPath string,
+) *premis.EventSummary {
+	ev := result.newEvent(ctx, "verify-checksums")
+	var verifyChecksums activities.VerifyChecksumsResult
+	e := temporalsdk_workflow.ExecuteActivity(
+		w.withActivityOpts(ctx, activities.VerifyChecksumsName),
+		activities.VerifyChecksumsName,
+		&activities.VerifyChecksumsParams{SIPPath: sipPath},
+	).Get(ctx, &verifyChecksums)
+	if e != nil {
+		result.systemError(ctx, e, ev, "checksums-failed")
+		return nil
+	}
+
+	result.processedFiles(verifyChecksums.Verified)
+	switch {
+	case verifyChecksums.Failures != nil:
+		failures, warnings := w.splitFailures(verifyChecksums.Failures)
+		result.validationError(
+			ctx,
+			ev,
+			"checksums-mismatch",
+			failures,
+			warnings,
+		)
+		return nil
+	case len(verifyChecksums.Manifests) == 0:
+		result.complete(ctx, ev, enums.EventOutcomeSuccess, "checksums-no-manifests", nil)
+		return nil
+	}
+
+	result.complete(ctx, ev, enums.EventOutcomeSuccess, "checksums-verified", messages.Params{
+		"verified":  strconv.Itoa(verifyChecksums.Verified),
+		"manifests": strings.Join(verifyChecksums.Manifests, ", "),
+	})
+
+	return &premis.EventSummary{
+		Type:          "fixity check",
+		Detail:        "name=\"Verify SIP checksums\"",
+		Outcome:       "pass",
+		OutcomeDetail: "Checksums match producer-supplied manifests",
+	}
+}
+
+// scanViruses scans the SIP files for viruses with clamd.
+func (w *PreprocessingWorkflow) scanViruses(
+	ctx temporalsdk_workflow.Context,
+	result *PreprocessingWorkflowResult,
+	sipPath string,
+) *premis.EventSummary {
+	ev := result.newEvent(ctx, "scan-viruses")
+	var scanViruses activities.ScanVirusesResult
+	e := temporalsdk_workflow.ExecuteActivity(
+		w.withActivityOpts(ctx, activities.ScanVirusesName),
+		activities.ScanVirusesName,
+		&activities.ScanVirusesParams{SIPPath: sipPath},
+	).Get(ctx, &scanViruses)
+	if e != nil {
+		result.systemError(ctx, e, ev, "viruses-failed")
+		return nil
+	}
+	result.processedFiles(scanViruses.Scanned)
+	if scanViruses.Failures != nil {
+		failures, warnings := w.splitFailures(scanViruses.Failures)
+		result.validationError(
+			ctx,
+			ev,
+			"viruses-found",
+			failures,
+			warnings,
+		)
+		return nil
+	}
+
+	result.complete(ctx, ev, enums.EventOutcomeSuccess, "viruses-not-found", messages.Params{
+		"files": strconv.Itoa(scanViruses.Scanned),
+	})
+
+	return &premis.EventSummary{
+		Type:          "virus check",
+		Detail:        "program=\"ClamAV (clamd)\"",
+		Outcome:       "pass",
+		OutcomeDetail: "No viruses found",
+	}
+}
+
+// validateStructure checks the SIP paths against the structure rules.
+func (w *PreprocessingWorkflow) validateStructure(
+	ctx temporalsdk_workflow.Context,
+	result *PreprocessingWorkflowResult,
+	sipPath string,
+) *premis.EventSummary {
+	ev := result.newEvent(ctx, "validate-structure")
+	var validateStructure activities.ValidateStructureResult
+	e := temporalsdk_workflow.ExecuteActivity(
+		w.withActivityOpts(ctx, activities.ValidateStructureName),
+		activities.ValidateStructureName,
+		&activities.ValidateStructureParams{
+			SIPPath:        sipPath,
+			RequiredPaths:  w.cfg.Structure.RequiredPaths,
+			ForbiddenPaths: w.cfg.Structure.ForbiddenPaths,
+		},
+	).Get(ctx, &validateStructure)
+	if e != nil {
+		result.systemError(ctx, e, ev, "structure-failed")
+		return nil
+	}
+	if validateStructure.Failures != nil {
+		failures, warnings := w.splitFailures(validateStructure.Failures)
+		result.validationError(
+			ctx,
+			ev,
+			"structure-invalid",
+			failures,
+			warnings,
+		)
+		return nil
+	}
+
+	result.complete(ctx, ev, enums.EventOutcomeSuccess, "structure-valid", nil)
+
+	return &premis.EventSummary{
+		Type:          "validation",
+		Detail:        "name=\"Validate SIP structure\"",
+		Outcome:       "valid",
+		OutcomeDetail: "SIP structure valid",
+	}
+}
+
+// checkFiles checks the SIP for empty files, unusual file names and
+// deprecated file formats.
+func (w *PreprocessingWorkflow) checkFiles(
+	ctx temporalsdk_workflow.Context,
+	result *PreprocessingWorkflowResult,
+	sipPath string,
+) *premis.EventSummary {
+	ev := result.newEvent(ctx, "check-files")
+	var checkFiles activities.CheckFilesResult
+	e := temporalsdk_workflow.ExecuteActivity(
+		w.withActivityOpts(ctx, activities.CheckFilesName),
+		activities.CheckFilesName,
+		&activities.CheckFilesParams{SIPPath: sipPath},
+	).Get(ctx, &checkFiles)
+	if e != nil {
+		result.systemError(ctx, e, ev, "files-failed")
+		return nil
+	}
+	result.processedFiles(checkFiles.Checked)
+	result.Statistics = checkFiles.Stats
+	w.upsertSearchAttributes(
+		ctx,
+		FileCountAttribute.ValueSet(int64(checkFiles.Checked)),
+		TotalSizeAttribute.ValueSet(checkFiles.Size),
+	)
+	w.addFileCheckEvents(ctx, result, ev, checkFiles.Failures)
+
+	// The outcome of the step is the aggregated outcome of the file checks.
+	if eventlog.AggregateOutcome(ev.Children) != enums.EventOutcomeSuccess {
+		failures, warnings := w.splitFailures(checkFiles.Failures)
+		result.validationError(
+			ctx,
+			ev,
+			"files-invalid",
+			failures,
+			warnings,
+		)
+		return nil
+	}
+
+	result.complete(ctx, ev, enums.EventOutcomeSuccess, "files-valid", messages.Params{
+		"files": strconv.Itoa(checkFiles.Checked),
+	})
+
+	return &premis.EventSummary{
+		Type:          "validation",
+		Detail:        "name=\"Check SIP files\"",
+		Outcome:       "valid",
+		OutcomeDetail: "No empty files, unusual file names or deprecated formats found",
+	}
+}
+
+// fileChecks are the checks of the check-files activity, with the codes of
+// their events.
+var fileChecks = []struct{ check, code string }{
+	{"empty file", "check-empty-files"},
+	{"file name", "check-file-names"},
+	{"deprecated format", "check-deprecated-formats"},
+}
+
+// addFileCheckEvents adds a child event to the check files event ev for each
+// file check, with the number of files failing the check.
+func (w *PreprocessingWorkflow) addFileCheckEvents(
+	ctx temporalsdk_workflow.Context,
+	result *PreprocessingWorkflowResult,
+	ev *eventlog.Event,
+	failures []eventlog.Failure,
+) {
+	for _, c := range fileChecks {
+		child := result.newChild(ev, c.code)
+		if c.check == "deprecated format" && len(w.cfg.Validation.DeprecatedFormats) == 0 {
+			result.complete(ctx, child, enums.EventOutcomeSkipped, "deprecated-formats-not-configured", nil)
+			continue
+		}
+
+		n := 0
+		for _, f := range failures {
+			if f.Check == c.check {
+				n++
+			}
+		}
+		params := messages.Params{"count": strconv.Itoa(n)}
+		switch {
+		case n == 0:
+			result.complete(ctx, child, enums.EventOutcomeSuccess, "file-check-valid", nil)
+		case w.checkMode(c.check) == config.CheckModeWarn:
+			result.complete(ctx, child, enums.EventOutcomeWarning, "file-check-invalid", params)
+		default:
+			result.complete(ctx, child, enums.EventOutcomeValidationFailure, "file-check-invalid", params)
+		}
+	}
+}
+
+// validateFileFormats checks that the SIP file formats are allowed.
+func (w *PreprocessingWorkflow) validateFileFormats(
+	ctx temporalsdk_workflow.Context,
+	result *PreprocessingWorkflowResult,
+	sipPath string,
+) *premis.EventSummary {
+	ev := result.newEvent(ctx, "validate-file-formats")
+	var validateFileFormat ffvalidate.Result
+	e := temporalsdk_workflow.ExecuteActivity(
+		w.withActivityOpts(ctx, ffvalidate.Name),
+		w.activityName(ffvalidate.Name),
+		&ffvalidate.Params{Path: sipPath},
+	).Get(ctx, &validateFileFormat)
+	if e != nil {
+		result.systemError(ctx, e, ev, "file-formats-failed")
+		return nil
+	}
+	if validateFileFormat.Failures != nil {
+		failures, warnings := w.splitFailures(fileFormatFailures(validateFileFormat.Failures))
+		result.validationError(
+			ctx,
+			ev,
+			"file-formats-invalid",
+			failures,
+			warnings,
+		)
+		return nil
+	}
+
+	result.complete(ctx, ev, enums.EventOutcomeSuccess, "file-formats-valid", nil)
+
+	return &premis.EventSummary{
+		Type:          "validation",
+		Detail:        "name=\"Validate SIP file formats\"",
+		Outcome:       "valid",
+		OutcomeDetail: "File formats allowed",
+	}
+}
+
+// fileFormatFailureRegex matches the ffvalidate failure messages, capturing
+// the Go quoted PUID and path.
+var fileFormatFailureRegex = regexp.MustCompile(
+	`^file format ("(?:[^"\\]|\\.)*") not allowed: ("(?:[^"\\]|\\.)*")$`,
+)
+
+// fileFormatFailures converts the ffvalidate failure messages to structured
+// failures, keeping the message as is when it can't be parsed.
+func fileFormatFailures(msgs []string) []eventlog.Failure {
+	failures := make([]eventlog.Failure, 0, len(msgs))
+	for _, msg := range msgs {
+		f := eventlog.Failure{Check: "file format", Code: "format-not-allowed", Message: msg}
+		if m := fileFormatFailureRegex.FindStringSubmatch(msg); m != nil {
+			puid, err1 := strconv.Unquote(m[1])
+			path, err2 := strconv.Unquote(m[2])
+			if err1 == nil && err2 == nil {
+				f.PUID = puid
+				f.Path = path
+				f.Params = messages.Params{"puid": f.PUID, "path": f.Path}
+			}
+		}
+		failures = append(failures, f)
+	}
+
+	return failures
+}
+
+// splitFailures splits failures into blocking failures and warnings, based on
+// the configured mode of each failed check.
+func (w *PreprocessingWorkflow) splitFailures(all []eventlog.Failure) (failures, warnings []eventlog.Failure) {
+	for _, f := range all {
+		if w.checkMode(f.Check) == config.CheckModeWarn {
+			warnings = append(warnings, f)
+		} else {
+			failures = append(failures, f)
+		}
+	}
+
+	return failures, warnings
+}
+
+// checkMode returns the configured mode of the named validation check.
+func (w *PreprocessingWorkflow) checkMode(check string) config.CheckMode {
+	checks := w.cfg.Validation.Checks
+	switch check {
+	case "checksum":
+		return checks.Checksums
+	case "virus":
+		return checks.Viruses
+	case "file format":
+		return checks.FileFormats
+	case "structure":
+		return checks.Structure
+	case "empty file":
+		return checks.EmptyFiles
+	case "file name":
+		return checks.FileNames
+	case "deprecated format":
+		return checks.DeprecatedFormats
+	}
+
+	return config.CheckModeBlock
+}
@@ -0,0 +1,81 @@
+package workflow
+
+import (
+	temporalsdk_workflow "go.temporal.io/sdk/workflow"
+)
+
+// Change IDs of the workflow changes that alter the sequence of commands
+// (activities, timers, etc.) executed by the workflow. Each change is guarded
+// by hasChange so executions started before the change was deployed replay
+// the previous behavior.
+//
+// To change the workflow: record a history in testdata/histories with the
+// code from before the change, named after the new change ID, then branch on
+// hasChange. Remove a branch only when no execution started before the change
+// is still running.
+const (
+	// verifyChecksumsChangeID adds the producer-supplied checksum
+	// verification.
+	verifyChecksumsChangeID = "verify-checksums"
+
+	// scanVirusesChangeID adds the virus scan.
+	scanVirusesChangeID = "scan-viruses"
+
+	// quarantineChangeID moves rejected SIPs to quarantine.
+	quarantineChangeID = "quarantine-sip"
+
+	// checkFilesChangeID adds the empty files, file names and deprecated
+	// formats checks.
+	checkFilesChangeID = "check-files"
+
+	// reviewChangeID adds the review of SIPs with warnings.
+	reviewChangeID = "review-sip"
+
+	// cancelChangeID adds the cancelled outcome and the SIP clean up after a
+	// cancellation.
+	cancelChangeID = "cancel-sip"
+
+	// searchAttributesChangeID upserts the custom search attributes.
+	searchAttributesChangeID = "search-attributes"
+
+	// sipMetadataChangeID adds the SIP metadata to bag-info.txt and the
+	// producer PREMIS agent.
+	sipMetadataChangeID = "sip-metadata"
+
+	// profilesChangeID adds the processing profiles and the SIP structure
+	// validation.
+	profilesChangeID = "profiles"
+
+	// preprocessingLogChangeID writes the preprocessing log into the SIP
+	// before bagging.
+	preprocessingLogChangeID = "preprocessing-log"
+
+	// validationReportChangeID writes the HTML validation report next to the
+	// SIP.
+	validationReportChangeID = "validation-report"
+
+	// webhooksChangeID notifies the configured webhooks when preprocessing
+	// finishes.
+	webhooksChangeID = "webhooks"
+
+	// auditChangeID records the run in the audit database.
+	auditChangeID = "audit"
+
+	// signingChangeID signs the tag manifests and premis.xml of the bagged
+	// SIP, with a PREMIS digital signature generation event.
+	signingChangeID = "signing"
+
+	// signatureEventChangeID records the signature in premis.xml once the SIP
+	// is signed, then signs it again, instead of recording it beforehand.
+	signatureEventChangeID = "signature-event"
+
+	// sipSizeChangeID measures the SIP before processing it, to scale the
+	// activity timeouts with the SIP size.
+	sipSizeChangeID = "sip-size"
+)
+
+// hasChange reports whether the workflow execution includes the change with
+// the given ID, i.e. it was started by a worker that knows about the change.
+func hasChange(ctx temporalsdk_workflow.Context, changeID string) bool {
+	return temporalsdk_workflow.GetVersion(ctx, changeID, temporalsdk_workflow.DefaultVersion, 1) == 1
+}